@@ -207,6 +207,15 @@ func featureName(f CoreFeatures) string {
 	case CoreFeatureSIMD:
 		// match https://github.com/WebAssembly/spec/blob/wg-2.0.draft1/proposals/simd/SIMD.md
 		return "simd"
+	case CoreFeatureSIMD << 1: // experimental.CoreFeaturesThreads
+		// match https://github.com/WebAssembly/threads/blob/main/proposals/threads/Overview.md
+		return "threads"
+	case CoreFeatureSIMD << 2: // experimental.CoreFeaturesMemory64
+		// match https://github.com/WebAssembly/memory64/blob/main/proposals/memory64/Overview.md
+		return "memory64"
+	case CoreFeatureSIMD << 3: // experimental.CoreFeaturesMultiMemory
+		// match https://github.com/WebAssembly/multi-memory/blob/main/proposals/multi-memory/Overview.md
+		return "multi-memory"
 	}
 	return ""
 }
@@ -93,6 +93,17 @@ type HostFunctionBuilder interface {
 	// or result types must map to WebAssembly numeric value types. This means
 	// uint32, int32, uint64, int64, float32 or float64.
 	//
+	// The last result may be an error, which isn't a WebAssembly result.
+	// Instead, returning a non-nil error traps, failing the call which
+	// entered the module.
+	//
+	//	builder.WithFunc(func(ctx context.Context, x, y uint32) (uint32, error) {
+	//		if y == 0 {
+	//			return 0, errors.New("division by zero")
+	//		}
+	//		return x / y, nil
+	//	})
+	//
 	// api.Module may be specified as the second parameter, usually to access
 	// memory. This is important because there are only numeric types in Wasm.
 	// The only way to share other data is via writing memory and sharing
@@ -185,6 +196,23 @@ type HostModuleBuilder interface {
 	// NewFunctionBuilder begins the definition of a host function.
 	NewFunctionBuilder() HostFunctionBuilder
 
+	// ExportGlobal exports a global of the given numeric type and initial value, which modules can import.
+	//
+	// The value is encoded as documented on api.ValueType. Once instantiated, use api.Module ExportedGlobal to read
+	// it from Go. When mutable, the result is an api.MutableGlobal which can also be set, and which reflects writes
+	// by modules importing it.
+	//
+	// Here's an example:
+	//
+	//	env, _ := r.NewHostModuleBuilder("env").
+	//		ExportGlobal("counter", api.ValueTypeI32, 0, true).
+	//		Instantiate(ctx)
+	//
+	//	// ... instantiate a module which imports and increments env.counter.
+	//
+	//	counter := env.ExportedGlobal("counter").Get()
+	ExportGlobal(exportName string, valueType api.ValueType, value uint64, mutable bool) HostModuleBuilder
+
 	// Compile returns a CompiledModule that can be instantiated by Runtime.
 	Compile(context.Context) (CompiledModule, error)
 
@@ -218,14 +246,18 @@ type hostModuleBuilder struct {
 	moduleName     string
 	exportNames    []string
 	nameToHostFunc map[string]*wasm.HostFunc
+
+	globalExportNames []string
+	nameToHostGlobal  map[string]*wasm.HostGlobal
 }
 
 // NewHostModuleBuilder implements Runtime.NewHostModuleBuilder
 func (r *runtime) NewHostModuleBuilder(moduleName string) HostModuleBuilder {
 	return &hostModuleBuilder{
-		r:              r,
-		moduleName:     moduleName,
-		nameToHostFunc: map[string]*wasm.HostFunc{},
+		r:                r,
+		moduleName:       moduleName,
+		nameToHostFunc:   map[string]*wasm.HostFunc{},
+		nameToHostGlobal: map[string]*wasm.HostGlobal{},
 	}
 }
 
@@ -307,6 +339,19 @@ func (b *hostModuleBuilder) ExportHostFunc(fn *wasm.HostFunc) {
 	b.nameToHostFunc[fn.ExportName] = fn
 }
 
+// ExportGlobal implements HostModuleBuilder.ExportGlobal
+func (b *hostModuleBuilder) ExportGlobal(exportName string, valueType api.ValueType, value uint64, mutable bool) HostModuleBuilder {
+	if _, ok := b.nameToHostGlobal[exportName]; !ok { // add a new name
+		b.globalExportNames = append(b.globalExportNames, exportName)
+	}
+	b.nameToHostGlobal[exportName] = &wasm.HostGlobal{
+		ExportName: exportName,
+		Type:       wasm.GlobalType{ValType: valueType, Mutable: mutable},
+		Value:      value,
+	}
+	return b
+}
+
 // NewFunctionBuilder implements HostModuleBuilder.NewFunctionBuilder
 func (b *hostModuleBuilder) NewFunctionBuilder() HostFunctionBuilder {
 	return &hostFunctionBuilder{b: b}
@@ -317,6 +362,8 @@ func (b *hostModuleBuilder) Compile(ctx context.Context) (CompiledModule, error)
 	module, err := wasm.NewHostModule(b.moduleName, b.exportNames, b.nameToHostFunc, b.r.enabledFeatures)
 	if err != nil {
 		return nil, err
+	} else if err = wasm.AddHostGlobals(module, b.globalExportNames, b.nameToHostGlobal); err != nil {
+		return nil, err
 	} else if err = module.Validate(b.r.enabledFeatures); err != nil {
 		return nil, err
 	}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
@@ -302,6 +303,35 @@ func TestNewHostModuleBuilder_Compile(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "ExportGlobal",
+			input: func(r Runtime) HostModuleBuilder {
+				return r.NewHostModuleBuilder("host").
+					ExportGlobal("counter", i32, 1, true).
+					ExportGlobal("limit", i64, 2, false)
+			},
+			expected: &wasm.Module{
+				GlobalSection: []wasm.Global{
+					{
+						Type: wasm.GlobalType{ValType: i32, Mutable: true},
+						Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{1}},
+					},
+					{
+						Type: wasm.GlobalType{ValType: i64},
+						Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI64Const, Data: []byte{2}},
+					},
+				},
+				ExportSection: []wasm.Export{
+					{Name: "counter", Type: wasm.ExternTypeGlobal, Index: 0},
+					{Name: "limit", Type: wasm.ExternTypeGlobal, Index: 1},
+				},
+				Exports: map[string]*wasm.Export{
+					"counter": {Name: "counter", Type: wasm.ExternTypeGlobal, Index: 0},
+					"limit":   {Name: "limit", Type: wasm.ExternTypeGlobal, Index: 1},
+				},
+				NameSection: &wasm.NameSection{ModuleName: "host"},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -350,6 +380,24 @@ func TestNewHostModuleBuilder_Compile_Errors(t *testing.T) {
 			},
 			expectedErr: `func[host.fn] param[0] is unsupported: string`,
 		},
+		{
+			name: "struct param",
+			input: func(rt Runtime) HostModuleBuilder {
+				return rt.NewHostModuleBuilder("host").NewFunctionBuilder().
+					WithFunc(func(context.Context, uint32, struct{ x uint32 }) {}).
+					Export("fn")
+			},
+			expectedErr: `func[host.fn] param[2] is unsupported: struct`,
+		},
+		{
+			name: "global name used by function",
+			input: func(rt Runtime) HostModuleBuilder {
+				return rt.NewHostModuleBuilder("host").
+					NewFunctionBuilder().WithFunc(func() {}).Export("fn").
+					ExportGlobal("fn", api.ValueTypeI32, 0, false)
+			},
+			expectedErr: `global[host.fn] export name already used by a function`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -362,6 +410,42 @@ func TestNewHostModuleBuilder_Compile_Errors(t *testing.T) {
 	}
 }
 
+// TestNewHostModuleBuilder_ExportGlobal ensures writes to an imported host global are visible from Go and vice versa.
+func TestNewHostModuleBuilder_ExportGlobal(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	env, err := r.NewHostModuleBuilder("env").
+		ExportGlobal("counter", api.ValueTypeI32, 1, true).
+		Instantiate(testCtx)
+	require.NoError(t, err)
+
+	mod, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{{}},
+		ImportSection: []wasm.Import{
+			{Type: wasm.ExternTypeGlobal, Module: "env", Name: "counter", DescGlobal: wasm.GlobalType{ValType: api.ValueTypeI32, Mutable: true}},
+		},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeGlobalGet, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Add, wasm.OpcodeGlobalSet, 0, wasm.OpcodeEnd,
+		}}},
+		ExportSection: []wasm.Export{{Type: wasm.ExternTypeFunc, Name: "increment", Index: 0}},
+	}))
+	require.NoError(t, err)
+
+	counter, ok := env.ExportedGlobal("counter").(api.MutableGlobal)
+	require.True(t, ok)
+
+	_, err = mod.ExportedFunction("increment").Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), counter.Get())
+
+	counter.Set(10)
+	_, err = mod.ExportedFunction("increment").Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(11), counter.Get())
+}
+
 // TestNewHostModuleBuilder_Instantiate ensures Runtime.InstantiateModule is called on success.
 func TestNewHostModuleBuilder_Instantiate(t *testing.T) {
 	r := NewRuntime(testCtx)
@@ -52,6 +52,53 @@ func NewCompilationCacheWithDir(dirname string) (CompilationCache, error) {
 	return c, err
 }
 
+// ModuleCache lets Runtime.CompileModule reuse a module compiled from the same bytes, skipping decoding and
+// validation. Entries are keyed by a SHA-256 over the sections of the binary.
+//
+// # Notes
+//
+//   - Implementations must be safe for concurrent use.
+//   - Entries are only valid for the Runtime which compiled them, so a ModuleCache shouldn't be shared across runtimes.
+//     Entries compiled by another Runtime are ignored.
+//   - Closing a CompiledModule only releases it from the engine. If its bytes are compiled again, the module is
+//     recompiled by the engine, but not decoded.
+//   - Modules aren't cached while a function listener factory is in the context.Context, as listeners affect
+//     compilation.
+type ModuleCache interface {
+	// Get returns the module previously put with the key, or false if there is none.
+	Get(key [32]byte) (CompiledModule, bool)
+
+	// Put stores the module compiled from the binary with the key.
+	Put(key [32]byte, compiled CompiledModule)
+}
+
+// NewModuleCache returns an in-memory ModuleCache to be passed to RuntimeConfig WithModuleCache. Entries are never
+// evicted, so this suits runtimes which compile a bounded set of modules.
+func NewModuleCache() ModuleCache {
+	return &moduleCache{modules: map[[32]byte]CompiledModule{}}
+}
+
+// moduleCache implements ModuleCache.
+type moduleCache struct {
+	mux     sync.RWMutex
+	modules map[[32]byte]CompiledModule
+}
+
+// Get implements ModuleCache.Get
+func (c *moduleCache) Get(key [32]byte) (CompiledModule, bool) {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+	compiled, ok := c.modules[key]
+	return compiled, ok
+}
+
+// Put implements ModuleCache.Put
+func (c *moduleCache) Put(key [32]byte, compiled CompiledModule) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.modules[key] = compiled
+}
+
 // cache implements Cache interface.
 type cache struct {
 	// eng is the engine for this cache. If the cache is configured, the engine is shared across multiple instances of
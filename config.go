@@ -148,6 +148,29 @@ type RuntimeConfig interface {
 	//	customSections := c.CustomSections()
 	WithCustomSections(bool) RuntimeConfig
 
+	// WithModuleCache configures Runtime.CompileModule, and so Runtime.Instantiate, to reuse a module compiled from the
+	// same bytes instead of decoding and validating them again. Defaults to nil, which disables this.
+	//
+	// See ModuleCache
+	WithModuleCache(ModuleCache) RuntimeConfig
+
+	// WithMemoryBudget limits the sum of the memory pages of all modules instantiated by the Runtime, including growth.
+	// Defaults to zero, which means no limit.
+	//
+	// Instantiating a module whose minimum memory pages exceed the remaining budget fails, and once the budget is
+	// exhausted, memory.grow returns -1. Pages are returned to the budget when the module defining the memory is
+	// closed. Unlike WithMemoryLimitPages, which limits each memory, this limits the memories of all modules together.
+	WithMemoryBudget(pages uint32) RuntimeConfig
+
+	// WithDeterministicProfile makes floating-point results deterministic when enabled. Defaults to false.
+	//
+	// WebAssembly allows the sign and payload of a NaN result to vary, for example depending on the operands and the
+	// platform. With this profile, every NaN result of a scalar floating-point instruction, including min and max, is
+	// the canonical NaN with a positive sign, so that the same inputs always produce bit-identical outputs.
+	//
+	// Note: This is only supported by NewRuntimeConfigInterpreter, and panics when enabled with another RuntimeConfig.
+	WithDeterministicProfile(bool) RuntimeConfig
+
 	// WithCloseOnContextDone ensures the executions of functions to be closed under one of the following circumstances:
 	//
 	// 	- context.Context passed to the Call method of api.Function is canceled during execution. (i.e. ctx by context.WithCancel)
@@ -186,6 +209,9 @@ type runtimeConfig struct {
 	dwarfDisabled         bool // negative as defaults to enabled
 	newEngine             newEngine
 	cache                 CompilationCache
+	moduleCache           ModuleCache
+	memoryBudgetPages     uint32
+	deterministicProfile  bool
 	storeCustomSections   bool
 	ensureTermination     bool
 }
@@ -294,6 +320,31 @@ func (c *runtimeConfig) WithCustomSections(storeCustomSections bool) RuntimeConf
 	return ret
 }
 
+// WithModuleCache implements RuntimeConfig.WithModuleCache
+func (c *runtimeConfig) WithModuleCache(moduleCache ModuleCache) RuntimeConfig {
+	ret := c.clone()
+	ret.moduleCache = moduleCache
+	return ret
+}
+
+// WithMemoryBudget implements RuntimeConfig.WithMemoryBudget
+func (c *runtimeConfig) WithMemoryBudget(pages uint32) RuntimeConfig {
+	ret := c.clone()
+	ret.memoryBudgetPages = pages
+	return ret
+}
+
+// WithDeterministicProfile implements RuntimeConfig.WithDeterministicProfile
+func (c *runtimeConfig) WithDeterministicProfile(deterministicProfile bool) RuntimeConfig {
+	ret := c.clone()
+	// This panics instead of returning an error as the engine is chosen when the config is created.
+	if deterministicProfile && c.engineKind != engineKindInterpreter {
+		panic(errors.New("deterministic profile is only supported by the interpreter"))
+	}
+	ret.deterministicProfile = deterministicProfile
+	return ret
+}
+
 // CompiledModule is a WebAssembly module ready to be instantiated (Runtime.InstantiateModule) as an api.Module.
 //
 // In WebAssembly terminology, this is a decoded, validated, and possibly also compiled module. wazero avoids using
@@ -635,6 +686,17 @@ type ModuleConfig interface {
 	// Note: The caller is responsible to close any io.Reader they supply: It
 	// is not closed on api.Module Close.
 	WithRandSource(io.Reader) ModuleConfig
+
+	// WithWriteLimit caps the total number of bytes the module can write via
+	// functions such as "fd_write" in "wasi_snapshot_preview1". Defaults to
+	// zero, which means there is no limit.
+	//
+	// Once the limit is reached, writes are truncated and further writes fail
+	// with EIO. This prevents a guest looping on output from flooding the
+	// host.
+	//
+	// Note: The limit is the sum of all writes, regardless of file descriptor.
+	WithWriteLimit(limit uint64) ModuleConfig
 }
 
 type moduleConfig struct {
@@ -645,6 +707,7 @@ type moduleConfig struct {
 	stdout             io.Writer
 	stderr             io.Writer
 	randSource         io.Reader
+	writeLimit         uint64
 	walltime           sys.Walltime
 	walltimeResolution sys.ClockResolution
 	nanotime           sys.Nanotime
@@ -819,6 +882,13 @@ func (c *moduleConfig) WithRandSource(source io.Reader) ModuleConfig {
 	return ret
 }
 
+// WithWriteLimit implements ModuleConfig.WithWriteLimit
+func (c *moduleConfig) WithWriteLimit(limit uint64) ModuleConfig {
+	ret := c.clone()
+	ret.writeLimit = limit
+	return ret
+}
+
 // toSysContext creates a baseline wasm.Context configured by ModuleConfig.
 func (c *moduleConfig) toSysContext() (sysCtx *internalsys.Context, err error) {
 	var environ [][]byte // Intentionally doesn't pre-allocate to reduce logic to default to nil.
@@ -859,7 +929,7 @@ func (c *moduleConfig) toSysContext() (sysCtx *internalsys.Context, err error) {
 		}
 	}
 
-	return internalsys.NewContext(
+	if sysCtx, err = internalsys.NewContext(
 		math.MaxUint32,
 		c.args,
 		environ,
@@ -872,5 +942,9 @@ func (c *moduleConfig) toSysContext() (sysCtx *internalsys.Context, err error) {
 		c.nanosleep, c.osyield,
 		fs, guestPaths,
 		listeners,
-	)
+	); err != nil {
+		return
+	}
+	sysCtx.SetWriteLimit(c.writeLimit)
+	return
 }
@@ -19,6 +19,9 @@ import (
 	"github.com/tetratelabs/wazero/sys"
 )
 
+// testModuleCache is compared by identity in TestRuntimeConfig.
+var testModuleCache = NewModuleCache()
+
 func TestRuntimeConfig(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -75,6 +78,16 @@ func TestRuntimeConfig(t *testing.T) {
 			with:     func(c RuntimeConfig) RuntimeConfig { return c.WithCloseOnContextDone(true) },
 			expected: &runtimeConfig{ensureTermination: true},
 		},
+		{
+			name:     "WithMemoryBudget",
+			with:     func(c RuntimeConfig) RuntimeConfig { return c.WithMemoryBudget(10) },
+			expected: &runtimeConfig{memoryBudgetPages: 10},
+		},
+		{
+			name:     "WithModuleCache",
+			with:     func(c RuntimeConfig) RuntimeConfig { return c.WithModuleCache(testModuleCache) },
+			expected: &runtimeConfig{moduleCache: testModuleCache},
+		},
 	}
 
 	for _, tt := range tests {
@@ -96,6 +109,22 @@ func TestRuntimeConfig(t *testing.T) {
 		})
 		require.EqualError(t, err, "memoryLimitPages invalid: 65537 > 65536")
 	})
+
+	t.Run("WithDeterministicProfile", func(t *testing.T) {
+		input := &runtimeConfig{engineKind: engineKindInterpreter}
+		rc := input.WithDeterministicProfile(true)
+		require.Equal(t, &runtimeConfig{engineKind: engineKindInterpreter, deterministicProfile: true}, rc)
+		require.Equal(t, &runtimeConfig{engineKind: engineKindInterpreter}, input)
+	})
+
+	t.Run("WithDeterministicProfile compiler panics", func(t *testing.T) {
+		input := &runtimeConfig{engineKind: engineKindCompiler}
+		require.Equal(t, input, input.WithDeterministicProfile(false))
+		err := require.CapturePanic(func() {
+			input.WithDeterministicProfile(true)
+		})
+		require.EqualError(t, err, "deterministic profile is only supported by the interpreter")
+	})
 }
 
 func TestModuleConfig(t *testing.T) {
@@ -372,6 +401,22 @@ func TestModuleConfig_toSysContext(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "WithWriteLimit",
+			input: func() (ModuleConfig, func(t *testing.T, sys *internalsys.Context)) {
+				config := base.WithWriteLimit(2)
+				return config, func(t *testing.T, sys *internalsys.Context) {
+					write := sys.LimitWriter(func(buf []byte) (int, experimentalsys.Errno) {
+						return len(buf), 0
+					})
+					n, errno := write([]byte("abc"))
+					require.EqualErrno(t, 0, errno)
+					require.Equal(t, 2, n)
+					_, errno = write([]byte("d"))
+					require.EqualErrno(t, experimentalsys.EIO, errno)
+				}
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,39 @@
+package experimental
+
+import "github.com/tetratelabs/wazero/api"
+
+// CoreFeaturesThreads enables threads instructions ("threads").
+//
+// # Notes
+//
+//   - This is not yet implemented by default, so you will need to use
+//     wazero.NewRuntimeConfig().WithCoreFeatures(api.CoreFeaturesV2 | experimental.CoreFeaturesThreads)
+//   - Currently, shared memory and atomic instructions are decoded and
+//     validated, but executing an atomic instruction traps.
+//     See https://github.com/WebAssembly/threads/blob/main/proposals/threads/Overview.md
+const CoreFeaturesThreads = api.CoreFeatureSIMD << 1
+
+// CoreFeaturesMemory64 enables 64-bit memory limits ("memory64").
+//
+// # Notes
+//
+//   - This is not yet implemented by default, so you will need to use
+//     wazero.NewRuntimeConfig().WithCoreFeatures(api.CoreFeaturesV2 | experimental.CoreFeaturesMemory64)
+//   - Currently, 64-bit memory limits are only decoded. A module which defines
+//     or imports a 64-bit memory fails validation, as memory instructions
+//     only support 32-bit addresses.
+//     See https://github.com/WebAssembly/memory64/blob/main/proposals/memory64/Overview.md
+const CoreFeaturesMemory64 = api.CoreFeatureSIMD << 2
+
+// CoreFeaturesMultiMemory enables memory indexes in memory instructions
+// ("multi-memory").
+//
+// # Notes
+//
+//   - This is not yet implemented by default, so you will need to use
+//     wazero.NewRuntimeConfig().WithCoreFeatures(api.CoreFeaturesV2 | experimental.CoreFeaturesMultiMemory)
+//   - Currently, memory.size and memory.grow read their memory index as
+//     LEB128 instead of a reserved zero byte. A module still has at most one
+//     memory, so the only valid index is zero.
+//     See https://github.com/WebAssembly/multi-memory/blob/main/proposals/multi-memory/Overview.md
+const CoreFeaturesMultiMemory = api.CoreFeatureSIMD << 3
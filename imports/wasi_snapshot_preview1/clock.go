@@ -49,6 +49,8 @@ func clockResGetFn(_ context.Context, mod api.Module, params []uint64) sys.Errno
 		resolution = uint64(sysCtx.WalltimeResolution())
 	case wasip1.ClockIDMonotonic:
 		resolution = uint64(sysCtx.NanotimeResolution())
+	case wasip1.ClockIDProcessCputime, wasip1.ClockIDThreadCputime:
+		return sys.ENOTSUP
 	default:
 		return sys.EINVAL
 	}
@@ -105,6 +107,8 @@ func clockTimeGetFn(_ context.Context, mod api.Module, params []uint64) sys.Errn
 		val = sysCtx.WalltimeNanos()
 	case wasip1.ClockIDMonotonic:
 		val = sysCtx.Nanotime()
+	case wasip1.ClockIDProcessCputime, wasip1.ClockIDThreadCputime:
+		return sys.ENOTSUP
 	default:
 		return sys.EINVAL
 	}
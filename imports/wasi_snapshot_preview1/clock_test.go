@@ -7,6 +7,7 @@ import (
 	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasip1"
+	"github.com/tetratelabs/wazero/sys"
 )
 
 func Test_clockResGet(t *testing.T) {
@@ -70,6 +71,31 @@ func Test_clockResGet(t *testing.T) {
 	}
 }
 
+func Test_clockResGet_Configured(t *testing.T) {
+	nanotime := func() int64 { return 0 }
+	config := wazero.NewModuleConfig().WithNanotime(nanotime, sys.ClockResolution(250))
+	mod, r, log := requireProxyModule(t, config)
+	defer r.Close(testCtx)
+
+	resultResolution := 16 // arbitrary offset
+	expectedMemory := []byte{
+		'?',                                     // resultResolution is after this
+		0xfa, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, // little endian-encoded resolution (configured to 250).
+		'?', // stopped after encoding
+	}
+	maskMemory(t, mod, resultResolution+len(expectedMemory))
+
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.ClockResGetName, uint64(wasip1.ClockIDMonotonic), uint64(resultResolution))
+	require.Equal(t, `
+==> wasi_snapshot_preview1.clock_res_get(id=monotonic)
+<== (resolution=250,errno=ESUCCESS)
+`, "\n"+log.String())
+
+	actual, ok := mod.Memory().Read(uint32(resultResolution-1), uint32(len(expectedMemory)))
+	require.True(t, ok)
+	require.Equal(t, expectedMemory, actual)
+}
+
 func Test_clockResGet_Unsupported(t *testing.T) {
 	mod, r, log := requireProxyModule(t, wazero.NewModuleConfig())
 	defer r.Close(testCtx)
@@ -82,20 +108,20 @@ func Test_clockResGet_Unsupported(t *testing.T) {
 	}{
 		{
 			name:          "process cputime",
-			clockID:       2,
-			expectedErrno: wasip1.ErrnoInval,
+			clockID:       wasip1.ClockIDProcessCputime,
+			expectedErrno: wasip1.ErrnoNotsup,
 			expectedLog: `
 ==> wasi_snapshot_preview1.clock_res_get(id=2)
-<== (resolution=,errno=EINVAL)
+<== (resolution=,errno=ENOTSUP)
 `,
 		},
 		{
 			name:          "thread cputime",
-			clockID:       3,
-			expectedErrno: wasip1.ErrnoInval,
+			clockID:       wasip1.ClockIDThreadCputime,
+			expectedErrno: wasip1.ErrnoNotsup,
 			expectedLog: `
 ==> wasi_snapshot_preview1.clock_res_get(id=3)
-<== (resolution=,errno=EINVAL)
+<== (resolution=,errno=ENOTSUP)
 `,
 		},
 		{
@@ -215,20 +241,20 @@ func Test_clockTimeGet_Unsupported(t *testing.T) {
 	}{
 		{
 			name:          "process cputime",
-			clockID:       2,
-			expectedErrno: wasip1.ErrnoInval,
+			clockID:       wasip1.ClockIDProcessCputime,
+			expectedErrno: wasip1.ErrnoNotsup,
 			expectedLog: `
 ==> wasi_snapshot_preview1.clock_time_get(id=2,precision=0)
-<== (timestamp=,errno=EINVAL)
+<== (timestamp=,errno=ENOTSUP)
 `,
 		},
 		{
 			name:          "thread cputime",
-			clockID:       3,
-			expectedErrno: wasip1.ErrnoInval,
+			clockID:       wasip1.ClockIDThreadCputime,
+			expectedErrno: wasip1.ErrnoNotsup,
 			expectedLog: `
 ==> wasi_snapshot_preview1.clock_time_get(id=3,precision=0)
-<== (timestamp=,errno=EINVAL)
+<== (timestamp=,errno=ENOTSUP)
 `,
 		},
 		{
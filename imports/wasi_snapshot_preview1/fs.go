@@ -1252,7 +1252,8 @@ func (w *pwriter) Write(buf []byte) (n int, errno experimentalsys.Errno) {
 
 func fdWriteOrPwrite(mod api.Module, params []uint64, isPwrite bool) experimentalsys.Errno {
 	mem := mod.Memory()
-	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
+	sysCtx := mod.(*wasm.ModuleInstance).Sys
+	fsc := sysCtx.FS()
 
 	fd := int32(params[0])
 	iovs := uint32(params[1])
@@ -1271,7 +1272,7 @@ func fdWriteOrPwrite(mod api.Module, params []uint64, isPwrite bool) experimenta
 		resultNwritten = uint32(params[3])
 	}
 
-	nwritten, errno := writev(mem, iovs, iovsCount, writer)
+	nwritten, errno := writev(mem, iovs, iovsCount, sysCtx.LimitWriter(writer))
 	if errno != 0 {
 		return errno
 	}
@@ -1302,6 +1303,8 @@ func writev(mem api.Memory, iovs uint32, iovsCount uint32, writer func(buf []byt
 		nwritten += uint32(n)
 		if errno == experimentalsys.ENOSYS {
 			return 0, experimentalsys.EBADF // e.g. unimplemented for write
+		} else if errno != 0 && nwritten > 0 {
+			return nwritten, 0 // Report the partial write, like POSIX writev. The next call returns the error.
 		} else if errno != 0 {
 			return 0, errno
 		}
@@ -1410,6 +1410,38 @@ func Test_fdPrestatDirName(t *testing.T) {
 	require.Equal(t, expectedMemory, actual)
 }
 
+func Test_fdPrestatDirName_Root(t *testing.T) {
+	fsConfig := wazero.NewFSConfig().WithDirMount(t.TempDir(), "/")
+	mod, r, log := requireProxyModule(t, wazero.NewModuleConfig().WithFSConfig(fsConfig))
+	defer r.Close(testCtx)
+
+	// Like wasi-libc, first read the length of the name, then the name.
+	resultPrestat := uint32(16) // arbitrary offset
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdPrestatGetName, uint64(sys.FdPreopen), uint64(resultPrestat))
+	nameLen, ok := mod.Memory().ReadUint32Le(resultPrestat + 4) // after the tag and padding
+	require.True(t, ok)
+	require.Equal(t, uint32(len("/")), nameLen)
+	log.Reset()
+
+	path := uint32(1) // arbitrary offset
+	expectedMemory := []byte{
+		'?', // path is after this
+		'/', // the name isn't NUL terminated
+		'?',
+	}
+	maskMemory(t, mod, len(expectedMemory))
+
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdPrestatDirNameName, uint64(sys.FdPreopen), uint64(path), uint64(nameLen))
+	require.Equal(t, `
+==> wasi_snapshot_preview1.fd_prestat_dir_name(fd=3)
+<== (path=/,errno=ESUCCESS)
+`, "\n"+log.String())
+
+	actual, ok := mod.Memory().Read(0, uint32(len(expectedMemory)))
+	require.True(t, ok)
+	require.Equal(t, expectedMemory, actual)
+}
+
 func Test_fdPrestatDirName_Errors(t *testing.T) {
 	mod, dirFD, log, r := requireOpenFile(t, t.TempDir(), "tmp", nil, true)
 	defer r.Close(testCtx)
@@ -2238,6 +2270,45 @@ func Test_fdReaddir_Rewind(t *testing.T) {
 `, "\n"+log.String())
 }
 
+// Test_fdReaddir_Paged ensures a guest can page through a directory by
+// passing the d_next of the last entry read as the cookie of the next call.
+func Test_fdReaddir_Paged(t *testing.T) {
+	mod, r, log := requireProxyModule(t, wazero.NewModuleConfig().WithFS(fstest.FS))
+	defer r.Close(testCtx)
+
+	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
+
+	fd, errno := fsc.OpenFile(fsc.RootFS(), "dir", experimentalsys.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+
+	mem := mod.Memory()
+	const resultBufused, buf = 0, 8
+	fdReaddir := func(bufLen uint32, cookie uint64) []byte {
+		requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdReaddirName,
+			uint64(fd), buf, uint64(bufLen), cookie, uint64(resultBufused))
+		bufused, ok := mem.ReadUint32Le(resultBufused)
+		require.True(t, ok)
+		b, ok := mem.Read(buf, bufused)
+		require.True(t, ok)
+		return append([]byte{}, b...)
+	}
+
+	// The first page only fits the dot entries and the first file.
+	first := append(append(append([]byte{}, direntDot...), direntDotDot...), dirent1...)
+	require.Equal(t, first, fdReaddir(uint32(len(first)), 0))
+
+	// The second page resumes after the first file (d_next = 3).
+	rest := append(append([]byte{}, dirent2...), dirent3...)
+	require.Equal(t, rest, fdReaddir(4096, 3))
+
+	require.Equal(t, `
+==> wasi_snapshot_preview1.fd_readdir(fd=4,buf=8,buf_len=76,cookie=0)
+<== (bufused=76,errno=ESUCCESS)
+==> wasi_snapshot_preview1.fd_readdir(fd=4,buf=8,buf_len=4096,cookie=3)
+<== (bufused=53,errno=ESUCCESS)
+`, "\n"+log.String())
+}
+
 func Test_fdReaddir_Errors(t *testing.T) {
 	mod, r, log := requireProxyModule(t, wazero.NewModuleConfig().WithFS(fstest.FS))
 	defer r.Close(testCtx)
@@ -2817,6 +2888,84 @@ func Test_fdWrite(t *testing.T) {
 	require.Equal(t, []byte("wazero"), buf) // verify the file was actually written
 }
 
+// Test_fdWrite_WriteLimit ensures a guest writing past the configured limit
+// is cut off with EIO.
+func Test_fdWrite_WriteLimit(t *testing.T) {
+	var stdout bytes.Buffer
+	mod, r, _ := requireProxyModule(t, wazero.NewModuleConfig().
+		WithStdout(&stdout).WithWriteLimit(8))
+	defer r.Close(testCtx)
+
+	iovs := uint32(1) // arbitrary offset
+	initialMemory := []byte{
+		'?',        // `iovs` is after this
+		9, 0, 0, 0, // = iovs[0].offset
+		6, 0, 0, 0, // = iovs[0].length
+		'w', 'a', 'z', 'e', 'r', 'o', // iovs[0].length bytes
+	}
+	iovsCount := uint32(1)       // The count of iovs
+	resultNwritten := uint32(16) // arbitrary offset
+
+	ok := mod.Memory().Write(0, initialMemory)
+	require.True(t, ok)
+
+	requireNwritten := func(expectedErrno wasip1.Errno, expected uint32) {
+		requireErrnoResult(t, expectedErrno, mod, wasip1.FdWriteName, uint64(sys.FdStdout), uint64(iovs), uint64(iovsCount), uint64(resultNwritten))
+		nwritten, ok := mod.Memory().ReadUint32Le(resultNwritten)
+		require.True(t, ok)
+		require.Equal(t, expected, nwritten)
+	}
+
+	// The first write is within the limit.
+	requireNwritten(wasip1.ErrnoSuccess, 6)
+	// The second write is truncated to the remaining two bytes.
+	requireNwritten(wasip1.ErrnoSuccess, 2)
+	// The limit is exhausted, so the guest is cut off.
+	requireNwritten(wasip1.ErrnoIo, 2) // nwritten is unchanged
+
+	require.Equal(t, "wazerowa", stdout.String())
+}
+
+// Test_fdWrite_WriteLimit_Iovecs ensures a write of several iovecs which
+// reaches the limit part way reports the bytes written before it, instead of
+// failing with EIO.
+func Test_fdWrite_WriteLimit_Iovecs(t *testing.T) {
+	var stdout bytes.Buffer
+	mod, r, _ := requireProxyModule(t, wazero.NewModuleConfig().
+		WithStdout(&stdout).WithWriteLimit(8))
+	defer r.Close(testCtx)
+
+	iovs := uint32(1) // arbitrary offset
+	initialMemory := []byte{
+		'?',         // `iovs` is after this
+		25, 0, 0, 0, // = iovs[0].offset
+		6, 0, 0, 0, // = iovs[0].length
+		31, 0, 0, 0, // = iovs[1].offset
+		6, 0, 0, 0, // = iovs[1].length
+		37, 0, 0, 0, // = iovs[2].offset
+		1, 0, 0, 0, // = iovs[2].length
+		'w', 'a', 'z', 'e', 'r', 'o', // iovs[0].length bytes
+		'W', 'A', 'Z', 'E', 'R', 'O', // iovs[1].length bytes
+		'!', // iovs[2].length bytes
+	}
+	iovsCount := uint32(3)       // The count of iovs
+	resultNwritten := uint32(40) // arbitrary offset
+
+	ok := mod.Memory().Write(0, initialMemory)
+	require.True(t, ok)
+
+	// The second iovec is truncated to the remaining two bytes, and the third
+	// fails as the limit is exhausted.
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdWriteName, uint64(sys.FdStdout), uint64(iovs), uint64(iovsCount), uint64(resultNwritten))
+	nwritten, ok := mod.Memory().ReadUint32Le(resultNwritten)
+	require.True(t, ok)
+	require.Equal(t, uint32(8), nwritten)
+	require.Equal(t, "wazeroWA", stdout.String())
+
+	// Nothing was written by the next call, so it fails.
+	requireErrnoResult(t, wasip1.ErrnoIo, mod, wasip1.FdWriteName, uint64(sys.FdStdout), uint64(iovs), uint64(iovsCount), uint64(resultNwritten))
+}
+
 func Test_fdWrite_Errors(t *testing.T) {
 	tmpDir := t.TempDir() // open before loop to ensure no locking problems.
 	pathName := "test_path"
@@ -3060,6 +3209,8 @@ func Test_pathFilestatGet(t *testing.T) {
 	initialMemoryDir := append([]byte{'?'}, dir...)
 	initialMemoryFileInDir := append([]byte{'?'}, fileInDir...)
 	initialMemoryNotExists := []byte{'?', '?'}
+	escape := "../" + file
+	initialMemoryEscape := append([]byte{'?'}, escape...)
 
 	mod, r, log := requireProxyModule(t, wazero.NewModuleConfig().WithFS(fstest.FS))
 	defer r.Close(testCtx)
@@ -3173,6 +3324,18 @@ func Test_pathFilestatGet(t *testing.T) {
 			expectedLog: `
 ==> wasi_snapshot_preview1.path_filestat_get(fd=3,flags=,path=?)
 <== (filestat=,errno=ENOENT)
+`,
+		},
+		{
+			name:           "path escapes root",
+			fd:             sys.FdPreopen,
+			memory:         initialMemoryEscape,
+			pathLen:        uint32(len(escape)),
+			resultFilestat: uint32(len(escape)) + 1,
+			expectedErrno:  wasip1.ErrnoPerm,
+			expectedLog: `
+==> wasi_snapshot_preview1.path_filestat_get(fd=3,flags=,path=../animals.txt)
+<== (filestat=,errno=EPERM)
 `,
 		},
 		{
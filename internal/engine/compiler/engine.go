@@ -1281,7 +1281,7 @@ func compileWasmFunction(buf asm.Buffer, cmp compiler, ir *wazeroir.CompilationR
 			fmt.Printf("compiling op=%s: %s\n", op.Kind, cmp)
 		}
 		switch op.Kind {
-		case wazeroir.OperationKindUnreachable:
+		case wazeroir.OperationKindUnreachable, wazeroir.OperationKindUnsupported:
 			err = cmp.compileUnreachable()
 		case wazeroir.OperationKindLabel:
 		// label op is already handled ^^.
@@ -634,6 +634,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 	typeIDs := moduleInst.TypeIDs
 	dataInstances := moduleInst.DataInstances
 	elementInstances := moduleInst.ElementInstances
+	deterministic := moduleInst.DeterministicProfile
 	ce.pushFrame(frame)
 	body := frame.f.parent.body
 	bodyLen := uint64(len(body))
@@ -650,6 +651,8 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			frame.pc++
 		case wazeroir.OperationKindUnreachable:
 			panic(wasmruntime.ErrRuntimeUnreachable)
+		case wazeroir.OperationKindUnsupported:
+			panic(&wasmruntime.ErrUnsupportedOpcode{Op: op.B1, SubOp: uint32(op.U1)})
 		case wazeroir.OperationKindBr:
 			frame.pc = op.U1
 		case wazeroir.OperationKindBrIf:
@@ -1002,6 +1005,9 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 				v := math.Float64frombits(v1) + math.Float64frombits(v2)
 				ce.pushValue(math.Float64bits(v))
 			}
+			if deterministic {
+				ce.canonicalizeNaNResult(op)
+			}
 			frame.pc++
 		case wazeroir.OperationKindSub:
 			v2 := ce.popValue()
@@ -1017,6 +1023,9 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 				v := math.Float64frombits(v1) - math.Float64frombits(v2)
 				ce.pushValue(math.Float64bits(v))
 			}
+			if deterministic {
+				ce.canonicalizeNaNResult(op)
+			}
 			frame.pc++
 		case wazeroir.OperationKindMul:
 			v2 := ce.popValue()
@@ -1032,6 +1041,9 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 				v := math.Float64frombits(v2) * math.Float64frombits(v1)
 				ce.pushValue(math.Float64bits(v))
 			}
+			if deterministic {
+				ce.canonicalizeNaNResult(op)
+			}
 			frame.pc++
 		case wazeroir.OperationKindClz:
 			v := ce.popValue()
@@ -1103,6 +1115,9 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			case wazeroir.SignedTypeFloat64:
 				ce.pushValue(math.Float64bits(math.Float64frombits(v1) / math.Float64frombits(v2)))
 			}
+			if deterministic {
+				ce.canonicalizeNaNResult(op)
+			}
 			frame.pc++
 		case wazeroir.OperationKindRem:
 			v2, v1 := ce.popValue(), ce.popValue()
@@ -1240,6 +1255,9 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 				v := moremath.WasmCompatCeilF64(math.Float64frombits(ce.popValue()))
 				ce.pushValue(math.Float64bits(v))
 			}
+			if deterministic {
+				ce.canonicalizeNaNResult(op)
+			}
 			frame.pc++
 		case wazeroir.OperationKindFloor:
 			if op.B1 == 0 {
@@ -1251,6 +1269,9 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 				v := moremath.WasmCompatFloorF64(math.Float64frombits(ce.popValue()))
 				ce.pushValue(math.Float64bits(v))
 			}
+			if deterministic {
+				ce.canonicalizeNaNResult(op)
+			}
 			frame.pc++
 		case wazeroir.OperationKindTrunc:
 			if op.B1 == 0 {
@@ -1262,6 +1283,9 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 				v := moremath.WasmCompatTruncF64(math.Float64frombits(ce.popValue()))
 				ce.pushValue(math.Float64bits(v))
 			}
+			if deterministic {
+				ce.canonicalizeNaNResult(op)
+			}
 			frame.pc++
 		case wazeroir.OperationKindNearest:
 			if op.B1 == 0 {
@@ -1273,6 +1297,9 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 				f := math.Float64frombits(ce.popValue())
 				ce.pushValue(math.Float64bits(moremath.WasmCompatNearestF64(f)))
 			}
+			if deterministic {
+				ce.canonicalizeNaNResult(op)
+			}
 			frame.pc++
 		case wazeroir.OperationKindSqrt:
 			if op.B1 == 0 {
@@ -1284,6 +1311,9 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 				v := math.Sqrt(math.Float64frombits(ce.popValue()))
 				ce.pushValue(math.Float64bits(v))
 			}
+			if deterministic {
+				ce.canonicalizeNaNResult(op)
+			}
 			frame.pc++
 		case wazeroir.OperationKindMin:
 			if op.B1 == 0 {
@@ -1294,6 +1324,9 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 				v1 := math.Float64frombits(ce.popValue())
 				ce.pushValue(math.Float64bits(moremath.WasmCompatMin64(v1, v2)))
 			}
+			if deterministic {
+				ce.canonicalizeNaNResult(op)
+			}
 			frame.pc++
 		case wazeroir.OperationKindMax:
 			if op.B1 == 0 {
@@ -1304,6 +1337,9 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 				v1 := math.Float64frombits(ce.popValue())
 				ce.pushValue(math.Float64bits(moremath.WasmCompatMax64(v1, v2)))
 			}
+			if deterministic {
+				ce.canonicalizeNaNResult(op)
+			}
 			frame.pc++
 		case wazeroir.OperationKindCopysign:
 			if op.B1 == 0 {
@@ -1569,10 +1605,16 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 		case wazeroir.OperationKindF32DemoteFromF64:
 			v := float32(math.Float64frombits(ce.popValue()))
 			ce.pushValue(uint64(math.Float32bits(v)))
+			if deterministic {
+				ce.canonicalizeNaNResult(op)
+			}
 			frame.pc++
 		case wazeroir.OperationKindF64PromoteFromF32:
 			v := float64(math.Float32frombits(uint32(ce.popValue())))
 			ce.pushValue(math.Float64bits(v))
+			if deterministic {
+				ce.canonicalizeNaNResult(op)
+			}
 			frame.pc++
 		case wazeroir.OperationKindExtend:
 			if op.B1 == 1 {
@@ -3896,6 +3938,48 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 	ce.popFrame()
 }
 
+// canonicalizeNaNResult replaces a NaN result of op on top of the stack with the canonical NaN of the same width,
+// for wasm.Store SetDeterministicProfile. Otherwise, the sign and payload of a NaN depend on the operands and the
+// platform.
+func (ce *callEngine) canonicalizeNaNResult(op *wazeroir.UnionOperation) {
+	var is32 bool
+	switch op.Kind {
+	case wazeroir.OperationKindAdd, wazeroir.OperationKindSub, wazeroir.OperationKindMul:
+		switch wazeroir.UnsignedType(op.B1) {
+		case wazeroir.UnsignedTypeF32:
+			is32 = true
+		case wazeroir.UnsignedTypeF64:
+		default:
+			return
+		}
+	case wazeroir.OperationKindDiv:
+		switch wazeroir.SignedType(op.B1) {
+		case wazeroir.SignedTypeFloat32:
+			is32 = true
+		case wazeroir.SignedTypeFloat64:
+		default:
+			return
+		}
+	case wazeroir.OperationKindCeil, wazeroir.OperationKindFloor, wazeroir.OperationKindTrunc,
+		wazeroir.OperationKindNearest, wazeroir.OperationKindSqrt, wazeroir.OperationKindMin, wazeroir.OperationKindMax:
+		is32 = wazeroir.Float(op.B1) == wazeroir.Float32
+	case wazeroir.OperationKindF32DemoteFromF64:
+		is32 = true
+	case wazeroir.OperationKindF64PromoteFromF32:
+	default:
+		return
+	}
+
+	top := len(ce.stack) - 1
+	if is32 {
+		if math.IsNaN(float64(math.Float32frombits(uint32(ce.stack[top])))) {
+			ce.stack[top] = uint64(moremath.F32CanonicalNaNBits)
+		}
+	} else if math.IsNaN(math.Float64frombits(ce.stack[top])) {
+		ce.stack[top] = moremath.F64CanonicalNaNBits
+	}
+}
+
 func WasmCompatMax32bits(v1, v2 uint32) uint64 {
 	return uint64(math.Float32bits(moremath.WasmCompatMax32(
 		math.Float32frombits(v1),
@@ -2,14 +2,17 @@ package interpreter
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"strconv"
 	"testing"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
 	"github.com/tetratelabs/wazero/internal/wazeroir"
 )
 
@@ -451,3 +454,77 @@ func TestEngine_CachedCompiledFunctionPerModule(t *testing.T) {
 	_, ok = e.getCompiledFunctions(m)
 	require.False(t, ok)
 }
+
+func TestInterpreter_UnsupportedOpcode(t *testing.T) {
+	i32 := []wasm.ValueType{wasm.ValueTypeI32}
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: i32, Results: i32}},
+		FunctionSection: []wasm.Index{0},
+		MemorySection:   &wasm.Memory{Min: 1},
+		// (func (param i32) (result i32) local.get 0 i32.atomic.load)
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeLocalGet, 0, wasm.OpcodeAtomicPrefix, wasm.OpcodeAtomicI32Load, 0x2, 0x0, wasm.OpcodeEnd,
+		}}},
+		ID: wasm.ModuleID{1},
+	}
+	m.TypeSection[0].CacheNumInUint64()
+
+	e := NewEngine(testCtx, api.CoreFeaturesV2|experimental.CoreFeaturesThreads, nil)
+	err := e.CompileModule(testCtx, m, nil, false)
+	require.NoError(t, err)
+
+	mi := &wasm.ModuleInstance{
+		TypeIDs:        []wasm.FunctionTypeID{0},
+		MemoryInstance: &wasm.MemoryInstance{Buffer: make([]byte, wasm.MemoryPageSize), Min: 1, Cap: 1, Max: 1},
+		Source:         m,
+	}
+	mi.Engine, err = e.NewModuleEngine(m, mi)
+	require.NoError(t, err)
+
+	_, err = mi.Engine.NewFunction(0).Call(testCtx, 0)
+	require.EqualError(t, err, `wasm error: unsupported opcode 0xfe 0x10
+wasm stack trace:
+	.$0(i32) i32`)
+
+	var unsupportedErr *wasmruntime.ErrUnsupportedOpcode
+	require.True(t, errors.As(err, &unsupportedErr))
+	require.Equal(t, wasmruntime.ErrUnsupportedOpcode{Op: wasm.OpcodeAtomicPrefix, SubOp: uint32(wasm.OpcodeAtomicI32Load)}, *unsupportedErr)
+}
+
+func TestInterpreter_DeterministicProfile(t *testing.T) {
+	f32, f64 := wasm.ValueTypeF32, wasm.ValueTypeF64
+	m := &wasm.Module{
+		TypeSection: []wasm.FunctionType{{
+			Params:  []wasm.ValueType{f64, f32},
+			Results: []wasm.ValueType{f64, f32, f32, f32, f64},
+		}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeLocalGet, 0, wasm.OpcodeF64Const, 0, 0, 0, 0, 0, 0, 0xf0, 0x3f, wasm.OpcodeF64Add, // 1.0 + $0
+			wasm.OpcodeLocalGet, 1, wasm.OpcodeF32Const, 0, 0, 0, 0, wasm.OpcodeF32Min,
+			wasm.OpcodeLocalGet, 1, wasm.OpcodeF32Sqrt,
+			wasm.OpcodeLocalGet, 0, wasm.OpcodeF32DemoteF64,
+			wasm.OpcodeLocalGet, 1, wasm.OpcodeF64PromoteF32,
+			wasm.OpcodeEnd,
+		}}},
+		ID: wasm.ModuleID{1},
+	}
+	m.TypeSection[0].CacheNumInUint64()
+
+	e := NewEngine(testCtx, api.CoreFeaturesV2, nil)
+	err := e.CompileModule(testCtx, m, nil, false)
+	require.NoError(t, err)
+
+	mi := &wasm.ModuleInstance{TypeIDs: []wasm.FunctionTypeID{0}, Source: m, DeterministicProfile: true}
+	mi.Engine, err = e.NewModuleEngine(m, mi)
+	require.NoError(t, err)
+
+	// Negative NaNs with a payload, which would otherwise propagate to the results.
+	params := []uint64{0xfff4_0000_0000_0123, 0xff80_0123}
+	expected := []uint64{0x7ff8_0000_0000_0000, 0x7fc0_0000, 0x7fc0_0000, 0x7fc0_0000, 0x7ff8_0000_0000_0000}
+	for i := 0; i < 2; i++ {
+		results, err := mi.Engine.NewFunction(0).Call(testCtx, params...)
+		require.NoError(t, err)
+		require.Equal(t, expected, results)
+	}
+}
@@ -1124,7 +1124,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 		state.push(sl)
 
 	case wasm.OpcodeMemorySize:
-		state.pc++ // skips the memory index.
+		c.readI32u() // skips the memory index.
 		if state.unreachable {
 			break
 		}
@@ -1157,7 +1157,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 		state.push(memSize)
 
 	case wasm.OpcodeMemoryGrow:
-		state.pc++ // skips the memory index.
+		c.readI32u() // skips the memory index.
 		if state.unreachable {
 			break
 		}
@@ -1,6 +1,7 @@
 package bench
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/tetratelabs/wazero/api"
@@ -17,4 +18,14 @@ func BenchmarkCodec(b *testing.B) {
 			}
 		}
 	})
+	b.Run("binary.DecodeModuleReader", func(b *testing.B) {
+		b.ReportAllocs()
+		r := bytes.NewReader(caseWasm)
+		for i := 0; i < b.N; i++ {
+			r.Reset(caseWasm)
+			if _, err := binary.DecodeModuleReader(r, api.CoreFeaturesV2, wasm.MemoryLimitPages, binary.DecodeOptions{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
 }
@@ -57,6 +57,17 @@ var tests = map[string]testCase{
 	"lookup function":                                                  {f: testLookupFunction},
 	"memory grow in recursive call":                                    {f: testMemoryGrowInRecursiveCall},
 	"call":                                                             {f: testCall},
+	"merged modules":                                                   {f: testMergedModules},
+	"memory offset overflow":                                           {f: testMemoryOffsetOverflow},
+	"host function with multiple results and error":                    {f: testHostFunctionMultipleResultsError},
+	"host function reads guest string":                                 {f: testHostFunctionReadsGuestString},
+	"host function panic is a trap":                                    {f: testHostFunctionPanicTrap},
+	"simd splat and lanes":                                             {f: testSIMDSplatLanes},
+	"local.tee feeds add":                                              {f: testLocalTee},
+	"memory.size after memory.grow":                                    {f: testMemorySizeGrow},
+	"if without else":                                                  {f: testIfWithoutElse},
+	"replaced function body":                                           {f: testReplaceFunctionBody},
+	"injected entry call":                                              {f: testInjectEntryCall},
 	"module memory":                                                    {f: testModuleMemory},
 	"two indirection to host":                                          {f: testTwoIndirection},
 	"before listener globals":                                          {f: testBeforeListenerGlobals},
@@ -2075,3 +2086,411 @@ func testManyParamsResultsCallManyConstsAndPickLastVectorListener(t *testing.T,
 <-- 5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f5f
 `, "\n"+buf.String())
 }
+
+func testMergedModules(t *testing.T, r wazero.Runtime) {
+	i32i32_i32 := wasm.FunctionType{
+		Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i32}, ParamNumInUint64: 2, ResultNumInUint64: 1,
+	}
+	v_i32 := wasm.FunctionType{Results: []wasm.ValueType{i32}, ResultNumInUint64: 1}
+
+	main := &wasm.Module{
+		TypeSection:         []wasm.FunctionType{v_i32, i32i32_i32},
+		ImportSection:       []wasm.Import{{Type: wasm.ExternTypeFunc, Module: "lib", Name: "add", DescFunc: 1}},
+		ImportFunctionCount: 1,
+		FunctionSection:     []wasm.Index{0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeI32Const, 2, wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+		},
+		ExportSection: []wasm.Export{{Type: wasm.ExternTypeFunc, Name: "run", Index: 1}},
+	}
+	lib := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{i32i32_i32},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeI32Add, wasm.OpcodeEnd}},
+		},
+		ExportSection: []wasm.Export{{Type: wasm.ExternTypeFunc, Name: "add", Index: 0}},
+	}
+
+	merged, err := wasm.MergeModules(main, lib, "lib")
+	require.NoError(t, err)
+
+	// The merged module doesn't import "lib", so it instantiates without it.
+	inst, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(merged))
+	require.NoError(t, err)
+
+	res, err := inst.ExportedFunction("run").Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{3}, res)
+}
+
+// testMemoryOffsetOverflow ensures the effective address of a load is computed without wrapping at 32 bits.
+func testMemoryOffsetOverflow(t *testing.T, r wazero.Runtime) {
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{{
+			Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}, ParamNumInUint64: 1, ResultNumInUint64: 1,
+		}},
+		FunctionSection: []wasm.Index{0},
+		MemorySection:   &wasm.Memory{Min: 1, Max: 1, IsMaxEncoded: true},
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32Load, 0x2, 0xff, 0xff, 0xff, 0xff, 0x0f, // offset=0xffffffff
+			wasm.OpcodeEnd,
+		}}},
+		ExportSection: []wasm.Export{{Name: "load", Type: wasm.ExternTypeFunc, Index: 0}},
+	})
+
+	inst, err := r.Instantiate(testCtx, bin)
+	require.NoError(t, err)
+
+	load := inst.ExportedFunction("load")
+	// Address 1 would wrap to zero, which is in range, if the offset were added in 32-bit space.
+	for _, addr := range []uint64{0, 1} {
+		_, err = load.Call(testCtx, addr)
+		require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+	}
+}
+
+func testHostFunctionMultipleResultsError(t *testing.T, r wazero.Runtime) {
+	_, err := r.NewHostModuleBuilder("env").
+		NewFunctionBuilder().
+		WithFunc(func(x, y uint32) (uint32, uint32, error) {
+			if y == 0 {
+				return 0, 0, errors.New("division by zero")
+			}
+			return x / y, x % y, nil
+		}).
+		Export("divmod").
+		Instantiate(testCtx)
+	require.NoError(t, err)
+
+	i32i32_i32i32 := wasm.FunctionType{
+		Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i32, i32}, ParamNumInUint64: 2, ResultNumInUint64: 2,
+	}
+	inst, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:         []wasm.FunctionType{i32i32_i32i32},
+		ImportSection:       []wasm.Import{{Type: wasm.ExternTypeFunc, Module: "env", Name: "divmod", DescFunc: 0}},
+		ImportFunctionCount: 1,
+		FunctionSection:     []wasm.Index{0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+		},
+		ExportSection: []wasm.Export{{Name: "divmod", Type: wasm.ExternTypeFunc, Index: 1}},
+	}))
+	require.NoError(t, err)
+
+	divmod := inst.ExportedFunction("divmod")
+	res, err := divmod.Call(testCtx, 7, 2)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{3, 1}, res)
+
+	// A non-nil error from the host function traps.
+	_, err = divmod.Call(testCtx, 7, 0)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "division by zero")
+}
+
+// testHostFunctionReadsGuestString ensures a host function which declares api.Module after context.Context is passed
+// the calling module, so it can read data the guest passes by offset and length.
+func testHostFunctionReadsGuestString(t *testing.T, r wazero.Runtime) {
+	var logged string
+	_, err := r.NewHostModuleBuilder("env").
+		NewFunctionBuilder().
+		WithFunc(func(ctx context.Context, m api.Module, offset, byteCount uint32) {
+			buf, ok := m.Memory().Read(offset, byteCount)
+			require.True(t, ok)
+			logged = string(buf)
+		}).
+		Export("log").
+		Instantiate(testCtx)
+	require.NoError(t, err)
+
+	inst, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:         []wasm.FunctionType{{Params: []wasm.ValueType{i32, i32}, ParamNumInUint64: 2}, {}},
+		ImportSection:       []wasm.Import{{Type: wasm.ExternTypeFunc, Module: "env", Name: "log", DescFunc: 0}},
+		ImportFunctionCount: 1,
+		FunctionSection:     []wasm.Index{1},
+		MemorySection:       &wasm.Memory{Min: 1},
+		DataSection: []wasm.DataSegment{
+			{OffsetExpression: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{8}}, Init: []byte("hello")},
+		},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeI32Const, 8, wasm.OpcodeI32Const, 5, wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+		},
+		ExportSection: []wasm.Export{{Name: "run", Type: wasm.ExternTypeFunc, Index: 1}},
+	}))
+	require.NoError(t, err)
+
+	_, err = inst.ExportedFunction("run").Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, "hello", logged)
+}
+
+// testHostFunctionPanicTrap ensures a panic in a host function is recovered into an error returned by the call into
+// the guest which called it, wrapping the recovered error.
+func testHostFunctionPanicTrap(t *testing.T, r wazero.Runtime) {
+	errHost := errors.New("host failure")
+	_, err := r.NewHostModuleBuilder("env").
+		NewFunctionBuilder().
+		WithFunc(func(v uint32) {
+			if v == 0 {
+				panic("host panic")
+			}
+			panic(errHost)
+		}).
+		Export("fail").
+		Instantiate(testCtx)
+	require.NoError(t, err)
+
+	inst, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:         []wasm.FunctionType{{Params: []wasm.ValueType{i32}, ParamNumInUint64: 1}},
+		ImportSection:       []wasm.Import{{Type: wasm.ExternTypeFunc, Module: "env", Name: "fail", DescFunc: 0}},
+		ImportFunctionCount: 1,
+		FunctionSection:     []wasm.Index{0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+		},
+		ExportSection: []wasm.Export{{Name: "run", Type: wasm.ExternTypeFunc, Index: 1}},
+	}))
+	require.NoError(t, err)
+
+	run := inst.ExportedFunction("run")
+
+	_, err = run.Call(testCtx, 0)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "host panic (recovered by wazero)")
+
+	// The module is still usable after a trap.
+	_, err = run.Call(testCtx, 1)
+	require.ErrorIs(t, err, errHost)
+	require.Contains(t, err.Error(), "host failure (recovered by wazero)")
+}
+
+// testSIMDSplatLanes ensures the most common vector instructions emitted by compilers: splat, extract_lane,
+// replace_lane, v128.store and v128.load.
+func testSIMDSplatLanes(t *testing.T, r wazero.Runtime) {
+	inst, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32, i32}},
+			{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}},
+		},
+		FunctionSection: []wasm.Index{0, 1},
+		MemorySection:   &wasm.Memory{Min: 1},
+		CodeSection: []wasm.Code{
+			{LocalTypes: []wasm.ValueType{v128}, Body: []byte{
+				// local[1] = i32x4.replace_lane 2 (i32x4.splat local[0]) 7
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeVecPrefix, wasm.OpcodeVecI32x4Splat,
+				wasm.OpcodeI32Const, 7,
+				wasm.OpcodeVecPrefix, wasm.OpcodeVecI32x4ReplaceLane, 2,
+				wasm.OpcodeLocalSet, 1,
+				// Return lane 1 and 2.
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeVecPrefix, wasm.OpcodeVecI32x4ExtractLane, 1,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeVecPrefix, wasm.OpcodeVecI32x4ExtractLane, 2,
+				wasm.OpcodeEnd,
+			}},
+			{Body: []byte{
+				// v128.store 0 (i8x16.splat local[0])
+				wasm.OpcodeI32Const, 0,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeVecPrefix, wasm.OpcodeVecI8x16Splat,
+				wasm.OpcodeVecPrefix, wasm.OpcodeVecV128Store, 0x04, 0x00, // align=2^4, offset=0
+				// Return the last lane of v128.load 0.
+				wasm.OpcodeI32Const, 0,
+				wasm.OpcodeVecPrefix, wasm.OpcodeVecV128Load, 0x04, 0x00, // align=2^4, offset=0
+				wasm.OpcodeVecPrefix, wasm.OpcodeVecI8x16ExtractLaneU, 15,
+				wasm.OpcodeEnd,
+			}},
+		},
+		ExportSection: []wasm.Export{
+			{Name: "i32x4", Type: wasm.ExternTypeFunc, Index: 0},
+			{Name: "i8x16", Type: wasm.ExternTypeFunc, Index: 1},
+		},
+	}))
+	require.NoError(t, err)
+
+	results, err := inst.ExportedFunction("i32x4").Call(testCtx, 42)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{42, 7}, results)
+
+	results, err = inst.ExportedFunction("i8x16").Call(testCtx, 0x1ff) // i8x16.splat truncates to 0xff.
+	require.NoError(t, err)
+	require.Equal(t, []uint64{0xff}, results)
+	buf, ok := inst.Memory().Read(0, 17)
+	require.True(t, ok)
+	require.Equal(t, append(bytes.Repeat([]byte{0xff}, 16), 0), buf)
+}
+
+func testLocalTee(t *testing.T, r wazero.Runtime) {
+	inst, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32, i32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{
+			{LocalTypes: []wasm.ValueType{i32}, Body: []byte{
+				// local[1] = local[0], leaving it on the stack for the add.
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalTee, 1,
+				wasm.OpcodeI32Const, 10,
+				wasm.OpcodeI32Add,
+				// Return the sum and local[1].
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeEnd,
+			}},
+		},
+		ExportSection: []wasm.Export{{Name: "tee", Type: wasm.ExternTypeFunc, Index: 0}},
+	}))
+	require.NoError(t, err)
+
+	results, err := inst.ExportedFunction("tee").Call(testCtx, 5)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{15, 5}, results)
+}
+
+func testIfWithoutElse(t *testing.T, r wazero.Runtime) {
+	inst, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{
+			{LocalTypes: []wasm.ValueType{i32}, Body: []byte{
+				wasm.OpcodeI32Const, 10,
+				wasm.OpcodeLocalSet, 1,
+				// (if (local.get 0) (then (local.set 1 (i32.const 20))))
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeIf, 0x40,
+				wasm.OpcodeI32Const, 20,
+				wasm.OpcodeLocalSet, 1,
+				wasm.OpcodeEnd,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeEnd,
+			}},
+		},
+		ExportSection: []wasm.Export{{Name: "if", Type: wasm.ExternTypeFunc, Index: 0}},
+	}))
+	require.NoError(t, err)
+
+	// The then-branch is skipped when the condition is false.
+	results, err := inst.ExportedFunction("if").Call(testCtx, 0)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{10}, results)
+
+	results, err = inst.ExportedFunction("if").Call(testCtx, 1)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{20}, results)
+}
+
+func testReplaceFunctionBody(t *testing.T, r wazero.Runtime) {
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeEnd}},
+		},
+		GlobalSection: []wasm.Global{{
+			Type: wasm.GlobalType{ValType: i32, Mutable: true},
+			Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}},
+		}},
+		ExportSection: []wasm.Export{
+			{Name: "f", Type: wasm.ExternTypeFunc, Index: 0},
+			{Name: "calls", Type: wasm.ExternTypeGlobal, Index: 0},
+		},
+	}
+
+	// Double the parameter instead of returning it.
+	err := m.ReplaceFunctionBody(0, nil, []byte{
+		wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Add, wasm.OpcodeEnd,
+	})
+	require.NoError(t, err)
+
+	// Count calls in the exported global.
+	err = m.PrependToFunctionBody(0, nil, []byte{
+		wasm.OpcodeGlobalGet, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Add, wasm.OpcodeGlobalSet, 0,
+	})
+	require.NoError(t, err)
+
+	inst, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(m))
+	require.NoError(t, err)
+
+	for i := uint64(1); i <= 3; i++ {
+		results, err := inst.ExportedFunction("f").Call(testCtx, i)
+		require.NoError(t, err)
+		require.Equal(t, []uint64{i * 2}, results)
+	}
+	require.Equal(t, uint64(3), inst.ExportedGlobal("calls").Get())
+}
+
+func testInjectEntryCall(t *testing.T, r wazero.Runtime) {
+	var entered []uint32
+	_, err := r.NewHostModuleBuilder("profiler").
+		NewFunctionBuilder().WithFunc(func(funcIdx uint32) { entered = append(entered, funcIdx) }).Export("enter").
+		Instantiate(testCtx)
+	require.NoError(t, err)
+
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Results: []wasm.ValueType{i32}}},
+		FunctionSection: []wasm.Index{0, 0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeCall, 1, wasm.OpcodeCall, 1, wasm.OpcodeI32Add, wasm.OpcodeEnd}}, // calls func[1] twice
+			{Body: []byte{wasm.OpcodeI32Const, 21, wasm.OpcodeEnd}},
+		},
+		ExportSection: []wasm.Export{{Name: "main", Type: wasm.ExternTypeFunc, Index: 0}},
+	}
+	require.NoError(t, m.InjectEntryCall("profiler", "enter"))
+
+	inst, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(m))
+	require.NoError(t, err)
+
+	results, err := inst.ExportedFunction("main").Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{42}, results)
+
+	// The import is func[0], so main is func[1] and the function it calls is func[2].
+	require.Equal(t, []uint32{1, 2, 2}, entered)
+}
+
+func testMemorySizeGrow(t *testing.T, r wazero.Runtime) {
+	max := uint32(5)
+	inst, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			{Results: []wasm.ValueType{i32}},
+			{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}},
+		},
+		FunctionSection: []wasm.Index{0, 1},
+		MemorySection:   &wasm.Memory{Min: 2, Max: max, IsMaxEncoded: true},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeMemorySize, 0x00, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeMemoryGrow, 0x00, wasm.OpcodeEnd}},
+		},
+		ExportSection: []wasm.Export{
+			{Name: "size", Type: wasm.ExternTypeFunc, Index: 0},
+			{Name: "grow", Type: wasm.ExternTypeFunc, Index: 1},
+		},
+	}))
+	require.NoError(t, err)
+	size, grow := inst.ExportedFunction("size"), inst.ExportedFunction("grow")
+
+	// The declared minimum at instantiation.
+	results, err := size.Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{2}, results)
+
+	// memory.grow returns the previous size.
+	results, err = grow.Call(testCtx, 2)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{2}, results)
+
+	results, err = size.Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{4}, results)
+	require.Equal(t, uint32(4*wasm.MemoryPageSize), inst.Memory().Size())
+
+	// Growing past the maximum fails with -1, leaving the size unchanged.
+	results, err = grow.Call(testCtx, 2)
+	require.NoError(t, err)
+	require.Equal(t, uint32(0xffffffff), uint32(results[0]))
+
+	results, err = size.Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{4}, results)
+}
@@ -96,24 +96,13 @@ func EncodeUint64(value uint64) (buf []byte) {
 type nextByte func(i int) (byte, error)
 
 func DecodeUint32(r io.ByteReader) (ret uint32, bytesRead uint64, err error) {
-	return decodeUint32(func(_ int) (byte, error) { return r.ReadByte() })
-}
-
-func LoadUint32(buf []byte) (ret uint32, bytesRead uint64, err error) {
-	return decodeUint32(func(i int) (byte, error) {
-		if i >= len(buf) {
-			return 0, io.EOF
-		}
-		return buf[i], nil
-	})
-}
-
-func decodeUint32(next nextByte) (ret uint32, bytesRead uint64, err error) {
 	// Derived from https://github.com/golang/go/blob/go1.20/src/encoding/binary/varint.go
 	// with the modification on the overflow handling tailored for 32-bits.
+	//
+	// This reads bytes directly instead of via a nextByte closure as this is hot during decoding.
 	var s uint32
 	for i := 0; i < maxVarintLen32; i++ {
-		b, err := next(i)
+		b, err := r.ReadByte()
 		if err != nil {
 			return 0, 0, err
 		}
@@ -130,6 +119,57 @@ func decodeUint32(next nextByte) (ret uint32, bytesRead uint64, err error) {
 	return 0, 0, errOverflow32
 }
 
+func LoadUint32(buf []byte) (ret uint32, bytesRead uint64, err error) {
+	bufLen := len(buf)
+	if bufLen == 0 {
+		return 0, 0, io.EOF
+	}
+	// Most immediates, such as local and function indexes, fit in a single byte.
+	if b := buf[0]; b < 0x80 {
+		return uint32(b), 1, nil
+	}
+
+	// Same as DecodeUint32, but indexing buf.
+	var s uint32
+	for i := 0; i < maxVarintLen32; i++ {
+		if i >= bufLen {
+			return 0, 0, io.EOF
+		}
+		b := buf[i]
+		if b < 0x80 {
+			// Unused bits must be all zero.
+			if i == maxVarintLen32-1 && (b&0xf0) > 0 {
+				return 0, 0, errOverflow32
+			}
+			return ret | uint32(b)<<s, uint64(i) + 1, nil
+		}
+		ret |= (uint32(b) & 0x7f) << s
+		s += 7
+	}
+	return 0, 0, errOverflow32
+}
+
+func DecodeUint64(r io.ByteReader) (ret uint64, bytesRead uint64, err error) {
+	// Same as LoadUint64, but reading bytes from r.
+	var s uint64
+	for i := 0; i < maxVarintLen64; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, 0, err
+		}
+		if b < 0x80 {
+			// Unused bits (non first bit) must all be zero.
+			if i == maxVarintLen64-1 && b > 1 {
+				return 0, 0, errOverflow64
+			}
+			return ret | uint64(b)<<s, uint64(i) + 1, nil
+		}
+		ret |= (uint64(b) & 0x7f) << s
+		s += 7
+	}
+	return 0, 0, errOverflow64
+}
+
 func LoadUint64(buf []byte) (ret uint64, bytesRead uint64, err error) {
 	bufLen := len(buf)
 	if bufLen == 0 {
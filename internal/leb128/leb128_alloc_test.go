@@ -2,6 +2,7 @@ package leb128
 
 import (
 	"bytes"
+	"math"
 	"testing"
 
 	"github.com/tetratelabs/wazero/internal/testing/require"
@@ -117,3 +118,48 @@ func BenchmarkDecodeInt64(b *testing.B) {
 		r.Reset(data)
 	}
 }
+
+// uint32Values approximates the LEB128 lengths in a compiled module: most immediates such as local and function
+// indexes fit in one byte, fewer need two or three, and only large constants need the maximum of five.
+var uint32Values = func() (ret []byte) {
+	for i := uint32(0); i < 100; i++ {
+		switch {
+		case i < 70:
+			ret = append(ret, EncodeUint32(i)...)
+		case i < 90:
+			ret = append(ret, EncodeUint32(i<<7)...)
+		case i < 98:
+			ret = append(ret, EncodeUint32(i<<14)...)
+		default:
+			ret = append(ret, EncodeUint32(math.MaxUint32-i)...)
+		}
+	}
+	return
+}()
+
+func BenchmarkDecodeUint32_Mixed(b *testing.B) {
+	r := bytes.NewReader(uint32Values)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Reset(uint32Values)
+		for r.Len() > 0 {
+			if _, _, err := DecodeUint32(r); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkLoadUint32_Mixed(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for buf := uint32Values; len(buf) > 0; {
+			_, n, err := LoadUint32(buf)
+			if err != nil {
+				b.Fatal(err)
+			}
+			buf = buf[n:]
+		}
+	}
+}
@@ -22,10 +22,20 @@ func TestEncode_DecodeInt32(t *testing.T) {
 		{input: 0, expected: []byte{0x00}},
 		{input: 1, expected: []byte{0x01}},
 		{input: 4, expected: []byte{0x04}},
+		// Sign boundaries: bit 6 of the last byte is the sign, so 64 and -65 need another byte.
+		{input: 63, expected: []byte{0x3f}},
+		{input: 64, expected: []byte{0xc0, 0x00}},
+		{input: -64, expected: []byte{0x40}},
+		{input: -65, expected: []byte{0xbf, 0x7f}},
+		{input: 8191, expected: []byte{0xff, 0x3f}},
+		{input: 8192, expected: []byte{0x80, 0xc0, 0x00}},
+		{input: -8192, expected: []byte{0x80, 0x40}},
+		{input: -8193, expected: []byte{0xff, 0xbf, 0x7f}},
 		{input: 16256, expected: []byte{0x80, 0xff, 0x0}},
 		{input: 624485, expected: []byte{0xe5, 0x8e, 0x26}},
 		{input: 165675008, expected: []byte{0x80, 0x80, 0x80, 0xcf, 0x0}},
 		{input: int32(math.MaxInt32), expected: []byte{0xff, 0xff, 0xff, 0xff, 0x7}},
+		{input: int32(math.MinInt32), expected: []byte{0x80, 0x80, 0x80, 0x80, 0x78}},
 	} {
 		require.Equal(t, c.expected, EncodeInt32(c.input))
 		decoded, _, err := LoadInt32(c.expected)
@@ -53,6 +63,15 @@ func TestEncode_DecodeInt64(t *testing.T) {
 		{input: 165675008, expected: []byte{0x80, 0x80, 0x80, 0xcf, 0x0}},
 		{input: math.MaxInt32, expected: []byte{0xff, 0xff, 0xff, 0xff, 0x7}},
 		{input: math.MaxInt64, expected: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x0}},
+		{input: math.MinInt64, expected: []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x7f}},
+		// Sign boundaries: bit 6 of the last byte is the sign, so 64 and -65 need another byte.
+		{input: 63, expected: []byte{0x3f}},
+		{input: 64, expected: []byte{0xc0, 0x00}},
+		{input: -64, expected: []byte{0x40}},
+		{input: -65, expected: []byte{0xbf, 0x7f}},
+		{input: 1<<34 - 1, expected: []byte{0xff, 0xff, 0xff, 0xff, 0x3f}},
+		{input: 1 << 34, expected: []byte{0x80, 0x80, 0x80, 0x80, 0xc0, 0x00}},
+		{input: -1 << 34, expected: []byte{0x80, 0x80, 0x80, 0x80, 0x40}},
 	} {
 		require.Equal(t, c.expected, EncodeInt64(c.input))
 		decoded, _, err := LoadInt64(c.expected)
@@ -114,6 +133,9 @@ func TestDecodeUint32(t *testing.T) {
 		{bytes: []byte{0x83, 0x80, 0x80, 0x80, 0x80, 0x00}, expErr: true},
 		{bytes: []byte{0x82, 0x80, 0x80, 0x80, 0x70}, expErr: true},
 		{bytes: []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x00}, expErr: true},
+		{bytes: []byte{}, expErr: true},
+		{bytes: []byte{0x80}, expErr: true},
+		{bytes: []byte{0xe5, 0x8e}, expErr: true},
 	} {
 		actual, num, err := LoadUint32(c.bytes)
 		if c.expErr {
@@ -123,6 +145,12 @@ func TestDecodeUint32(t *testing.T) {
 			require.Equal(t, c.exp, actual)
 			require.Equal(t, uint64(len(c.bytes)), num)
 		}
+
+		// DecodeUint32 doesn't share the loop with LoadUint32, so ensure they agree.
+		decoded, decodedNum, decodeErr := DecodeUint32(bytes.NewReader(c.bytes))
+		require.Equal(t, err, decodeErr)
+		require.Equal(t, actual, decoded)
+		require.Equal(t, num, decodedNum)
 	}
 }
 
@@ -148,6 +176,11 @@ func TestDecodeUint64(t *testing.T) {
 			require.Equal(t, c.exp, actual)
 			require.Equal(t, uint64(len(c.bytes)), num)
 		}
+
+		decoded, decodedNum, decodeErr := DecodeUint64(bytes.NewReader(c.bytes))
+		require.Equal(t, err, decodeErr)
+		require.Equal(t, actual, decoded)
+		require.Equal(t, num, decodedNum)
 	}
 }
 
@@ -228,3 +261,41 @@ func TestDecodeInt64(t *testing.T) {
 		require.Equal(t, uint64(len(c.bytes)), num)
 	}
 }
+
+// TestDecode_SignBoundaries ensures values either side of ±2^k round-trip, as off-by-one errors in sign extension
+// show up where the sign bit moves to the next byte, e.g. 63 vs 64 and -64 vs -65.
+func TestDecode_SignBoundaries(t *testing.T) {
+	var values []int64
+	for k := 0; k < 63; k++ {
+		v := int64(1) << k
+		values = append(values, v-1, v, -v, -v-1)
+	}
+	values = append(values, math.MaxInt64, math.MinInt64)
+
+	for _, v := range values {
+		v := v
+		t.Run(fmt.Sprintf("%d", v), func(t *testing.T) {
+			encoded := EncodeInt64(v)
+			actual, num, err := DecodeInt64(bytes.NewReader(encoded))
+			require.NoError(t, err)
+			require.Equal(t, v, actual)
+			require.Equal(t, uint64(len(encoded)), num)
+
+			if v >= -1<<32 && v < 1<<32 {
+				actual, num, err = DecodeInt33AsInt64(bytes.NewReader(encoded))
+				require.NoError(t, err)
+				require.Equal(t, v, actual)
+				require.Equal(t, uint64(len(encoded)), num)
+			}
+
+			if v >= math.MinInt32 && v <= math.MaxInt32 {
+				encoded32 := EncodeInt32(int32(v))
+				require.Equal(t, encoded, encoded32)
+				actual32, num, err := DecodeInt32(bytes.NewReader(encoded32))
+				require.NoError(t, err)
+				require.Equal(t, int32(v), actual32)
+				require.Equal(t, uint64(len(encoded32)), num)
+			}
+		})
+	}
+}
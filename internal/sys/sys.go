@@ -26,6 +26,11 @@ type Context struct {
 	osyield            sys.Osyield
 	randSource         io.Reader
 	fsc                FSContext
+
+	// writeLimit is the total bytes LimitWriter allows, or zero for no limit.
+	writeLimit uint64
+	// written is the total bytes written through LimitWriter.
+	written uint64
 }
 
 // Args is like os.Args and defaults to nil.
@@ -109,6 +114,37 @@ func (c *Context) RandSource() io.Reader {
 	return c.randSource
 }
 
+// SetWriteLimit caps the total bytes written through LimitWriter. Zero means
+// there is no limit.
+// see wazero.ModuleConfig WithWriteLimit
+func (c *Context) SetWriteLimit(limit uint64) {
+	c.writeLimit = limit
+}
+
+// LimitWriter wraps the write function so that the total bytes written do not
+// exceed the write limit. Writes past the limit are truncated, and once it is
+// exhausted, writes fail with EIO.
+func (c *Context) LimitWriter(write func([]byte) (int, experimentalsys.Errno)) func([]byte) (int, experimentalsys.Errno) {
+	if c.writeLimit == 0 {
+		return write
+	}
+	return func(buf []byte) (int, experimentalsys.Errno) {
+		if len(buf) == 0 {
+			return write(buf)
+		}
+		remaining := c.writeLimit - c.written
+		if remaining == 0 {
+			return 0, experimentalsys.EIO
+		}
+		if uint64(len(buf)) > remaining {
+			buf = buf[:remaining]
+		}
+		n, errno := write(buf)
+		c.written += uint64(n)
+		return n, errno
+	}
+}
+
 // DefaultContext returns Context with no values set except a possible nil
 // sys.FS.
 //
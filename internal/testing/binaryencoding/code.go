@@ -7,12 +7,32 @@ import (
 
 // encodeCode returns the wasm.Code encoded in WebAssembly 1.0 (20191205) Binary Format.
 //
+// When preserveLocals is true and wasm.Code LocalEntries is set, locals are encoded as those entries instead of
+// being compressed.
+//
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#binary-code
-func encodeCode(c *wasm.Code) []byte {
+func encodeCode(c *wasm.Code, preserveLocals bool) []byte {
 	if c.GoFunc != nil {
 		panic("BUG: GoFunction is not encodable")
 	}
 
+	code := append(encodeLocals(c, preserveLocals), c.Body...)
+	return append(leb128.EncodeUint32(uint32(len(code))), code...)
+}
+
+// encodeLocals returns the vector of local entries of the wasm.Code, which precedes its body.
+//
+// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#binary-code
+func encodeLocals(c *wasm.Code, preserveLocals bool) []byte {
+	if preserveLocals && c.LocalEntries != nil {
+		localBlocks := leb128.EncodeUint32(uint32(len(c.LocalEntries)))
+		for _, e := range c.LocalEntries {
+			localBlocks = append(localBlocks, leb128.EncodeUint32(e.Count)...)
+			localBlocks = append(localBlocks, e.Type)
+		}
+		return localBlocks
+	}
+
 	// local blocks compress locals while preserving index order by grouping locals of the same type.
 	// https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#code-section%E2%91%A0
 	localBlockCount := uint32(0) // how many blocks of locals with the same type (types can repeat!)
@@ -43,6 +63,5 @@ func encodeCode(c *wasm.Code) []byte {
 	} else {
 		localBlocks = leb128.EncodeUint32(0)
 	}
-	code := append(localBlocks, c.Body...)
-	return append(leb128.EncodeUint32(uint32(len(code))), code...)
+	return localBlocks
 }
@@ -75,12 +75,39 @@ func TestEncodeCode(t *testing.T) {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			bytes := encodeCode(tc.input)
+			bytes := encodeCode(tc.input, false)
 			require.Equal(t, tc.expected, bytes)
 		})
 	}
 }
 
+func TestEncodeCode_preserveLocals(t *testing.T) {
+	input := &wasm.Code{ // e.g. (func (local i32) (local i32) (local) local.get 0 local.get 1 i32.add)
+		LocalTypes: []wasm.ValueType{wasm.ValueTypeI32, wasm.ValueTypeI32},
+		LocalEntries: []wasm.LocalEntry{
+			{Count: 1, Type: wasm.ValueTypeI32},
+			{Count: 1, Type: wasm.ValueTypeI32},
+			{Count: 0, Type: wasm.ValueTypeF64},
+		},
+		Body: addLocalZeroLocalTwo,
+	}
+
+	require.Equal(t, append([]byte{
+		0x0d,                    // 13 bytes to encode locals and the body
+		0x03,                    // 3 local blocks
+		0x01, wasm.ValueTypeI32, // local block 1
+		0x01, wasm.ValueTypeI32, // local block 2
+		0x00, wasm.ValueTypeF64, // local block 3
+	}, addLocalZeroLocalTwo...), encodeCode(input, true))
+
+	// Without preserving locals, they are compressed.
+	require.Equal(t, append([]byte{
+		0x09,                    // 9 bytes to encode locals and the body
+		0x01,                    // 1 local block
+		0x02, wasm.ValueTypeI32, // local block 1
+	}, addLocalZeroLocalTwo...), encodeCode(input, false))
+}
+
 func BenchmarkEncodeCode(b *testing.B) {
 	input := &wasm.Code{ // e.g. (func (result i32) (local i32) (local i64) (local i32) local.get 0 local.get 2 i32.add)
 		LocalTypes: []wasm.ValueType{wasm.ValueTypeI32, wasm.ValueTypeI64, wasm.ValueTypeI32},
@@ -89,7 +116,7 @@ func BenchmarkEncodeCode(b *testing.B) {
 
 	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
-		if bytes := encodeCode(input); len(bytes) == 0 {
+		if bytes := encodeCode(input, false); len(bytes) == 0 {
 			b.Fatal("didn't encode anything")
 		}
 	}
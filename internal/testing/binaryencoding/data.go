@@ -6,6 +6,11 @@ import (
 )
 
 func encodeDataSegment(d *wasm.DataSegment) (ret []byte) {
+	return append(encodeDataSegmentHeader(d), d.Init...)
+}
+
+// encodeDataSegmentHeader returns the encoding of the wasm.DataSegment up to, but not including, its Init bytes.
+func encodeDataSegmentHeader(d *wasm.DataSegment) (ret []byte) {
 	// Currently multiple memories are not supported.
 	if d.Passive {
 		ret = append(ret, leb128.EncodeInt32(1)...)
@@ -13,7 +18,5 @@ func encodeDataSegment(d *wasm.DataSegment) (ret []byte) {
 		ret = append(ret, leb128.EncodeInt32(0)...) // active segment
 		ret = append(ret, encodeConstantExpression(d.OffsetExpression)...)
 	}
-	ret = append(ret, leb128.EncodeUint32(uint32(len(d.Init)))...)
-	ret = append(ret, d.Init...)
-	return
+	return append(ret, leb128.EncodeUint32(uint32(len(d.Init)))...)
 }
@@ -1,6 +1,10 @@
 package binaryencoding
 
 import (
+	"io"
+	"sort"
+	"sync"
+
 	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
@@ -9,59 +13,262 @@ var sizePrefixedName = []byte{4, 'n', 'a', 'm', 'e'}
 
 // EncodeModule implements wasm.EncodeModule for the WebAssembly 1.0 (20191205) Binary Format.
 // Note: If saving to a file, the conventional extension is wasm
+// Note: This panics if the module can't be encoded, such as when an export name is duplicated. Use WriterTo to get an
+// error instead.
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#binary-format%E2%91%A0
 func EncodeModule(m *wasm.Module) (bytes []byte) {
-	bytes = append(Magic, version...)
-	if m.SectionElementCount(wasm.SectionIDType) > 0 {
-		bytes = append(bytes, encodeTypeSection(m.TypeSection)...)
-	}
-	if m.SectionElementCount(wasm.SectionIDImport) > 0 {
-		bytes = append(bytes, encodeImportSection(m.ImportSection)...)
-	}
-	if m.SectionElementCount(wasm.SectionIDFunction) > 0 {
-		bytes = append(bytes, EncodeFunctionSection(m.FunctionSection)...)
-	}
-	if m.SectionElementCount(wasm.SectionIDTable) > 0 {
-		bytes = append(bytes, encodeTableSection(m.TableSection)...)
+	if hasOnlyTypeSection(m) {
+		return encodeTypeOnlyModule(m.TypeSection)
 	}
-	if m.SectionElementCount(wasm.SectionIDMemory) > 0 {
-		bytes = append(bytes, encodeMemorySection(m.MemorySection)...)
-	}
-	if m.SectionElementCount(wasm.SectionIDGlobal) > 0 {
-		bytes = append(bytes, encodeGlobalSection(m.GlobalSection)...)
+	mustEncodeModule(m, encodeOptions{}, func(b []byte) {
+		bytes = append(bytes, b...)
+	})
+	return
+}
+
+// bufferPool holds the buffers of EncodeModulePooled, so that encoding many modules doesn't allocate a result for
+// each.
+var bufferPool = sync.Pool{New: func() interface{} { return new([]byte) }}
+
+// EncodeModulePooled is like EncodeModule, except the result is appended to a buffer from a pool shared by all
+// goroutines. Call release exactly once when done with the result, which must not be used after.
+func EncodeModulePooled(m *wasm.Module) (bytes []byte, release func()) {
+	buf := bufferPool.Get().(*[]byte)
+	bytes = (*buf)[:0]
+	mustEncodeModule(m, encodeOptions{}, func(b []byte) {
+		bytes = append(bytes, b...)
+	})
+	return bytes, func() {
+		*buf = bytes // retain any growth for the next use.
+		bufferPool.Put(buf)
 	}
-	if m.SectionElementCount(wasm.SectionIDExport) > 0 {
-		bytes = append(bytes, encodeExportSection(m.ExportSection)...)
+}
+
+// EncodeModulePreservingLocals is like EncodeModule, except the locals of each function are encoded as the entries
+// recorded by the decoder in wasm.Code LocalEntries, if any, instead of being compressed.
+//
+// This allows a decoded module to be re-encoded without changing the encoding of its locals.
+func EncodeModulePreservingLocals(m *wasm.Module) (bytes []byte) {
+	mustEncodeModule(m, encodeOptions{preserveLocals: true}, func(b []byte) {
+		bytes = append(bytes, b...)
+	})
+	return
+}
+
+// WriterTo returns an io.WriterTo which writes the module in the same format as EncodeModule.
+//
+// Unlike EncodeModule, sections are written as they are encoded, so the whole module is never buffered at once.
+func WriterTo(m *wasm.Module) io.WriterTo {
+	return &moduleWriterTo{m: m}
+}
+
+// StreamingWriterTo is like WriterTo, except the code and data sections aren't buffered either. Their size is computed
+// in a first pass, then their header and each function or segment is written in a second pass. Function bodies and
+// data segment contents are written as-is, without copying them.
+//
+// This is useful for modules with very large code or data sections.
+func StreamingWriterTo(m *wasm.Module) io.WriterTo {
+	return &moduleWriterTo{m: m, opts: encodeOptions{streamSections: true}}
+}
+
+type moduleWriterTo struct {
+	m    *wasm.Module
+	opts encodeOptions
+}
+
+// WriteTo implements io.WriterTo
+func (w *moduleWriterTo) WriteTo(out io.Writer) (n int64, err error) {
+	err = encodeModule(w.m, w.opts, func(b []byte) error {
+		written, err := out.Write(b)
+		n += int64(written)
+		return err
+	})
+	return
+}
+
+// encodeOptions are options of encodeModule.
+type encodeOptions struct {
+	// preserveLocals encodes locals as recorded in wasm.Code LocalEntries.
+	preserveLocals bool
+	// streamSections emits the code and data sections in parts, using streamCodeSection and streamDataSection.
+	streamSections bool
+}
+
+// encodeModule passes the magic number, version and each present section of the module to emit in order, stopping
+// at the first error. Unknown sections are emitted in order of ID after the known ones.
+//
+// Custom sections with a recorded position (wasm.CustomSection After) are emitted right after that section, even if
+// it is absent. Others are emitted last, after the name section, as are those which followed the name section when
+// decoded (wasm.CustomSection AfterNameSection).
+func encodeModule(m *wasm.Module, opts encodeOptions, emit func([]byte) error) error {
+	if err := emit(append(Magic, version...)); err != nil {
+		return err
 	}
-	if m.SectionElementCount(wasm.SectionIDStart) > 0 {
-		bytes = append(bytes, EncodeStartSection(*m.StartSection)...)
+
+	emitted := make([]bool, len(m.CustomSections))
+	emitCustomSectionsAfter := func(id wasm.SectionID) error {
+		for i, custom := range m.CustomSections {
+			if custom.AfterNameSection && m.NameSection != nil {
+				continue // emitted after the name section.
+			}
+			if !emitted[i] && custom.After != nil && *custom.After == id {
+				emitted[i] = true
+				if err := emit(encodeCustomSection(custom)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
 	}
-	if m.SectionElementCount(wasm.SectionIDElement) > 0 {
-		bytes = append(bytes, encodeElementSection(m.ElementSection)...)
+	if err := emitCustomSectionsAfter(wasm.SectionIDCustom); err != nil {
+		return err
 	}
-	if m.SectionElementCount(wasm.SectionIDCode) > 0 {
-		bytes = append(bytes, encodeCodeSection(m.CodeSection)...)
+
+	sections := []struct {
+		id     wasm.SectionID
+		encode func() []byte
+	}{
+		{wasm.SectionIDType, func() []byte { return encodeTypeSection(m.TypeSection) }},
+		{wasm.SectionIDImport, func() []byte { return encodeImportSection(m.ImportSection) }},
+		{wasm.SectionIDFunction, func() []byte { return EncodeFunctionSection(m.FunctionSection) }},
+		{wasm.SectionIDTable, func() []byte { return encodeTableSection(m.TableSection) }},
+		{wasm.SectionIDMemory, func() []byte { return encodeMemorySection(m.MemorySection) }},
+		{wasm.SectionIDGlobal, func() []byte { return encodeGlobalSection(m.GlobalSection) }},
+		{wasm.SectionIDExport, nil}, // encoded below, as it can fail.
+		{wasm.SectionIDStart, func() []byte { return EncodeStartSection(*m.StartSection) }},
+		{wasm.SectionIDElement, func() []byte { return encodeElementSection(m.ElementSection) }},
+		// The data count section precedes the code section, so that data indexes in it can be validated in one pass.
+		{wasm.SectionIDDataCount, func() []byte {
+			return encodeSection(wasm.SectionIDDataCount, leb128.EncodeUint32(*m.DataCountSection))
+		}},
+		{wasm.SectionIDCode, func() []byte { return encodeCodeSection(m.CodeSection, opts.preserveLocals) }},
+		{wasm.SectionIDData, func() []byte { return encodeDataSection(m.DataSection) }},
 	}
-	if m.SectionElementCount(wasm.SectionIDData) > 0 {
-		bytes = append(bytes, encodeDataSection(m.DataSection)...)
+	for _, s := range sections {
+		if m.SectionElementCount(s.id) > 0 {
+			var err error
+			switch {
+			case opts.streamSections && s.id == wasm.SectionIDCode:
+				err = streamCodeSection(m.CodeSection, opts.preserveLocals, emit)
+			case opts.streamSections && s.id == wasm.SectionIDData:
+				err = streamDataSection(m.DataSection, emit)
+			case s.id == wasm.SectionIDExport:
+				var section []byte
+				if section, err = encodeExportSection(m.ExportSection); err == nil {
+					err = emit(section)
+				}
+			default:
+				err = emit(s.encode())
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if err := emitCustomSectionsAfter(s.id); err != nil {
+			return err
+		}
 	}
-	if dc := m.DataCountSection; dc != nil {
-		bytes = append(bytes, encodeSection(wasm.SectionIDDataCount, leb128.EncodeUint32(*dc))...)
+	if len(m.UnknownSections) > 0 {
+		ids := make([]wasm.SectionID, 0, len(m.UnknownSections))
+		for id := range m.UnknownSections {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		for _, id := range ids {
+			if err := emit(encodeSection(id, m.UnknownSections[id])); err != nil {
+				return err
+			}
+			if err := emitCustomSectionsAfter(id); err != nil {
+				return err
+			}
+		}
 	}
 	if m.SectionElementCount(wasm.SectionIDCustom) > 0 {
 		// >> The name section should appear only once in a module, and only after the data section.
 		// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#binary-namesec
 		if m.NameSection != nil {
 			nameSection := append(sizePrefixedName, EncodeNameSectionData(m.NameSection)...)
-			bytes = append(bytes, encodeSection(wasm.SectionIDCustom, nameSection)...)
+			if err := emit(encodeSection(wasm.SectionIDCustom, nameSection)); err != nil {
+				return err
+			}
 		}
-		for _, custom := range m.CustomSections {
-			bytes = append(bytes, encodeCustomSection(custom)...)
+		for i, custom := range m.CustomSections {
+			if emitted[i] {
+				continue
+			}
+			if err := emit(encodeCustomSection(custom)); err != nil {
+				return err
+			}
 		}
 	}
+	return nil
+}
+
+// mustEncodeModule is like encodeModule, except emit can't fail, and this panics if the module can't be encoded.
+func mustEncodeModule(m *wasm.Module, opts encodeOptions, emit func([]byte)) {
+	if err := encodeModule(m, opts, func(b []byte) error {
+		emit(b)
+		return nil
+	}); err != nil {
+		panic(err)
+	}
+}
+
+// hasOnlyTypeSection returns true if the type section is the only section of the module, as is the case for modules
+// which only declare an interface.
+func hasOnlyTypeSection(m *wasm.Module) bool {
+	if len(m.TypeSection) == 0 || len(m.UnknownSections) > 0 {
+		return false
+	}
+	for id := wasm.SectionIDCustom; id <= wasm.SectionIDDataCount; id++ {
+		if id != wasm.SectionIDType && m.SectionElementCount(id) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeTypeOnlyModule is a fast path of EncodeModule for a module where hasOnlyTypeSection is true. Unlike
+// encodeModule, the result is allocated once at its final size, instead of per section and function type.
+func encodeTypeOnlyModule(types []wasm.FunctionType) []byte {
+	contentsSize := uint32Size(uint32(len(types)))
+	for i := range types {
+		t := &types[i]
+		contentsSize += 1 + // 0x60
+			uint32Size(uint32(len(t.Params))) + len(t.Params) +
+			uint32Size(uint32(len(t.Results))) + len(t.Results)
+	}
+
+	ret := make([]byte, 0, len(Magic)+len(version)+1+uint32Size(uint32(contentsSize))+contentsSize)
+	ret = append(append(ret, Magic...), version...)
+	ret = append(ret, wasm.SectionIDType)
+	ret = appendUint32(ret, uint32(contentsSize))
+	ret = appendUint32(ret, uint32(len(types)))
+	for i := range types {
+		t := &types[i]
+		ret = append(ret, 0x60)
+		ret = append(appendUint32(ret, uint32(len(t.Params))), t.Params...)
+		ret = append(appendUint32(ret, uint32(len(t.Results))), t.Results...)
+	}
+	return ret
+}
+
+// uint32Size returns the length of v encoded as unsigned LEB128.
+func uint32Size(v uint32) (size int) {
+	for size = 1; v >= 0x80; size++ {
+		v >>= 7
+	}
 	return
 }
 
+// appendUint32 appends v encoded as unsigned LEB128 to buf, without allocating unless buf must grow.
+func appendUint32(buf []byte, v uint32) []byte {
+	for ; v >= 0x80; v >>= 7 {
+		buf = append(buf, byte(v)|0x80)
+	}
+	return append(buf, byte(v))
+}
+
 func encodeCustomSection(c *wasm.CustomSection) []byte {
 	content := append(leb128.EncodeUint32(uint32(len(c.Name))), c.Name...)
 	content = append(content, c.Data...)
@@ -1,6 +1,10 @@
 package binaryencoding
 
 import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
 	"testing"
 
 	"github.com/tetratelabs/wazero/internal/leb128"
@@ -11,6 +15,7 @@ import (
 func TestModule_Encode(t *testing.T) {
 	i32, f32 := wasm.ValueTypeI32, wasm.ValueTypeF32
 	zero := uint32(0)
+	first, afterType := wasm.SectionIDCustom, wasm.SectionIDType
 
 	tests := []struct {
 		name     string
@@ -196,6 +201,27 @@ func TestModule_Encode(t *testing.T) {
 				wasm.ExternTypeGlobal, 0x00, // global[0]
 			),
 		},
+		{
+			name: "custom sections at recorded positions",
+			input: &wasm.Module{
+				TypeSection: []wasm.FunctionType{{}},
+				NameSection: &wasm.NameSection{ModuleName: "m"},
+				CustomSections: []*wasm.CustomSection{
+					{Name: "last", Data: []byte{3}},
+					{Name: "b", Data: []byte{2}, After: &afterType},
+					{Name: "a", Data: []byte{1}, After: &first},
+				},
+			},
+			expected: append(append(Magic, version...),
+				wasm.SectionIDCustom, 0x03, 0x01, 'a', 1, // recorded before all sections
+				wasm.SectionIDType, 0x04, 0x01, 0x60, 0x00, 0x00, // 1 type: func=0x60 no param no result
+				wasm.SectionIDCustom, 0x03, 0x01, 'b', 2, // recorded after the type section
+				wasm.SectionIDCustom, 0x09, // 9 bytes in this section
+				0x04, 'n', 'a', 'm', 'e',
+				subsectionIDModuleName, 0x02, 0x01, 'm',
+				wasm.SectionIDCustom, 0x06, 0x04, 'l', 'a', 's', 't', 3, // without a position, after the name section
+			),
+		},
 	}
 
 	for _, tt := range tests {
@@ -204,10 +230,94 @@ func TestModule_Encode(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			bytes := EncodeModule(tc.input)
 			require.Equal(t, tc.expected, bytes)
+			size, err := EncodedSize(tc.input)
+			require.NoError(t, err)
+			require.Equal(t, len(bytes), size)
 		})
 	}
 }
 
+func TestWriterTo(t *testing.T) {
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeEnd}}},
+		ExportSection:   []wasm.Export{{Type: wasm.ExternTypeFunc, Name: "run", Index: 0}},
+		NameSection:     &wasm.NameSection{ModuleName: "simple"},
+	}
+	expected := EncodeModule(m)
+
+	var buf bytes.Buffer
+	n, err := WriterTo(m).WriteTo(&buf)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(expected)), n)
+	require.Equal(t, expected, buf.Bytes())
+}
+
+func TestEncodeModulePooled(t *testing.T) {
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeEnd}}},
+		NameSection:     &wasm.NameSection{ModuleName: "simple"},
+	}
+	expected := EncodeModule(m)
+
+	// Encoding concurrently doesn't share buffers which are in use.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				bytes, release := EncodeModulePooled(m)
+				require.Equal(t, expected, bytes)
+				release()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestWriterTo_Error(t *testing.T) {
+	m := &wasm.Module{TypeSection: []wasm.FunctionType{{}}}
+
+	// Fail after the magic number and version are written.
+	w := &limitedWriter{limit: len(Magic) + len(version)}
+	n, err := WriterTo(m).WriteTo(w)
+	require.EqualError(t, err, "limit exceeded")
+	require.Equal(t, int64(len(Magic)+len(version)), n)
+
+	t.Run("duplicate export name", func(t *testing.T) {
+		m := &wasm.Module{
+			TypeSection:     []wasm.FunctionType{{}},
+			FunctionSection: []wasm.Index{0},
+			CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeEnd}}},
+			ExportSection: []wasm.Export{
+				{Type: wasm.ExternTypeFunc, Name: "run", Index: 0},
+				{Type: wasm.ExternTypeFunc, Name: "run", Index: 0},
+			},
+		}
+		_, err := WriterTo(m).WriteTo(io.Discard)
+		require.EqualError(t, err, `export[1] duplicates name "run"`)
+
+		err = require.CapturePanic(func() { EncodeModule(m) })
+		require.EqualError(t, err, `export[1] duplicates name "run"`)
+	})
+}
+
+type limitedWriter struct {
+	limit, written int
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if w.written+len(p) > w.limit {
+		return 0, errors.New("limit exceeded")
+	}
+	w.written += len(p)
+	return len(p), nil
+}
+
 func TestModule_Encode_HostFunctionSection_Unsupported(t *testing.T) {
 	// We don't currently have an approach to serialize reflect.Value pointers
 	fn := func() {}
@@ -220,3 +330,109 @@ func TestModule_Encode_HostFunctionSection_Unsupported(t *testing.T) {
 	})
 	require.EqualError(t, captured, "BUG: GoFunction is not encodable")
 }
+
+func TestEncodeModule_TypeOnly(t *testing.T) {
+	i32, f64 := wasm.ValueTypeI32, wasm.ValueTypeF64
+	m := &wasm.Module{TypeSection: []wasm.FunctionType{
+		{},
+		{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}},
+		{Params: []wasm.ValueType{i32, i32, i32, i32}, Results: []wasm.ValueType{i32, f64}},
+		{Params: bytes.Repeat([]byte{i32}, 200)}, // count encoded in 2 bytes
+	}}
+	require.True(t, hasOnlyTypeSection(m))
+
+	var expected []byte
+	_ = encodeModule(m, encodeOptions{}, func(b []byte) error {
+		expected = append(expected, b...)
+		return nil
+	})
+	actual := EncodeModule(m)
+	require.Equal(t, expected, actual)
+	require.Equal(t, len(actual), cap(actual))
+
+	allocs := testing.AllocsPerRun(10, func() { EncodeModule(m) })
+	require.Equal(t, float64(1), allocs)
+
+	t.Run("not only types", func(t *testing.T) {
+		for _, m := range []*wasm.Module{
+			{},
+			{TypeSection: []wasm.FunctionType{{}}, FunctionSection: []wasm.Index{0}},
+			{TypeSection: []wasm.FunctionType{{}}, NameSection: &wasm.NameSection{ModuleName: "m"}},
+			{TypeSection: []wasm.FunctionType{{}}, UnknownSections: map[wasm.SectionID][]byte{0x42: {}}},
+		} {
+			require.False(t, hasOnlyTypeSection(m))
+		}
+	})
+}
+
+// BenchmarkEncodeModule_TypeOnly compares the fast path of EncodeModule for a module with only a type section to the
+// general encoding.
+func BenchmarkEncodeModule_TypeOnly(b *testing.B) {
+	i32 := wasm.ValueTypeI32
+	m := &wasm.Module{TypeSection: []wasm.FunctionType{
+		{},
+		{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}},
+		{Params: []wasm.ValueType{i32, i32, i32, i32}, Results: []wasm.ValueType{i32}},
+		{Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i32}},
+	}}
+
+	b.Run("EncodeModule", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if bytes := EncodeModule(m); len(bytes) == 0 {
+				b.Fatal("didn't encode anything")
+			}
+		}
+	})
+	b.Run("general", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var bytes []byte
+			_ = encodeModule(m, encodeOptions{}, func(b []byte) error {
+				bytes = append(bytes, b...)
+				return nil
+			})
+			if len(bytes) == 0 {
+				b.Fatal("didn't encode anything")
+			}
+		}
+	})
+}
+
+// BenchmarkEncodeModule compares allocations of EncodeModule and EncodeModulePooled under concurrent load.
+func BenchmarkEncodeModule(b *testing.B) {
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0, 0, 0, 0},
+		CodeSection: []wasm.Code{
+			{Body: bytes.Repeat([]byte{wasm.OpcodeNop}, 1024)},
+			{Body: bytes.Repeat([]byte{wasm.OpcodeNop}, 1024)},
+			{Body: bytes.Repeat([]byte{wasm.OpcodeNop}, 1024)},
+			{Body: bytes.Repeat([]byte{wasm.OpcodeNop}, 1024)},
+		},
+		DataSection: []wasm.DataSegment{{Init: make([]byte, 4096)}},
+	}
+
+	b.Run("EncodeModule", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if bytes := EncodeModule(m); len(bytes) == 0 {
+					b.Fatal("didn't encode anything")
+				}
+			}
+		})
+	})
+	b.Run("EncodeModulePooled", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				bytes, release := EncodeModulePooled(m)
+				if len(bytes) == 0 {
+					b.Fatal("didn't encode anything")
+				}
+				release()
+			}
+		})
+	})
+}
@@ -12,5 +12,12 @@ func EncodeMemory(i *wasm.Memory) []byte {
 	if !i.IsMaxEncoded {
 		maxPtr = nil
 	}
-	return EncodeLimitsType(i.Min, maxPtr)
+	ret := EncodeLimitsType(i.Min, maxPtr)
+	if i.IsShared {
+		ret[0] |= 0x02 // shared flag added by the threads proposal.
+	}
+	if i.Is64 {
+		ret[0] |= 0x04 // 64-bit flag added by the memory64 proposal.
+	}
+	return ret
 }
@@ -1,6 +1,8 @@
 package binaryencoding
 
 import (
+	"sort"
+
 	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
@@ -13,6 +15,12 @@ const (
 	// subsectionIDLocalNames contain a map of function indices to a map of local indices to their names, in ascending
 	// order by function and local index
 	subsectionIDLocalNames = uint8(2)
+	// subsectionIDGlobalNames is a map of indices to global names, in ascending order by global index. This is defined
+	// in the extended name section proposal.
+	subsectionIDGlobalNames = uint8(7)
+	// subsectionIDDataNames is a map of indices to data segment names, in ascending order by data index. This is
+	// defined in the extended name section proposal.
+	subsectionIDDataNames = uint8(9)
 )
 
 // EncodeNameSectionData serializes the data for the "name" key in wasm.SectionIDCustom according to the
@@ -31,6 +39,12 @@ func EncodeNameSectionData(n *wasm.NameSection) (data []byte) {
 	if ld := encodeLocalNameData(n); len(ld) > 0 {
 		data = append(data, encodeNameSubsection(subsectionIDLocalNames, ld)...)
 	}
+	if len(n.GlobalNames) > 0 {
+		data = append(data, encodeNameSubsection(subsectionIDGlobalNames, encodeNameMap(n.GlobalNames))...)
+	}
+	if len(n.DataNames) > 0 {
+		data = append(data, encodeNameSubsection(subsectionIDDataNames, encodeNameMap(n.DataNames))...)
+	}
 	return
 }
 
@@ -44,10 +58,12 @@ func encodeFunctionNameData(n *wasm.NameSection) []byte {
 	return encodeNameMap(n.FunctionNames)
 }
 
+// encodeNameMap encodes the name map in ascending order by index, as required by the name section, regardless of the
+// order of m.
 func encodeNameMap(m wasm.NameMap) []byte {
 	count := uint32(len(m))
 	data := leb128.EncodeUint32(count)
-	for _, na := range m {
+	for _, na := range sortedNameMap(m) {
 		data = append(data, encodeNameAssoc(na)...)
 	}
 	return data
@@ -63,13 +79,36 @@ func encodeLocalNameData(n *wasm.NameSection) []byte {
 	funcNameCount := uint32(len(n.LocalNames))
 	subsection := leb128.EncodeUint32(funcNameCount)
 
-	for _, na := range n.LocalNames {
+	for _, na := range sortedIndirectNameMap(n.LocalNames) {
 		locals := encodeNameMap(na.NameMap)
 		subsection = append(subsection, append(leb128.EncodeUint32(na.Index), locals...)...)
 	}
 	return subsection
 }
 
+// sortedNameMap returns m if already sorted by index, or otherwise a sorted copy, so that the caller's data isn't
+// modified.
+func sortedNameMap(m wasm.NameMap) wasm.NameMap {
+	less := func(i, j int) bool { return m[i].Index < m[j].Index }
+	if sort.SliceIsSorted(m, less) {
+		return m
+	}
+	ret := append(wasm.NameMap(nil), m...)
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Index < ret[j].Index })
+	return ret
+}
+
+// sortedIndirectNameMap is like sortedNameMap, except for function indices of an wasm.IndirectNameMap.
+func sortedIndirectNameMap(m wasm.IndirectNameMap) wasm.IndirectNameMap {
+	less := func(i, j int) bool { return m[i].Index < m[j].Index }
+	if sort.SliceIsSorted(m, less) {
+		return m
+	}
+	ret := append(wasm.IndirectNameMap(nil), m...)
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Index < ret[j].Index })
+	return ret
+}
+
 // encodeNameSubsection returns a buffer encoding the given subsection
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#subsections%E2%91%A0
 func encodeNameSubsection(subsectionID uint8, content []byte) []byte {
@@ -108,6 +108,44 @@ func TestEncodeNameSectionData(t *testing.T) {
 				0x01, 0x01, 'r', // index 1, size of "r", "r"
 			},
 		},
+		{
+			name: "global and data names",
+			input: &wasm.NameSection{
+				GlobalNames: wasm.NameMap{{Index: wasm.Index(0), Name: "sp"}},
+				DataNames:   wasm.NameMap{{Index: wasm.Index(1), Name: "d"}},
+			},
+			expected: []byte{
+				subsectionIDGlobalNames, 0x05, // 5 bytes
+				0x01,                 // one global name
+				0x00, 0x02, 's', 'p', // index 0, size of "sp", "sp"
+				subsectionIDDataNames, 0x04, // 4 bytes
+				0x01,            // one data name
+				0x01, 0x01, 'd', // index 1, size of "d", "d"
+			},
+		},
+		{
+			name: "local names out of order",
+			input: &wasm.NameSection{
+				LocalNames: wasm.IndirectNameMap{
+					{Index: wasm.Index(1), NameMap: wasm.NameMap{
+						{Index: wasm.Index(1), Name: "r"},
+						{Index: wasm.Index(0), Name: "l"},
+					}},
+					{Index: wasm.Index(0), NameMap: wasm.NameMap{
+						{Index: wasm.Index(0), Name: "x"},
+					}},
+				},
+			},
+			expected: []byte{
+				subsectionIDLocalNames, 0x0e, // 14 bytes
+				0x02,       // two functions
+				0x00, 0x01, // index 0 has 1 local
+				0x00, 0x01, 'x', // index 0, size of "x", "x"
+				0x01, 0x02, // index 1 has 2 locals
+				0x00, 0x01, 'l', // index 0, size of "l", "l"
+				0x01, 0x01, 'r', // index 1, size of "r", "r"
+			},
+		},
 	}
 
 	for _, tt := range tests {
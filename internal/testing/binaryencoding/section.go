@@ -1,6 +1,8 @@
 package binaryencoding
 
 import (
+	"fmt"
+
 	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
@@ -56,11 +58,11 @@ func EncodeFunctionSection(typeIndices []wasm.Index) []byte {
 //
 // See encodeCode
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#code-section%E2%91%A0
-func encodeCodeSection(code []wasm.Code) []byte {
+func encodeCodeSection(code []wasm.Code, preserveLocals bool) []byte {
 	contents := leb128.EncodeUint32(uint32(len(code)))
 	for i := range code {
 		c := &code[i]
-		contents = append(contents, encodeCode(c)...)
+		contents = append(contents, encodeCode(c, preserveLocals)...)
 	}
 	return encodeSection(wasm.SectionIDCode, contents)
 }
@@ -107,13 +109,20 @@ func encodeGlobalSection(globals []wasm.Global) []byte {
 //
 // See encodeExport
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#export-section%E2%91%A0
-func encodeExportSection(exports []wasm.Export) []byte {
+//
+// Note: This returns an error if an export name is duplicated, as that would encode an invalid module.
+func encodeExportSection(exports []wasm.Export) ([]byte, error) {
+	names := make(map[string]struct{}, len(exports))
 	contents := leb128.EncodeUint32(uint32(len(exports)))
 	for i := range exports {
 		e := &exports[i]
+		if _, ok := names[e.Name]; ok {
+			return nil, fmt.Errorf("export[%d] duplicates name %q", i, e.Name)
+		}
+		names[e.Name] = struct{}{}
 		contents = append(contents, encodeExport(e)...)
 	}
-	return encodeSection(wasm.SectionIDExport, contents)
+	return encodeSection(wasm.SectionIDExport, contents), nil
 }
 
 // EncodeStartSection encodes a wasm.SectionIDStart for the given function index in WebAssembly 1.0 (20191205)
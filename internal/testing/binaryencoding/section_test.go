@@ -15,3 +15,11 @@ func TestEncodeFunctionSection(t *testing.T) {
 func TestEncodeStartSection(t *testing.T) {
 	require.Equal(t, []byte{wasm.SectionIDStart, 0x01, 0x05}, EncodeStartSection(5))
 }
+
+func TestEncodeExportSection_DuplicateName(t *testing.T) {
+	_, err := encodeExportSection([]wasm.Export{
+		{Type: wasm.ExternTypeFunc, Name: "run", Index: 0},
+		{Type: wasm.ExternTypeFunc, Name: "run", Index: 1},
+	})
+	require.EqualError(t, err, `export[1] duplicates name "run"`)
+}
@@ -0,0 +1,263 @@
+package binaryencoding
+
+import (
+	"fmt"
+
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// EncodedSize returns the length of the result of EncodeModule, computed from the lengths of each part of the module
+// instead of encoding it.
+//
+// An error is returned if the module can't be encoded, for example if a function is implemented in Go.
+func EncodedSize(m *wasm.Module) (int, error) {
+	size := len(Magic) + len(version)
+
+	sectionSizes := []struct {
+		id   wasm.SectionID
+		size func() (int, error)
+	}{
+		{wasm.SectionIDType, func() (int, error) { return typeSectionSize(m.TypeSection), nil }},
+		{wasm.SectionIDImport, func() (int, error) { return importSectionSize(m.ImportSection) }},
+		{wasm.SectionIDFunction, func() (int, error) { return functionSectionSize(m.FunctionSection), nil }},
+		{wasm.SectionIDTable, func() (int, error) { return tableSectionSize(m.TableSection), nil }},
+		{wasm.SectionIDMemory, func() (int, error) { return sectionSize(1 + memorySize(m.MemorySection)), nil }},
+		{wasm.SectionIDGlobal, func() (int, error) { return globalSectionSize(m.GlobalSection), nil }},
+		{wasm.SectionIDExport, func() (int, error) { return exportSectionSize(m.ExportSection) }},
+		{wasm.SectionIDStart, func() (int, error) { return sectionSize(uint32Size(*m.StartSection)), nil }},
+		{wasm.SectionIDElement, func() (int, error) { return elementSectionSize(m.ElementSection) }},
+		{wasm.SectionIDDataCount, func() (int, error) { return sectionSize(uint32Size(*m.DataCountSection)), nil }},
+		{wasm.SectionIDCode, func() (int, error) { return codeSectionSize(m.CodeSection, false) }},
+		{wasm.SectionIDData, func() (int, error) { return dataSectionSize(m.DataSection), nil }},
+	}
+	for _, s := range sectionSizes {
+		if m.SectionElementCount(s.id) == 0 {
+			continue
+		}
+		n, err := s.size()
+		if err != nil {
+			return 0, err
+		}
+		size += n
+	}
+
+	for _, data := range m.UnknownSections {
+		size += sectionSize(len(data))
+	}
+	if m.NameSection != nil {
+		size += sectionSize(len(sizePrefixedName) + nameSectionDataSize(m.NameSection))
+	}
+	for _, c := range m.CustomSections {
+		size += sectionSize(sizePrefixedSize(len(c.Name)) + len(c.Data))
+	}
+	return size, nil
+}
+
+// sectionSize returns the length of a section with contents of the given length, including its ID and size.
+func sectionSize(contents int) int {
+	return 1 + sizePrefixedSize(contents)
+}
+
+// sizePrefixedSize returns the length of data of the given length, prefixed by that length as in encodeSizePrefixed.
+func sizePrefixedSize(data int) int {
+	return uint32Size(uint32(data)) + data
+}
+
+// int32Size returns the length of v encoded as signed LEB128.
+func int32Size(v int32) (size int) {
+	for size = 1; v < -64 || v >= 64; size++ {
+		v >>= 7
+	}
+	return
+}
+
+func typeSectionSize(types []wasm.FunctionType) int {
+	contents := uint32Size(uint32(len(types)))
+	for i := range types {
+		t := &types[i]
+		contents += 1 + sizePrefixedSize(len(t.Params)) + sizePrefixedSize(len(t.Results)) // 1 for 0x60
+	}
+	return sectionSize(contents)
+}
+
+func importSectionSize(imports []wasm.Import) (int, error) {
+	contents := uint32Size(uint32(len(imports)))
+	for i := range imports {
+		imp := &imports[i]
+		contents += sizePrefixedSize(len(imp.Module)) + sizePrefixedSize(len(imp.Name)) + 1 // 1 for the type
+		switch imp.Type {
+		case wasm.ExternTypeFunc:
+			contents += uint32Size(imp.DescFunc)
+		case wasm.ExternTypeTable:
+			contents += 1 + limitsSize(imp.DescTable.Min, imp.DescTable.Max)
+		case wasm.ExternTypeMemory:
+			contents += memorySize(imp.DescMem)
+		case wasm.ExternTypeGlobal:
+			contents += 2
+		default:
+			return 0, fmt.Errorf("import[%d]: invalid externtype: %s", i, wasm.ExternTypeName(imp.Type))
+		}
+	}
+	return sectionSize(contents), nil
+}
+
+func functionSectionSize(typeIndices []wasm.Index) int {
+	contents := uint32Size(uint32(len(typeIndices)))
+	for _, index := range typeIndices {
+		contents += uint32Size(index)
+	}
+	return sectionSize(contents)
+}
+
+func tableSectionSize(tables []wasm.Table) int {
+	contents := uint32Size(uint32(len(tables)))
+	for i := range tables {
+		contents += 1 + limitsSize(tables[i].Min, tables[i].Max)
+	}
+	return sectionSize(contents)
+}
+
+// limitsSize returns the length of the result of EncodeLimitsType.
+func limitsSize(min uint32, max *uint32) int {
+	size := 1 + uint32Size(min)
+	if max != nil {
+		size += uint32Size(*max)
+	}
+	return size
+}
+
+// memorySize returns the length of the result of EncodeMemory.
+func memorySize(mem *wasm.Memory) int {
+	size := 1 + uint32Size(mem.Min)
+	if mem.IsMaxEncoded {
+		size += uint32Size(mem.Max)
+	}
+	return size
+}
+
+func globalSectionSize(globals []wasm.Global) int {
+	contents := uint32Size(uint32(len(globals)))
+	for i := range globals {
+		contents += 2 + constantExpressionSize(&globals[i].Init)
+	}
+	return sectionSize(contents)
+}
+
+func constantExpressionSize(expr *wasm.ConstantExpression) int {
+	return 1 + len(expr.Data) + 1 // 1 for the opcode and 1 for OpcodeEnd
+}
+
+func exportSectionSize(exports []wasm.Export) (int, error) {
+	names := make(map[string]struct{}, len(exports))
+	contents := uint32Size(uint32(len(exports)))
+	for i := range exports {
+		e := &exports[i]
+		if _, ok := names[e.Name]; ok {
+			return 0, fmt.Errorf("export[%d] duplicates name %q", i, e.Name)
+		}
+		names[e.Name] = struct{}{}
+		contents += sizePrefixedSize(len(e.Name)) + 1 + uint32Size(e.Index)
+	}
+	return sectionSize(contents), nil
+}
+
+func elementSectionSize(elements []wasm.ElementSegment) (int, error) {
+	contents := uint32Size(uint32(len(elements)))
+	for i := range elements {
+		e := &elements[i]
+		if e.Mode != wasm.ElementModeActive {
+			return 0, fmt.Errorf("element[%d]: encoding non-active elements isn't supported", i)
+		}
+		contents += int32Size(int32(e.TableIndex)) + constantExpressionSize(&e.OffsetExpr) + uint32Size(uint32(len(e.Init)))
+		for _, idx := range e.Init {
+			contents += int32Size(int32(idx))
+		}
+	}
+	return sectionSize(contents), nil
+}
+
+func codeSectionSize(code []wasm.Code, preserveLocals bool) (int, error) {
+	contents := uint32Size(uint32(len(code)))
+	for i := range code {
+		c := &code[i]
+		if c.GoFunc != nil {
+			return 0, fmt.Errorf("code[%d]: a function implemented in Go isn't encodable", i)
+		}
+		contents += sizePrefixedSize(localsSize(c, preserveLocals) + len(c.Body))
+	}
+	return sectionSize(contents), nil
+}
+
+// localsSize returns the length of the result of encodeLocals.
+func localsSize(c *wasm.Code, preserveLocals bool) int {
+	if preserveLocals && c.LocalEntries != nil {
+		size := uint32Size(uint32(len(c.LocalEntries)))
+		for _, e := range c.LocalEntries {
+			size += uint32Size(e.Count) + 1
+		}
+		return size
+	}
+
+	// Locals are compressed into blocks of consecutive locals of the same type.
+	var size, blockCount int
+	for i := 0; i < len(c.LocalTypes); {
+		runCount := 1
+		for i+runCount < len(c.LocalTypes) && c.LocalTypes[i+runCount] == c.LocalTypes[i] {
+			runCount++
+		}
+		size += uint32Size(uint32(runCount)) + uint32Size(uint32(c.LocalTypes[i]))
+		blockCount++
+		i += runCount
+	}
+	return uint32Size(uint32(blockCount)) + size
+}
+
+func dataSectionSize(datum []wasm.DataSegment) int {
+	contents := uint32Size(uint32(len(datum)))
+	for i := range datum {
+		contents += dataSegmentHeaderSize(&datum[i]) + len(datum[i].Init)
+	}
+	return sectionSize(contents)
+}
+
+// dataSegmentHeaderSize returns the length of the result of encodeDataSegmentHeader.
+func dataSegmentHeaderSize(d *wasm.DataSegment) int {
+	size := 1 // the mode
+	if !d.Passive {
+		size += constantExpressionSize(&d.OffsetExpression)
+	}
+	return size + uint32Size(uint32(len(d.Init)))
+}
+
+// nameSectionDataSize returns the length of the result of EncodeNameSectionData. Like a section, each subsection is
+// prefixed by a one-byte ID and the size of its contents.
+func nameSectionDataSize(n *wasm.NameSection) (size int) {
+	if n.ModuleName != "" {
+		size += sectionSize(sizePrefixedSize(len(n.ModuleName)))
+	}
+	if len(n.FunctionNames) > 0 {
+		size += sectionSize(nameMapSize(n.FunctionNames))
+	}
+	if len(n.LocalNames) > 0 {
+		localNames := uint32Size(uint32(len(n.LocalNames)))
+		for _, na := range n.LocalNames {
+			localNames += uint32Size(na.Index) + nameMapSize(na.NameMap)
+		}
+		size += sectionSize(localNames)
+	}
+	if len(n.GlobalNames) > 0 {
+		size += sectionSize(nameMapSize(n.GlobalNames))
+	}
+	if len(n.DataNames) > 0 {
+		size += sectionSize(nameMapSize(n.DataNames))
+	}
+	return
+}
+
+func nameMapSize(m wasm.NameMap) int {
+	size := uint32Size(uint32(len(m)))
+	for _, na := range m {
+		size += uint32Size(na.Index) + sizePrefixedSize(len(na.Name))
+	}
+	return size
+}
@@ -0,0 +1,170 @@
+package binaryencoding
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/testing/dwarftestdata"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasm/binary"
+)
+
+func TestEncodedSize(t *testing.T) {
+	i32, i64 := wasm.ValueTypeI32, wasm.ValueTypeI64
+	max := uint32(200)
+	dataCount := uint32(1)
+	manyLocals := make([]wasm.ValueType, 200) // a count of 200 takes 2 bytes
+	for i := range manyLocals {
+		manyLocals[i] = i64
+	}
+
+	tests := []struct {
+		name  string
+		input *wasm.Module
+	}{
+		{
+			name:  "empty",
+			input: &wasm.Module{},
+		},
+		{
+			name: "locals in runs of the same type",
+			input: &wasm.Module{
+				TypeSection:     []wasm.FunctionType{{}},
+				FunctionSection: []wasm.Index{0, 0},
+				CodeSection: []wasm.Code{
+					{LocalTypes: []wasm.ValueType{i32, i32, i64, i32}, Body: []byte{wasm.OpcodeEnd}},
+					{LocalTypes: manyLocals, Body: []byte{wasm.OpcodeEnd}},
+				},
+			},
+		},
+		{
+			name: "imports of each type",
+			input: &wasm.Module{
+				TypeSection: []wasm.FunctionType{{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i64}}},
+				ImportSection: []wasm.Import{
+					{Type: wasm.ExternTypeFunc, Module: "env", Name: "f", DescFunc: 0},
+					{Type: wasm.ExternTypeTable, Module: "env", Name: "t", DescTable: wasm.Table{Min: 1, Max: &max}},
+					{Type: wasm.ExternTypeMemory, Module: "env", Name: "m", DescMem: &wasm.Memory{Min: 1, Max: 200, IsMaxEncoded: true}},
+					{Type: wasm.ExternTypeGlobal, Module: "env", Name: "g", DescGlobal: wasm.GlobalType{ValType: i32}},
+				},
+			},
+		},
+		{
+			name: "signed element indexes",
+			input: &wasm.Module{
+				TableSection: []wasm.Table{{Min: 200}},
+				ElementSection: []wasm.ElementSegment{{
+					OffsetExpr: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}},
+					Init:       []wasm.Index{0, 63, 64, 199}, // 64 takes 2 bytes as signed LEB128
+					Type:       wasm.RefTypeFuncref,
+				}},
+			},
+		},
+		{
+			name: "data, start and data count",
+			input: &wasm.Module{
+				TypeSection:      []wasm.FunctionType{{}},
+				FunctionSection:  []wasm.Index{0},
+				CodeSection:      []wasm.Code{{Body: []byte{wasm.OpcodeEnd}}},
+				MemorySection:    &wasm.Memory{Min: 1},
+				StartSection:     new(wasm.Index),
+				DataCountSection: &dataCount,
+				DataSection: []wasm.DataSegment{
+					{OffsetExpression: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}}, Init: []byte("hi")},
+					{Passive: true, Init: make([]byte, 200)},
+				},
+			},
+		},
+		{
+			name: "name, custom and unknown sections",
+			input: &wasm.Module{
+				NameSection: &wasm.NameSection{
+					ModuleName:    "m",
+					FunctionNames: wasm.NameMap{{Index: 200, Name: "f"}},
+					LocalNames:    wasm.IndirectNameMap{{Index: 0, NameMap: wasm.NameMap{{Index: 0, Name: "x"}}}},
+					GlobalNames:   wasm.NameMap{{Index: 0, Name: "g"}},
+					DataNames:     wasm.NameMap{{Index: 0, Name: "d"}},
+				},
+				CustomSections:  []*wasm.CustomSection{{Name: "c", Data: make([]byte, 200)}},
+				UnknownSections: map[wasm.SectionID][]byte{0x42: {1, 2, 3}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			size, err := EncodedSize(tc.input)
+			require.NoError(t, err)
+			require.Equal(t, len(EncodeModule(tc.input)), size)
+		})
+	}
+
+	t.Run("compiled by toolchains", func(t *testing.T) {
+		for _, bin := range [][]byte{dwarftestdata.TinyGoWasm, dwarftestdata.ZigWasm, dwarftestdata.ZigCCWasm} {
+			m, err := binary.DecodeModule(bin, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, true)
+			require.NoError(t, err)
+
+			size, err := EncodedSize(m)
+			require.NoError(t, err)
+			require.Equal(t, len(EncodeModule(m)), size)
+		}
+	})
+}
+
+func TestEncodedSize_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       *wasm.Module
+		expectedErr string
+	}{
+		{
+			name: "function implemented in Go",
+			input: &wasm.Module{
+				TypeSection:     []wasm.FunctionType{{}},
+				FunctionSection: []wasm.Index{0},
+				CodeSection:     []wasm.Code{wasm.MustParseGoReflectFuncCode(func() {})},
+			},
+			expectedErr: "code[0]: a function implemented in Go isn't encodable",
+		},
+		{
+			name: "passive element",
+			input: &wasm.Module{
+				ElementSection: []wasm.ElementSegment{{Mode: wasm.ElementModePassive, Type: wasm.RefTypeFuncref}},
+			},
+			expectedErr: "element[0]: encoding non-active elements isn't supported",
+		},
+		{
+			name: "duplicate export name",
+			input: &wasm.Module{
+				GlobalSection: []wasm.Global{{
+					Type: wasm.GlobalType{ValType: wasm.ValueTypeI32},
+					Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}},
+				}},
+				ExportSection: []wasm.Export{
+					{Type: wasm.ExternTypeGlobal, Name: "g", Index: 0},
+					{Type: wasm.ExternTypeGlobal, Name: "g", Index: 0},
+				},
+			},
+			expectedErr: `export[1] duplicates name "g"`,
+		},
+		{
+			name: "invalid import type",
+			input: &wasm.Module{
+				ImportSection: []wasm.Import{{Type: 0x42, Module: "env", Name: "x"}},
+			},
+			expectedErr: "import[0]: invalid externtype: 0x42",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := EncodedSize(tc.input)
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package binaryencoding
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// streamCodeSection emits the same bytes as encodeCodeSection, without buffering the section. The first pass
+// computes the size of the section from the size of each function, and the second emits the section header followed
+// by each function's size and locals, then its body as-is.
+func streamCodeSection(code []wasm.Code, preserveLocals bool, emit func([]byte) error) error {
+	count := leb128.EncodeUint32(uint32(len(code)))
+
+	// First pass: compute the size of the section contents.
+	size := uint64(len(count))
+	for i := range code {
+		c := &code[i]
+		if c.GoFunc != nil {
+			panic("BUG: GoFunction is not encodable")
+		}
+		codeSize := uint64(localsSize(c, preserveLocals)) + uint64(len(c.Body))
+		size += uint64(len(leb128.EncodeUint64(codeSize))) + codeSize
+	}
+
+	// Second pass: emit the header, then each function.
+	if err := emitSectionHeader(wasm.SectionIDCode, size, emit); err != nil {
+		return err
+	}
+	if err := emit(count); err != nil {
+		return err
+	}
+	for i := range code {
+		c := &code[i]
+		locals := encodeLocals(c, preserveLocals)
+		codeSize := uint32(len(locals) + len(c.Body))
+		if err := emit(append(leb128.EncodeUint32(codeSize), locals...)); err != nil {
+			return err
+		}
+		if err := emit(c.Body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// streamDataSection emits the same bytes as encodeDataSection, without buffering the section. The first pass
+// computes the size of the section from the size of each segment, and the second emits the section header followed
+// by each segment's header, then its Init bytes as-is.
+func streamDataSection(datum []wasm.DataSegment, emit func([]byte) error) error {
+	count := leb128.EncodeUint32(uint32(len(datum)))
+
+	// First pass: compute the size of the section contents.
+	size := uint64(len(count))
+	for i := range datum {
+		d := &datum[i]
+		size += uint64(dataSegmentHeaderSize(d)) + uint64(len(d.Init))
+	}
+
+	// Second pass: emit the header, then each segment.
+	if err := emitSectionHeader(wasm.SectionIDData, size, emit); err != nil {
+		return err
+	}
+	if err := emit(count); err != nil {
+		return err
+	}
+	for i := range datum {
+		d := &datum[i]
+		if err := emit(encodeDataSegmentHeader(d)); err != nil {
+			return err
+		}
+		if err := emit(d.Init); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// emitSectionHeader emits the section ID and the size of its contents, which must fit in 32 bits.
+func emitSectionHeader(sectionID wasm.SectionID, size uint64, emit func([]byte) error) error {
+	if size > math.MaxUint32 {
+		return fmt.Errorf("section %s: size %d exceeds the maximum %d", wasm.SectionIDName(sectionID), size, uint32(math.MaxUint32))
+	}
+	return emit(append([]byte{sectionID}, leb128.EncodeUint32(uint32(size))...))
+}
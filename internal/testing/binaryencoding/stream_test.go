@@ -0,0 +1,52 @@
+package binaryencoding
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// maxWriteWriter records the size of the largest write.
+type maxWriteWriter struct {
+	bytes.Buffer
+	maxWrite int
+}
+
+func (w *maxWriteWriter) Write(p []byte) (int, error) {
+	if len(p) > w.maxWrite {
+		w.maxWrite = len(p)
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestStreamingWriterTo(t *testing.T) {
+	const segmentSize = 4 << 20 // 4 MiB
+	large := bytes.Repeat([]byte{0xaa}, segmentSize)
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0, 0},
+		CodeSection: []wasm.Code{
+			{LocalTypes: []wasm.ValueType{wasm.ValueTypeI32, wasm.ValueTypeI32, wasm.ValueTypeI64}, Body: []byte{wasm.OpcodeEnd}},
+			{Body: append(bytes.Repeat([]byte{wasm.OpcodeNop}, segmentSize), wasm.OpcodeEnd)},
+		},
+		MemorySection: &wasm.Memory{Min: 128},
+		DataSection: []wasm.DataSegment{
+			{OffsetExpression: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}}, Init: large},
+			{Passive: true, Init: large},
+			{Passive: true},
+		},
+		NameSection: &wasm.NameSection{ModuleName: "large"},
+	}
+	expected := EncodeModule(m)
+
+	var w maxWriteWriter
+	n, err := StreamingWriterTo(m).WriteTo(&w)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(expected)), n)
+	require.True(t, bytes.Equal(expected, w.Bytes()))
+
+	// The largest write is a function body or segment, not a whole section.
+	require.Equal(t, segmentSize+1, w.maxWrite)
+}
@@ -0,0 +1,149 @@
+// Package wasmdiff compares wasm.Module values to give clearer failures in tests, such as encoder round trips.
+package wasmdiff
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// valueTypeFields are the names of fields whose type is wasm.ValueType or []wasm.ValueType. These are formatted with
+// wasm.ValueTypeName, as wasm.ValueType is an alias of byte and can't be identified by reflection.
+var valueTypeFields = map[string]struct{}{"Params": {}, "Results": {}, "LocalTypes": {}, "ValType": {}}
+
+// Diff compares two modules section by section and returns a description of the first difference, or an empty
+// string if they are equal. For example:
+//
+//	TypeSection[2].Params differ: [i32] vs [i64]
+//
+// Note: Unexported fields, such as cached values, are not compared.
+func Diff(expected, actual *wasm.Module) string {
+	return diff("", "", reflect.ValueOf(expected), reflect.ValueOf(actual))
+}
+
+// diff returns a description of the first difference between x and y at path, or an empty string if they are equal.
+// name is the name of the last struct field in path, used to format value types.
+func diff(path, name string, x, y reflect.Value) string {
+	if x.Kind() == reflect.Interface {
+		if x.IsNil() || y.IsNil() {
+			if x.IsNil() != y.IsNil() {
+				return differ(path, name, x, y)
+			}
+			return ""
+		}
+		x, y = x.Elem(), y.Elem()
+		if x.Type() != y.Type() {
+			return fmt.Sprintf("%s types differ: %s vs %s", pathOrRoot(path), x.Type(), y.Type())
+		}
+	}
+
+	switch x.Kind() {
+	case reflect.Pointer:
+		if x.IsNil() || y.IsNil() {
+			if x.IsNil() != y.IsNil() {
+				return differ(path, name, x, y)
+			}
+			return ""
+		}
+		return diff(path, name, x.Elem(), y.Elem())
+	case reflect.Struct:
+		t := x.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() || f.Anonymous {
+				continue
+			}
+			if d := diff(join(path, f.Name), f.Name, x.Field(i), y.Field(i)); d != "" {
+				return d
+			}
+		}
+		return ""
+	case reflect.Slice, reflect.Array:
+		if x.Kind() == reflect.Slice && x.Type().Elem().Kind() == reflect.Uint8 {
+			if !reflect.DeepEqual(x.Interface(), y.Interface()) {
+				return differ(path, name, x, y)
+			}
+			return ""
+		}
+		if x.Len() != y.Len() {
+			return fmt.Sprintf("%s lengths differ: %d vs %d", pathOrRoot(path), x.Len(), y.Len())
+		}
+		for i := 0; i < x.Len(); i++ {
+			if d := diff(fmt.Sprintf("%s[%d]", path, i), name, x.Index(i), y.Index(i)); d != "" {
+				return d
+			}
+		}
+		return ""
+	case reflect.Map:
+		if x.Len() != y.Len() {
+			return fmt.Sprintf("%s lengths differ: %d vs %d", pathOrRoot(path), x.Len(), y.Len())
+		}
+		keys := x.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		for _, k := range keys {
+			keyPath := fmt.Sprintf("%s[%#v]", path, k.Interface())
+			yv := y.MapIndex(k)
+			if !yv.IsValid() {
+				return fmt.Sprintf("%s missing", keyPath)
+			}
+			if d := diff(keyPath, name, x.MapIndex(k), yv); d != "" {
+				return d
+			}
+		}
+		return ""
+	case reflect.Func:
+		if x.IsNil() != y.IsNil() {
+			return differ(path, name, x, y)
+		}
+		return "" // Functions can't be compared beyond nil.
+	default:
+		if x.Interface() != y.Interface() {
+			return differ(path, name, x, y)
+		}
+		return ""
+	}
+}
+
+func differ(path, name string, x, y reflect.Value) string {
+	return fmt.Sprintf("%s differ: %s vs %s", pathOrRoot(path), format(name, x), format(name, y))
+}
+
+// format formats v, using value type names when name is in valueTypeFields.
+func format(name string, v reflect.Value) string {
+	if (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface || v.Kind() == reflect.Func) && v.IsNil() {
+		return "nil"
+	}
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if _, ok := valueTypeFields[name]; ok {
+		switch v.Kind() {
+		case reflect.Uint8:
+			return wasm.ValueTypeName(byte(v.Uint()))
+		case reflect.Slice:
+			names := make([]string, v.Len())
+			for i := range names {
+				names[i] = wasm.ValueTypeName(byte(v.Index(i).Uint()))
+			}
+			return "[" + strings.Join(names, " ") + "]"
+		}
+	}
+	return fmt.Sprintf("%v", v.Interface())
+}
+
+func join(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "module"
+	}
+	return path
+}
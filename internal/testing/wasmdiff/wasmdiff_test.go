@@ -0,0 +1,89 @@
+package wasmdiff
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func TestDiff(t *testing.T) {
+	i32, i64 := wasm.ValueTypeI32, wasm.ValueTypeI64
+	zero, one := wasm.Index(0), wasm.Index(1)
+
+	// cached has its unexported string field set, unlike the same type written as a literal.
+	cached := wasm.FunctionType{Params: []wasm.ValueType{i32}}
+	_ = cached.String()
+
+	tests := []struct {
+		name             string
+		expected, actual *wasm.Module
+		expectedDiff     string
+	}{
+		{
+			name: "equal",
+			expected: &wasm.Module{
+				TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}},
+				FunctionSection: []wasm.Index{0},
+				CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeEnd}}},
+			},
+			actual: &wasm.Module{
+				TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}},
+				FunctionSection: []wasm.Index{0},
+				CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeEnd}}},
+			},
+		},
+		{
+			name: "value types",
+			expected: &wasm.Module{TypeSection: []wasm.FunctionType{
+				{}, {Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}},
+			}},
+			actual: &wasm.Module{TypeSection: []wasm.FunctionType{
+				{}, {Params: []wasm.ValueType{i64}, Results: []wasm.ValueType{i32}},
+			}},
+			expectedDiff: "TypeSection[1].Params differ: [i32] vs [i64]",
+		},
+		{
+			name:         "slice length",
+			expected:     &wasm.Module{FunctionSection: []wasm.Index{2}},
+			actual:       &wasm.Module{},
+			expectedDiff: "FunctionSection lengths differ: 1 vs 0",
+		},
+		{
+			name:         "body",
+			expected:     &wasm.Module{CodeSection: []wasm.Code{{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeEnd}}}},
+			actual:       &wasm.Module{CodeSection: []wasm.Code{{Body: []byte{wasm.OpcodeEnd}}}},
+			expectedDiff: "CodeSection[0].Body differ: [32 0 11] vs [11]",
+		},
+		{
+			name:         "map",
+			expected:     &wasm.Module{Exports: map[string]*wasm.Export{"f": {Type: wasm.ExternTypeFunc, Name: "f"}}},
+			actual:       &wasm.Module{Exports: map[string]*wasm.Export{"f": {Type: wasm.ExternTypeFunc, Name: "g"}}},
+			expectedDiff: `Exports["f"].Name differ: f vs g`,
+		},
+		{
+			name:         "nil pointer",
+			expected:     &wasm.Module{StartSection: &one},
+			actual:       &wasm.Module{},
+			expectedDiff: "StartSection differ: 1 vs nil",
+		},
+		{
+			name:         "pointer",
+			expected:     &wasm.Module{StartSection: &one},
+			actual:       &wasm.Module{StartSection: &zero},
+			expectedDiff: "StartSection differ: 1 vs 0",
+		},
+		{
+			name:     "unexported fields are ignored",
+			expected: &wasm.Module{TypeSection: []wasm.FunctionType{{Params: []wasm.ValueType{i32}}}},
+			actual:   &wasm.Module{TypeSection: []wasm.FunctionType{cached}},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expectedDiff, Diff(tc.expected, tc.actual))
+		})
+	}
+}
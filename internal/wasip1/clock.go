@@ -11,6 +11,13 @@ const (
 	ClockIDRealtime = iota
 	// ClockIDMonotonic is the name ID named "monotonic" like sys.Nanotime
 	ClockIDMonotonic
-	// Note: clockIDProcessCputime and clockIDThreadCputime were removed by
-	// WASI maintainers: https://github.com/WebAssembly/wasi-libc/pull/294
+	// ClockIDProcessCputime is the name ID named "process_cputime_id", which
+	// is defined, but not supported.
+	//
+	// Note: This and ClockIDThreadCputime were removed by WASI maintainers:
+	// https://github.com/WebAssembly/wasi-libc/pull/294
+	ClockIDProcessCputime
+	// ClockIDThreadCputime is the name ID named "thread_cputime_id", which is
+	// defined, but not supported.
+	ClockIDThreadCputime
 )
@@ -4,13 +4,16 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"math"
 
 	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
 
-func decodeCode(r *bytes.Reader, codeSectionStart uint64, ret *wasm.Code) (err error) {
+// decodeCode decodes a function body into ret, failing if it declares more than maxLocals locals.
+//
+// The total count of locals is checked before allocating them, so a malicious run-length entry such as
+// 0xffffffff locals can't cause a huge allocation.
+func decodeCode(r *bytes.Reader, codeSectionStart uint64, maxLocals uint32, ret *wasm.Code) (err error) {
 	ss, _, err := leb128.DecodeUint32(r)
 	if err != nil {
 		return fmt.Errorf("get the size of code: %w", err)
@@ -29,6 +32,8 @@ func decodeCode(r *bytes.Reader, codeSectionStart uint64, ret *wasm.Code) (err e
 	// Validate the locals.
 	bytesRead = 0
 	var sum uint64
+	canonical := true // false if the entries aren't the canonical run-length encoding of the locals.
+	var lastType wasm.ValueType
 	for i := uint32(0); i < ls; i++ {
 		num, n, err := leb128.DecodeUint32(r)
 		if err != nil {
@@ -37,7 +42,9 @@ func decodeCode(r *bytes.Reader, codeSectionStart uint64, ret *wasm.Code) (err e
 			return io.EOF
 		}
 
-		sum += uint64(num)
+		if sum += uint64(num); sum > uint64(maxLocals) {
+			return fmt.Errorf("too many locals: %d", sum)
+		}
 
 		b, err := r.ReadByte()
 		if err != nil {
@@ -51,10 +58,10 @@ func decodeCode(r *bytes.Reader, codeSectionStart uint64, ret *wasm.Code) (err e
 		default:
 			return fmt.Errorf("invalid local type: 0x%x", vt)
 		}
-	}
-
-	if sum > math.MaxUint32 {
-		return fmt.Errorf("too many locals: %d", sum)
+		if num == 0 || (i > 0 && b == lastType) {
+			canonical = false
+		}
+		lastType = b
 	}
 
 	// Rewind the buffer.
@@ -64,6 +71,10 @@ func decodeCode(r *bytes.Reader, codeSectionStart uint64, ret *wasm.Code) (err e
 	}
 
 	localTypes := make([]wasm.ValueType, 0, sum)
+	var localEntries []wasm.LocalEntry
+	if !canonical {
+		localEntries = make([]wasm.LocalEntry, 0, ls)
+	}
 	for i := uint32(0); i < ls; i++ {
 		num, bytesRead, err := leb128.DecodeUint32(r)
 		remaining -= int64(bytesRead) + 1 // +1 for the subsequent ReadByte
@@ -81,6 +92,9 @@ func decodeCode(r *bytes.Reader, codeSectionStart uint64, ret *wasm.Code) (err e
 		for j := uint32(0); j < num; j++ {
 			localTypes = append(localTypes, b)
 		}
+		if !canonical {
+			localEntries = append(localEntries, wasm.LocalEntry{Count: num, Type: b})
+		}
 	}
 
 	bodyOffsetInCodeSection := codeSectionStart - uint64(r.Len())
@@ -95,6 +109,31 @@ func decodeCode(r *bytes.Reader, codeSectionStart uint64, ret *wasm.Code) (err e
 
 	ret.BodyOffsetInCodeSection = bodyOffsetInCodeSection
 	ret.LocalTypes = localTypes
+	ret.LocalEntries = localEntries
 	ret.Body = body
 	return nil
 }
+
+// decodeCodeLazily reads a function body into ret without decoding it. Instead, ret.DecodeBody decodes it like
+// decodeCode, when wasm.Module DecodeFunctionBody is first called for it.
+func decodeCodeLazily(r *bytes.Reader, codeSectionStart uint64, maxLocals uint32, ret *wasm.Code) (err error) {
+	entryOffsetInCodeSection := codeSectionStart - uint64(r.Len())
+	ss, n, err := leb128.DecodeUint32(r)
+	if err != nil {
+		return fmt.Errorf("get the size of code: %w", err)
+	}
+
+	// Rewind the buffer, so that the entry includes its size as decodeCode expects.
+	if _, err = r.Seek(-int64(n), io.SeekCurrent); err != nil {
+		return err
+	}
+	entry := make([]byte, n+uint64(ss))
+	if _, err = io.ReadFull(r, entry); err != nil {
+		return fmt.Errorf("read body: %w", err)
+	}
+
+	ret.DecodeBody = func(c *wasm.Code) error {
+		return decodeCode(bytes.NewReader(entry), entryOffsetInCodeSection+uint64(len(entry)), maxLocals, c)
+	}
+	return nil
+}
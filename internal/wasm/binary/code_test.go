@@ -0,0 +1,108 @@
+package binary
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func TestDecodeCode(t *testing.T) {
+	input := []byte{
+		0x06,                    // size of the function
+		0x02,                    // two runs of locals
+		0x02, wasm.ValueTypeI32, // (local i32 i32)
+		0x01, wasm.ValueTypeI64, // (local i64)
+		wasm.OpcodeEnd, // body
+	}
+	var actual wasm.Code
+	err := decodeCode(bytes.NewReader(input), uint64(len(input)), 3, &actual)
+	require.NoError(t, err)
+	require.Equal(t, []wasm.ValueType{wasm.ValueTypeI32, wasm.ValueTypeI32, wasm.ValueTypeI64}, actual.LocalTypes)
+	require.Equal(t, []byte{wasm.OpcodeEnd}, actual.Body)
+}
+
+func TestDecodeCode_LocalEntries(t *testing.T) {
+	input := []byte{
+		0x08,                    // size of the function
+		0x03,                    // three runs of locals
+		0x01, wasm.ValueTypeI32, // (local i32)
+		0x01, wasm.ValueTypeI32, // (local i32), which isn't compressed with the previous run
+		0x00, wasm.ValueTypeF64, // an empty run
+		wasm.OpcodeEnd, // body
+	}
+	var actual wasm.Code
+	err := decodeCode(bytes.NewReader(input), uint64(len(input)), wasm.MaximumFunctionLocals, &actual)
+	require.NoError(t, err)
+	require.Equal(t, []wasm.ValueType{wasm.ValueTypeI32, wasm.ValueTypeI32}, actual.LocalTypes)
+	require.Equal(t, []wasm.LocalEntry{
+		{Count: 1, Type: wasm.ValueTypeI32},
+		{Count: 1, Type: wasm.ValueTypeI32},
+		{Count: 0, Type: wasm.ValueTypeF64},
+	}, actual.LocalEntries)
+
+	// The entries round-trip when preserving locals.
+	m := &wasm.Module{TypeSection: []wasm.FunctionType{{}}, FunctionSection: []wasm.Index{0}, CodeSection: []wasm.Code{actual}}
+	encoded := binaryencoding.EncodeModulePreservingLocals(m)
+	decoded, err := DecodeModule(encoded, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, false)
+	require.NoError(t, err)
+	require.Equal(t, actual.LocalEntries, decoded.CodeSection[0].LocalEntries)
+	require.Equal(t, encoded, binaryencoding.EncodeModulePreservingLocals(decoded))
+}
+
+func TestDecodeCode_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       []byte
+		maxLocals   uint32
+		expectedErr string
+	}{
+		{
+			name: "run exceeds maximum locals",
+			input: []byte{
+				0x08,                                            // size of the function
+				0x01,                                            // one run of locals
+				0xff, 0xff, 0xff, 0xff, 0x0f, wasm.ValueTypeI32, // 0xffffffff locals
+				wasm.OpcodeEnd,
+			},
+			maxLocals:   wasm.MaximumFunctionLocals,
+			expectedErr: "too many locals: 4294967295",
+		},
+		{
+			name: "sum of runs exceeds maximum locals",
+			input: []byte{
+				0x06,                    // size of the function
+				0x02,                    // two runs of locals
+				0x02, wasm.ValueTypeI32, // (local i32 i32)
+				0x02, wasm.ValueTypeI64, // (local i64 i64)
+				wasm.OpcodeEnd,
+			},
+			maxLocals:   3,
+			expectedErr: "too many locals: 4",
+		},
+		{
+			name: "sum of runs overflows uint32",
+			input: []byte{
+				0x0d,                                            // size of the function
+				0x02,                                            // two runs of locals
+				0xff, 0xff, 0xff, 0xff, 0x0f, wasm.ValueTypeI32, // 0xffffffff locals
+				0xff, 0xff, 0xff, 0xff, 0x0f, wasm.ValueTypeI32, // 0xffffffff locals
+				wasm.OpcodeEnd,
+			},
+			maxLocals:   0xffffffff,
+			expectedErr: "too many locals: 8589934590",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			var actual wasm.Code
+			err := decodeCode(bytes.NewReader(tc.input), uint64(len(tc.input)), tc.maxLocals, &actual)
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
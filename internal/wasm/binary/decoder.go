@@ -3,7 +3,6 @@ package binary
 import (
 	"bytes"
 	"debug/dwarf"
-	"errors"
 	"fmt"
 	"io"
 
@@ -14,6 +13,12 @@ import (
 )
 
 // DecodeModule implements wasm.DecodeModule for the WebAssembly 1.0 (20191205) Binary Format
+//
+// This only checks the binary is well-formed, and whether it uses features which aren't enabled. The result can be
+// inspected even if it isn't valid, for example when a function body is type-unsound. Use DecodeAndValidateModule or
+// wasm.Module Validate to also check semantics.
+//
+// See DecodeModuleWithOptions for options beyond these.
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#binary-format%E2%91%A0
 func DecodeModule(
 	binary []byte,
@@ -22,8 +27,77 @@ func DecodeModule(
 	memoryCapacityFromMax,
 	dwarfEnabled, storeCustomSections bool,
 ) (*wasm.Module, error) {
-	r := bytes.NewReader(binary)
+	return DecodeModuleWithOptions(binary, enabledFeatures, memoryLimitPages, DecodeOptions{
+		MemoryCapacityFromMax: memoryCapacityFromMax,
+		DWARFEnabled:          dwarfEnabled,
+		StoreCustomSections:   storeCustomSections,
+	})
+}
+
+// DecodeOptions are options of DecodeModuleWithOptions and DecodeModuleReader. The zero value decodes like
+// DecodeModule with all options false.
+type DecodeOptions struct {
+	// MemoryCapacityFromMax sets the capacity of memories to their maximum, instead of their minimum.
+	MemoryCapacityFromMax bool
+
+	// DWARFEnabled parses DWARF custom sections into wasm.Module DWARFLines, which implies StoreCustomSections.
+	DWARFEnabled bool
+
+	// StoreCustomSections keeps custom sections other than the name section in wasm.Module CustomSections.
+	StoreCustomSections bool
+
+	// StoreUnknownSections keeps sections with an ID not defined by the specification in wasm.Module UnknownSections,
+	// instead of failing with ErrInvalidSectionID.
+	StoreUnknownSections bool
+
+	// StoreRawSections records the contents of each section in wasm.Module RawSections, for example to hash specific
+	// sections. Custom sections are read into memory even if they aren't otherwise stored.
+	StoreRawSections bool
+
+	// Strict requires section sizes to be minimally encoded as ULEB128. Otherwise, padded encodings such as 0x81 0x00
+	// for the size one are tolerated for compatibility with older tools.
+	Strict bool
+
+	// MaxFunctionLocals limits the count of locals declared by a single function, excluding its parameters. Zero
+	// defaults to wasm.MaximumFunctionLocals.
+	MaxFunctionLocals uint32
+
+	// LazyFunctionBodies keeps each function body undecoded until wasm.Module DecodeFunctionBody is called for it,
+	// for example when only a few functions of a large module are used. Module.Validate decodes any remaining bodies.
+	LazyFunctionBodies bool
+}
+
+// DecodeModuleWithOptions is like DecodeModule, except all options are set with DecodeOptions.
+func DecodeModuleWithOptions(
+	binary []byte,
+	enabledFeatures api.CoreFeatures,
+	memoryLimitPages uint32,
+	opts DecodeOptions,
+) (*wasm.Module, error) {
+	return decodeModule(newBytesSource(binary), enabledFeatures, memoryLimitPages, opts)
+}
 
+// DecodeModuleReader is like DecodeModuleWithOptions, except it reads the binary from r, which doesn't need to
+// implement io.Seeker, such as a network stream. Custom sections which aren't stored are discarded with io.CopyN
+// instead of being read into memory.
+//
+// Prefer DecodeModule when the binary is already in memory: it decodes sections in place, whereas this copies each
+// section before decoding it.
+func DecodeModuleReader(
+	r io.Reader,
+	enabledFeatures api.CoreFeatures,
+	memoryLimitPages uint32,
+	opts DecodeOptions,
+) (*wasm.Module, error) {
+	return decodeModule(newStreamSource(r), enabledFeatures, memoryLimitPages, opts)
+}
+
+func decodeModule(
+	r sectionSource,
+	enabledFeatures api.CoreFeatures,
+	memoryLimitPages uint32,
+	opts DecodeOptions,
+) (*wasm.Module, error) {
 	// Magic number.
 	buf := make([]byte, 4)
 	if _, err := io.ReadFull(r, buf); err != nil || !bytes.Equal(buf, Magic) {
@@ -35,13 +109,18 @@ func DecodeModule(
 		return nil, ErrInvalidVersion
 	}
 
-	memSizer := newMemorySizer(memoryLimitPages, memoryCapacityFromMax)
+	maxFunctionLocals := opts.MaxFunctionLocals
+	if maxFunctionLocals == 0 {
+		maxFunctionLocals = wasm.MaximumFunctionLocals
+	}
+	memSizer := newMemorySizer(memoryLimitPages, opts.MemoryCapacityFromMax)
+	keepCustomSections := opts.StoreCustomSections || opts.DWARFEnabled
 
 	m := &wasm.Module{}
-	var info, line, str, abbrev, ranges []byte // For DWARF Data.
+	after := wasm.SectionIDCustom // The last non-custom section, recorded on custom sections.
+	lastKnown := wasm.SectionIDCustom
+	offset := uint64(len(Magic) + len(version)) // The offset of the current section in the binary.
 	for {
-		// TODO: except custom sections, all others are required to be in order, but we aren't checking yet.
-		// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#modules%E2%91%A0%E2%93%AA
 		sectionID, err := r.ReadByte()
 		if err == io.EOF {
 			break
@@ -49,16 +128,43 @@ func DecodeModule(
 			return nil, fmt.Errorf("read section id: %w", err)
 		}
 
-		sectionSize, _, err := leb128.DecodeUint32(r)
-		if err != nil {
+		sectionSize, sizeBytes, err := leb128.DecodeUint32(r)
+		if err == io.EOF {
+			// There's no room for even an empty section, so these bytes are garbage rather than a truncated section.
+			return nil, fmt.Errorf("trailing bytes after the last section at %#x", offset)
+		} else if err != nil {
 			return nil, fmt.Errorf("get size of section %s: %v", wasm.SectionIDName(sectionID), err)
+		} else if opts.Strict && sizeBytes > uint64(len(leb128.EncodeUint32(sectionSize))) {
+			return nil, fmt.Errorf("get size of section %s: non-minimal encoding of %d in %d bytes",
+				wasm.SectionIDName(sectionID), sectionSize, sizeBytes)
+		}
+
+		// Custom sections may appear anywhere, but known sections must be in order and at most once.
+		// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#modules%E2%91%A0%E2%93%AA
+		if order := sectionOrder(sectionID); order > 0 {
+			if sectionID == lastKnown {
+				return nil, fmt.Errorf("multiple %s sections are invalid", wasm.SectionIDName(sectionID))
+			} else if order < sectionOrder(lastKnown) {
+				return nil, fmt.Errorf("section %s: must precede section %s",
+					wasm.SectionIDName(sectionID), wasm.SectionIDName(lastKnown))
+			}
+			lastKnown = sectionID
+		}
+
+		// Decode the section from a reader bounded to its size, so that malformed contents can't consume bytes of the
+		// next section. Custom sections which aren't kept are only read up to their name, so that the rest can be
+		// discarded.
+		nameOnly := sectionID == wasm.SectionIDCustom && !keepCustomSections && !opts.StoreRawSections
+		data, err := r.section(sectionSize, nameOnly)
+		if err != nil {
+			return nil, fmt.Errorf("section %s: %v", wasm.SectionIDName(sectionID), err)
 		}
+		sr := bytes.NewReader(data)
 
-		sectionContentStart := r.Len()
 		switch sectionID {
 		case wasm.SectionIDCustom:
 			// First, validate the section and determine if the section for this name has already been set
-			name, nameSize, decodeErr := decodeUTF8(r, "custom section name")
+			name, nameSize, decodeErr := decodeUTF8(sr, "custom section name")
 			if decodeErr != nil {
 				err = decodeErr
 				break
@@ -75,74 +181,81 @@ func DecodeModule(
 
 			var c *wasm.CustomSection
 			if name != "name" {
-				if storeCustomSections || dwarfEnabled {
-					c, err = decodeCustomSection(r, name, uint64(limit))
+				if keepCustomSections {
+					c, err = decodeCustomSection(sr, name, uint64(limit))
 					if err != nil {
 						return nil, fmt.Errorf("failed to read custom section name[%s]: %w", name, err)
 					}
+					position := after
+					c.After = &position
+					c.AfterNameSection = m.NameSection != nil
 					m.CustomSections = append(m.CustomSections, c)
-					if dwarfEnabled {
-						switch name {
-						case ".debug_info":
-							info = c.Data
-						case ".debug_line":
-							line = c.Data
-						case ".debug_str":
-							str = c.Data
-						case ".debug_abbrev":
-							abbrev = c.Data
-						case ".debug_ranges":
-							ranges = c.Data
-						}
-					}
-				} else {
-					if _, err = io.CopyN(io.Discard, r, int64(limit)); err != nil {
+				} else if nameOnly {
+					if err = r.discard(sr, limit); err != nil {
 						return nil, fmt.Errorf("failed to skip name[%s]: %w", name, err)
 					}
+				} else { // The section was read in full anyway, in order to store it raw.
+					_, _ = sr.Seek(int64(limit), io.SeekCurrent)
 				}
 			} else {
-				m.NameSection, err = decodeNameSection(r, uint64(limit))
+				if nameOnly {
+					if sr, err = r.rest(sr, limit); err != nil {
+						break
+					}
+				}
+				m.NameSection, err = decodeNameSection(sr, uint64(limit))
 			}
 		case wasm.SectionIDType:
-			m.TypeSection, err = decodeTypeSection(enabledFeatures, r)
+			m.TypeSection, err = decodeTypeSection(enabledFeatures, sr)
 		case wasm.SectionIDImport:
-			m.ImportSection, m.ImportPerModule, m.ImportFunctionCount, m.ImportGlobalCount, m.ImportMemoryCount, m.ImportTableCount, err = decodeImportSection(r, memSizer, memoryLimitPages, enabledFeatures)
+			m.ImportSection, m.ImportPerModule, m.ImportFunctionCount, m.ImportGlobalCount, m.ImportMemoryCount, m.ImportTableCount, err = decodeImportSection(sr, memSizer, memoryLimitPages, enabledFeatures)
 			if err != nil {
 				return nil, err // avoid re-wrapping the error.
 			}
 		case wasm.SectionIDFunction:
-			m.FunctionSection, err = decodeFunctionSection(r)
+			m.FunctionSection, err = decodeFunctionSection(sr)
 		case wasm.SectionIDTable:
-			m.TableSection, err = decodeTableSection(r, enabledFeatures)
+			m.TableSection, err = decodeTableSection(sr, enabledFeatures, m.ImportTableCount)
 		case wasm.SectionIDMemory:
-			m.MemorySection, err = decodeMemorySection(r, memSizer, memoryLimitPages)
+			m.MemorySection, err = decodeMemorySection(sr, enabledFeatures, memSizer, memoryLimitPages, m.ImportMemoryCount)
 		case wasm.SectionIDGlobal:
-			if m.GlobalSection, err = decodeGlobalSection(r, enabledFeatures); err != nil {
+			if m.GlobalSection, err = decodeGlobalSection(sr, enabledFeatures); err != nil {
 				return nil, err // avoid re-wrapping the error.
 			}
 		case wasm.SectionIDExport:
-			m.ExportSection, m.Exports, err = decodeExportSection(r)
+			m.ExportSection, m.Exports, err = decodeExportSection(sr)
 		case wasm.SectionIDStart:
-			if m.StartSection != nil {
-				return nil, errors.New("multiple start sections are invalid")
-			}
-			m.StartSection, err = decodeStartSection(r)
+			m.StartSection, err = decodeStartSection(sr)
 		case wasm.SectionIDElement:
-			m.ElementSection, err = decodeElementSection(r, enabledFeatures)
+			m.ElementSection, err = decodeElementSection(sr, enabledFeatures)
 		case wasm.SectionIDCode:
-			m.CodeSection, err = decodeCodeSection(r)
+			m.CodeSection, err = decodeCodeSection(sr, maxFunctionLocals, opts.LazyFunctionBodies)
 		case wasm.SectionIDData:
-			m.DataSection, err = decodeDataSection(r, enabledFeatures)
+			m.DataSection, err = decodeDataSection(sr, enabledFeatures)
 		case wasm.SectionIDDataCount:
 			if err := enabledFeatures.RequireEnabled(api.CoreFeatureBulkMemoryOperations); err != nil {
 				return nil, fmt.Errorf("data count section not supported as %v", err)
 			}
-			m.DataCountSection, err = decodeDataCountSection(r)
+			m.DataCountSection, err = decodeDataCountSection(sr)
 		default:
-			err = ErrInvalidSectionID
+			if !opts.StoreUnknownSections {
+				err = ErrInvalidSectionID
+				break
+			}
+			if _, ok := m.UnknownSections[sectionID]; ok {
+				return nil, fmt.Errorf("redundant section with id %#x", sectionID)
+			}
+			data := make([]byte, sectionSize)
+			if _, err = io.ReadFull(sr, data); err != nil {
+				return nil, fmt.Errorf("read section with id %#x: %w", sectionID, err)
+			}
+			if m.UnknownSections == nil {
+				m.UnknownSections = map[wasm.SectionID][]byte{}
+			}
+			m.UnknownSections[sectionID] = data
 		}
 
-		readBytes := sectionContentStart - r.Len()
+		readBytes := int(sectionSize) - sr.Len()
 		if err == nil && int(sectionSize) != readBytes {
 			err = fmt.Errorf("invalid section length: expected to be %d but got %d", sectionSize, readBytes)
 		}
@@ -150,10 +263,19 @@ func DecodeModule(
 		if err != nil {
 			return nil, fmt.Errorf("section %s: %v", wasm.SectionIDName(sectionID), err)
 		}
+		if sectionID != wasm.SectionIDCustom {
+			after = sectionID
+		}
+		end := offset + 1 + sizeBytes + uint64(sectionSize) // +1 for the section ID
+		if opts.StoreRawSections {
+			m.RawSections = append(m.RawSections, wasm.RawSection{ID: sectionID, Data: data})
+		}
+		offset = end
 	}
 
-	if dwarfEnabled {
-		d, _ := dwarf.New(abbrev, nil, nil, info, line, nil, ranges, str)
+	if opts.DWARFEnabled {
+		s := m.DWARFSections()
+		d, _ := dwarf.New(s[".debug_abbrev"], nil, nil, s[".debug_info"], s[".debug_line"], nil, s[".debug_ranges"], s[".debug_str"])
 		m.DWARFLines = wasmdebug.NewDWARFLines(d)
 	}
 
@@ -164,6 +286,45 @@ func DecodeModule(
 	return m, nil
 }
 
+// sectionOrder returns the position of a known section in the binary format, or zero for custom and unknown sections,
+// which aren't ordered.
+//
+// Note: The data count section is between the element and code sections, despite its higher ID.
+func sectionOrder(sectionID wasm.SectionID) int {
+	switch sectionID {
+	case wasm.SectionIDCustom:
+		return 0
+	case wasm.SectionIDDataCount:
+		return int(wasm.SectionIDElement) + 1
+	case wasm.SectionIDCode, wasm.SectionIDData:
+		return int(sectionID) + 1
+	}
+	if sectionID < wasm.SectionIDCode {
+		return int(sectionID)
+	}
+	return 0
+}
+
+// DecodeAndValidateModule is like DecodeModule, except it also validates the decoded module with
+// wasm.Module Validate, as needed before compiling it.
+func DecodeAndValidateModule(
+	binary []byte,
+	enabledFeatures api.CoreFeatures,
+	memoryLimitPages uint32,
+	memoryCapacityFromMax,
+	dwarfEnabled, storeCustomSections bool,
+) (*wasm.Module, error) {
+	m, err := DecodeModule(binary, enabledFeatures, memoryLimitPages, memoryCapacityFromMax, dwarfEnabled, storeCustomSections)
+	if err != nil {
+		return nil, err
+	} else if err = m.Validate(enabledFeatures); err != nil {
+		// TODO: decoders should validate before returning, as that allows
+		// them to err with the correct position in the wasm binary.
+		return nil, err
+	}
+	return m, nil
+}
+
 // memorySizer derives min, capacity and max pages from decoded wasm.
 type memorySizer func(minPages uint32, maxPages *uint32) (min uint32, capacity uint32, max uint32)
 
@@ -1,12 +1,16 @@
 package binary
 
 import (
+	"bytes"
 	"testing"
+	"testing/iotest"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
 	"github.com/tetratelabs/wazero/internal/testing/dwarftestdata"
 	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/testing/wasmdiff"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
 
@@ -115,6 +119,26 @@ func TestDecodeModule(t *testing.T) {
 				StartSection: &zero,
 			},
 		},
+		{
+			name: "local names in two functions",
+			input: &wasm.Module{
+				TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32}}},
+				FunctionSection: []wasm.Index{0, 0},
+				CodeSection: []wasm.Code{
+					// The body follows the function count, the function's size and its locals: 1 + 1 + 3
+					{LocalTypes: []wasm.ValueType{i32}, Body: []byte{wasm.OpcodeEnd}, BodyOffsetInCodeSection: 5},
+					// The body follows the previous function and this one's size and locals: 5 + 1 + 1 + 3
+					{LocalTypes: []wasm.ValueType{i32, i32}, Body: []byte{wasm.OpcodeEnd}, BodyOffsetInCodeSection: 10},
+				},
+				NameSection: &wasm.NameSection{
+					FunctionNames: wasm.NameMap{{Index: 0, Name: "f"}, {Index: 1, Name: "g"}},
+					LocalNames: wasm.IndirectNameMap{
+						{Index: 0, NameMap: wasm.NameMap{{Index: 0, Name: "x"}, {Index: 1, Name: "y"}}},
+						{Index: 1, NameMap: wasm.NameMap{{Index: 0, Name: "l"}, {Index: 2, Name: "r"}}},
+					},
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -136,10 +160,13 @@ func TestDecodeModule(t *testing.T) {
 				}
 				tc.input.ImportPerModule = expImportPerModule
 			}
+			require.Equal(t, "", wasmdiff.Diff(tc.input, m))
 			require.Equal(t, tc.input, m)
 		})
 	}
 
+	first := wasm.SectionIDCustom // custom sections preceding all others.
+
 	t.Run("skips custom section", func(t *testing.T) {
 		input := append(append(Magic, version...),
 			wasm.SectionIDCustom, 0xf, // 15 bytes in this section
@@ -160,13 +187,27 @@ func TestDecodeModule(t *testing.T) {
 		require.Equal(t, &wasm.Module{
 			CustomSections: []*wasm.CustomSection{
 				{
-					Name: "meme",
-					Data: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 0},
+					Name:  "meme",
+					Data:  []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 0},
+					After: &first,
 				},
 			},
 		}, m)
 	})
 
+	t.Run("only custom section round-trips", func(t *testing.T) {
+		input := append(append(Magic, version...),
+			wasm.SectionIDCustom, 0x06, // 6 bytes in this section
+			0x04, 'm', 'e', 'm', 'e',
+			1)
+		m, e := DecodeModule(input, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, true)
+		require.NoError(t, e)
+		require.Equal(t, &wasm.Module{
+			CustomSections: []*wasm.CustomSection{{Name: "meme", Data: []byte{1}, After: &first}},
+		}, m)
+		require.Equal(t, input, binaryencoding.EncodeModule(m))
+	})
+
 	t.Run("skips custom section, but not name", func(t *testing.T) {
 		input := append(append(Magic, version...),
 			wasm.SectionIDCustom, 0xf, // 15 bytes in this section
@@ -198,13 +239,96 @@ func TestDecodeModule(t *testing.T) {
 			NameSection: &wasm.NameSection{ModuleName: "simple"},
 			CustomSections: []*wasm.CustomSection{
 				{
-					Name: "meme",
-					Data: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 0},
+					Name:  "meme",
+					Data:  []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 0},
+					After: &first,
 				},
 			},
 		}, m)
 	})
 
+	t.Run("custom section position round-trips", func(t *testing.T) {
+		input := append(append(Magic, version...),
+			wasm.SectionIDType, 0x04, 0x01, 0x60, 0x00, 0x00, // (type (func))
+			wasm.SectionIDCustom, 0x05, // 5 bytes in this section
+			0x03, 'f', 'o', 'o', 1,
+			wasm.SectionIDFunction, 0x02, 0x01, 0x00, // one function of type 0
+			wasm.SectionIDCode, 0x04, 0x01, 0x02, 0x00, wasm.OpcodeEnd, // one empty body
+			wasm.SectionIDCustom, 0x05, // 5 bytes in this section
+			0x03, 'b', 'a', 'r', 2)
+		m, e := DecodeModule(input, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, true)
+		require.NoError(t, e)
+
+		afterType, afterCode := wasm.SectionIDType, wasm.SectionIDCode
+		require.Equal(t, []*wasm.CustomSection{
+			{Name: "foo", Data: []byte{1}, After: &afterType},
+			{Name: "bar", Data: []byte{2}, After: &afterCode},
+		}, m.CustomSections)
+		require.Equal(t, input, binaryencoding.EncodeModule(m))
+	})
+
+	t.Run("custom sections around the name section round-trip", func(t *testing.T) {
+		input := append(append(Magic, version...),
+			wasm.SectionIDType, 0x04, 0x01, 0x60, 0x00, 0x00, // (type (func))
+			wasm.SectionIDCustom, 0x05, // 5 bytes in this section
+			0x03, 'f', 'o', 'o', 1,
+			wasm.SectionIDCustom, 0x0e, // 14 bytes in this section
+			0x04, 'n', 'a', 'm', 'e',
+			subsectionIDModuleName, 0x07, // 7 bytes in this subsection
+			0x06, // the Module name simple is 6 bytes long
+			's', 'i', 'm', 'p', 'l', 'e',
+			wasm.SectionIDCustom, 0x05, // 5 bytes in this section
+			0x03, 'b', 'a', 'r', 2)
+		m, e := DecodeModule(input, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, true)
+		require.NoError(t, e)
+
+		afterType := wasm.SectionIDType
+		require.Equal(t, []*wasm.CustomSection{
+			{Name: "foo", Data: []byte{1}, After: &afterType},
+			{Name: "bar", Data: []byte{2}, After: &afterType, AfterNameSection: true},
+		}, m.CustomSections)
+		require.Equal(t, input, binaryencoding.EncodeModule(m))
+	})
+
+	t.Run("custom sections between every section", func(t *testing.T) {
+		custom := func(name byte) []byte {
+			return []byte{wasm.SectionIDCustom, 0x02, 0x01, name} // custom section with a one-letter name and no data
+		}
+		var input []byte
+		input = append(input, Magic...)
+		input = append(input, version...)
+		input = append(input, custom('a')...)
+		input = append(input, custom('b')...)
+		input = append(input, wasm.SectionIDType, 0x04, 0x01, 0x60, 0x00, 0x00) // (type (func))
+		input = append(input, custom('c')...)
+		input = append(input, wasm.SectionIDFunction, 0x02, 0x01, 0x00) // one function of type 0
+		input = append(input, custom('d')...)
+		input = append(input, wasm.SectionIDMemory, 0x03, 0x01, 0x00, 0x01) // (memory 1)
+		input = append(input, custom('e')...)
+		input = append(input, custom('f')...)
+		input = append(input, wasm.SectionIDDataCount, 0x01, 0x00) // no data
+		input = append(input, custom('g')...)
+		input = append(input, wasm.SectionIDCode, 0x04, 0x01, 0x02, 0x00, wasm.OpcodeEnd) // one empty body
+		input = append(input, custom('h')...)
+
+		m, e := DecodeModule(input, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, true)
+		require.NoError(t, e)
+
+		afterType, afterFunction, afterMemory := wasm.SectionIDType, wasm.SectionIDFunction, wasm.SectionIDMemory
+		afterDataCount, afterCode := wasm.SectionIDDataCount, wasm.SectionIDCode
+		require.Equal(t, []*wasm.CustomSection{
+			{Name: "a", Data: []byte{}, After: &first},
+			{Name: "b", Data: []byte{}, After: &first},
+			{Name: "c", Data: []byte{}, After: &afterType},
+			{Name: "d", Data: []byte{}, After: &afterFunction},
+			{Name: "e", Data: []byte{}, After: &afterMemory},
+			{Name: "f", Data: []byte{}, After: &afterMemory},
+			{Name: "g", Data: []byte{}, After: &afterDataCount},
+			{Name: "h", Data: []byte{}, After: &afterCode},
+		}, m.CustomSections)
+		require.Equal(t, input, binaryencoding.EncodeModule(m))
+	})
+
 	t.Run("DWARF enabled", func(t *testing.T) {
 		m, err := DecodeModule(dwarftestdata.ZigWasm, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, true, true)
 		require.NoError(t, err)
@@ -217,6 +341,151 @@ func TestDecodeModule(t *testing.T) {
 		require.Nil(t, m.DWARFLines)
 	})
 
+	t.Run("only header", func(t *testing.T) {
+		input := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00} // "\0asm" then version 1
+		m, e := DecodeModule(input, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, false)
+		require.NoError(t, e)
+		require.Equal(t, &wasm.Module{}, m)
+		require.Equal(t, input, binaryencoding.EncodeModule(m))
+	})
+
+	t.Run("unknown section", func(t *testing.T) {
+		input := append(append(Magic, version...),
+			wasm.SectionIDType, 4, 1, 0x60, 0, 0,
+			0x42, 3, 1, 2, 3, // unknown section with 3 bytes
+			wasm.SectionIDCustom, 0x06, // 6 bytes in this section
+			0x04, 'm', 'e', 'm', 'e',
+			1)
+
+		_, e := DecodeModule(input, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, true)
+		require.EqualError(t, e, "section unknown: invalid section id")
+
+		m, e := DecodeModuleWithOptions(input, api.CoreFeaturesV2, wasm.MemoryLimitPages,
+			DecodeOptions{StoreCustomSections: true, StoreUnknownSections: true})
+		require.NoError(t, e)
+		require.Equal(t, map[wasm.SectionID][]byte{0x42: {1, 2, 3}}, m.UnknownSections)
+
+		// Encoding the module emits the unknown section unchanged.
+		require.Equal(t, input, binaryencoding.EncodeModule(m))
+	})
+
+	t.Run("non-minimal section size", func(t *testing.T) {
+		input := append(append(Magic, version...),
+			wasm.SectionIDType, 0x84, 0x80, 0, // 4 bytes in this section, padded to 3 bytes
+			1, 0x60, 0, 0)
+
+		m, e := DecodeModule(input, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, false)
+		require.NoError(t, e)
+		require.Equal(t, 1, len(m.TypeSection))
+
+		_, e = DecodeModuleWithOptions(input, api.CoreFeaturesV2, wasm.MemoryLimitPages, DecodeOptions{Strict: true})
+		require.EqualError(t, e, "get size of section type: non-minimal encoding of 4 in 3 bytes")
+
+		// The same error is returned when decoding from a stream.
+		_, e = DecodeModuleReader(iotest.OneByteReader(bytes.NewReader(input)),
+			api.CoreFeaturesV2, wasm.MemoryLimitPages, DecodeOptions{Strict: true})
+		require.EqualError(t, e, "get size of section type: non-minimal encoding of 4 in 3 bytes")
+	})
+
+	t.Run("raw sections", func(t *testing.T) {
+		input := binaryencoding.EncodeModule(&wasm.Module{
+			TypeSection:     []wasm.FunctionType{{}},
+			FunctionSection: []wasm.Index{0},
+			ExportSection:   []wasm.Export{{Type: wasm.ExternTypeFunc, Name: "f", Index: 0}},
+			CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeEnd}}},
+			CustomSections:  []*wasm.CustomSection{{Name: "meme", Data: []byte{1}}},
+		})
+
+		m, e := DecodeModuleWithOptions(input, api.CoreFeaturesV2, wasm.MemoryLimitPages,
+			DecodeOptions{StoreCustomSections: true, StoreRawSections: true})
+		require.NoError(t, e)
+
+		var ids []wasm.SectionID
+		raw := append(append([]byte{}, Magic...), version...)
+		for _, s := range m.RawSections {
+			ids = append(ids, s.ID)
+			raw = append(append(append(raw, s.ID), leb128.EncodeUint32(uint32(len(s.Data)))...), s.Data...)
+		}
+		require.Equal(t, []wasm.SectionID{
+			wasm.SectionIDType, wasm.SectionIDFunction, wasm.SectionIDExport, wasm.SectionIDCode, wasm.SectionIDCustom,
+		}, ids)
+		// The sections are in order and cover the whole binary.
+		require.Equal(t, input, raw)
+
+		// Each section shares the memory of the binary, after its ID and size.
+		typeSection := m.RawSections[0]
+		require.Same(t, &input[len(Magic)+len(version)+2], &typeSection.Data[0])
+
+		// The sections are not stored unless asked.
+		m, e = DecodeModule(input, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, true)
+		require.NoError(t, e)
+		require.Nil(t, m.RawSections)
+
+		// The sections are the same when decoding from a stream, including custom sections which are otherwise
+		// discarded.
+		m, e = DecodeModuleReader(iotest.OneByteReader(bytes.NewReader(input)),
+			api.CoreFeaturesV2, wasm.MemoryLimitPages, DecodeOptions{StoreRawSections: true})
+		require.NoError(t, e)
+		require.Equal(t, 5, len(m.RawSections))
+		require.Equal(t, typeSection, m.RawSections[0])
+		require.Equal(t, []byte{4, 'm', 'e', 'm', 'e', 1}, m.RawSections[4].Data)
+		require.Nil(t, m.CustomSections)
+	})
+
+	t.Run("max function locals", func(t *testing.T) {
+		input := binaryencoding.EncodeModule(&wasm.Module{
+			TypeSection:     []wasm.FunctionType{{}},
+			FunctionSection: []wasm.Index{0},
+			CodeSection: []wasm.Code{{
+				LocalTypes: []wasm.ValueType{wasm.ValueTypeI32, wasm.ValueTypeI32, wasm.ValueTypeI64, wasm.ValueTypeI64},
+				Body:       []byte{wasm.OpcodeEnd},
+			}},
+		})
+
+		m, e := DecodeModule(input, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, false)
+		require.NoError(t, e)
+		require.Equal(t, 4, len(m.CodeSection[0].LocalTypes))
+
+		_, e = DecodeModuleWithOptions(input, api.CoreFeaturesV2, wasm.MemoryLimitPages, DecodeOptions{MaxFunctionLocals: 3})
+		require.EqualError(t, e, "section code: read 0-th code segment: too many locals: 4")
+	})
+
+	t.Run("lazy function bodies", func(t *testing.T) {
+		input := binaryencoding.EncodeModule(&wasm.Module{
+			TypeSection:     []wasm.FunctionType{{}},
+			FunctionSection: []wasm.Index{0, 0},
+			CodeSection: []wasm.Code{
+				{LocalTypes: []wasm.ValueType{wasm.ValueTypeI32, wasm.ValueTypeI32}, Body: []byte{wasm.OpcodeEnd}},
+				{LocalTypes: []wasm.ValueType{wasm.ValueTypeI64}, Body: []byte{wasm.OpcodeNop, wasm.OpcodeEnd}},
+			},
+			ExportSection: []wasm.Export{{Type: wasm.ExternTypeFunc, Name: "f", Index: 0}},
+		})
+		expected, e := DecodeModule(input, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, false)
+		require.NoError(t, e)
+
+		opts := DecodeOptions{MaxFunctionLocals: 1, LazyFunctionBodies: true}
+		m, e := DecodeModuleWithOptions(input, api.CoreFeaturesV2, wasm.MemoryLimitPages, opts)
+		require.NoError(t, e) // the first body has too many locals, but isn't decoded yet.
+		for i := range m.CodeSection {
+			require.NotNil(t, m.CodeSection[i].DecodeBody)
+			require.Nil(t, m.CodeSection[i].Body)
+		}
+
+		require.NoError(t, m.DecodeFunctionBody(1))
+		require.Equal(t, expected.CodeSection[1], m.CodeSection[1])
+		require.NotNil(t, m.CodeSection[0].DecodeBody) // only the requested body is decoded.
+		require.NoError(t, m.DecodeFunctionBody(1))    // decoding again does nothing.
+
+		require.EqualError(t, m.DecodeFunctionBody(0), `code[0] export["f"]: too many locals: 2`)
+		require.EqualError(t, m.DecodeFunctionBody(2), "code index 2 out of range")
+
+		opts.MaxFunctionLocals = 0
+		m, e = DecodeModuleWithOptions(input, api.CoreFeaturesV2, wasm.MemoryLimitPages, opts)
+		require.NoError(t, e)
+		require.NoError(t, m.Validate(api.CoreFeaturesV2)) // decodes the remaining bodies.
+		require.Equal(t, expected.CodeSection, m.CodeSection)
+	})
+
 	t.Run("data count section disabled", func(t *testing.T) {
 		input := append(append(Magic, version...),
 			wasm.SectionIDDataCount, 1, 0)
@@ -225,6 +494,144 @@ func TestDecodeModule(t *testing.T) {
 	})
 }
 
+func TestDecodeAndValidateModule(t *testing.T) {
+	// i32.add without operands is well-formed, but type-unsound.
+	input := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeI32Add, wasm.OpcodeDrop, wasm.OpcodeEnd}}},
+	})
+	expectedErr := "invalid function[0]: cannot pop the 1st operand for i32.add: i32 missing"
+
+	t.Run("decode only", func(t *testing.T) {
+		m, err := DecodeModule(input, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, false)
+		require.NoError(t, err)
+		require.Equal(t, []byte{wasm.OpcodeI32Add, wasm.OpcodeDrop, wasm.OpcodeEnd}, m.CodeSection[0].Body)
+		require.EqualError(t, m.Validate(api.CoreFeaturesV2), expectedErr)
+	})
+
+	t.Run("decode and validate", func(t *testing.T) {
+		_, err := DecodeAndValidateModule(input, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, false)
+		require.EqualError(t, err, expectedErr)
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		input := binaryencoding.EncodeModule(&wasm.Module{
+			TypeSection:     []wasm.FunctionType{{}},
+			FunctionSection: []wasm.Index{0},
+			CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeEnd}}},
+		})
+		_, err := DecodeAndValidateModule(input, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, false)
+		require.NoError(t, err)
+	})
+}
+
+// TestDecodeAndValidateModule_ImportedFunctions ensures imported functions precede defined ones in the function index
+// space, for both call targets and exports.
+func TestDecodeAndValidateModule_ImportedFunctions(t *testing.T) {
+	module := func(callTarget, exportIndex wasm.Index) []byte {
+		return binaryencoding.EncodeModule(&wasm.Module{
+			TypeSection:     []wasm.FunctionType{{}},
+			ImportSection:   []wasm.Import{{Module: "env", Name: "f", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+			FunctionSection: []wasm.Index{0},
+			CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeCall, byte(callTarget), wasm.OpcodeEnd}}},
+			ExportSection:   []wasm.Export{{Type: wasm.ExternTypeFunc, Name: "g", Index: exportIndex}},
+		})
+	}
+
+	// The defined function calls the import at index 0, and is exported at index 1.
+	m, err := DecodeAndValidateModule(module(0, 1), api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, false)
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), m.ImportFunctionCount)
+
+	// The defined function can call itself at index 1.
+	_, err = DecodeAndValidateModule(module(1, 1), api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, false)
+	require.NoError(t, err)
+
+	_, err = DecodeAndValidateModule(module(2, 1), api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, false)
+	require.EqualError(t, err, "invalid function[0] export[\"g\"]: invalid function index: 2")
+
+	_, err = DecodeAndValidateModule(module(0, 2), api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, false)
+	require.EqualError(t, err, "unknown function for export[\"g\"]")
+}
+
+// TestDecodeAndValidateModule_MutableGlobals ensures importing or exporting a mutable global depends on
+// api.CoreFeatureMutableGlobal, and that such modules round-trip through the encoder.
+func TestDecodeAndValidateModule_MutableGlobals(t *testing.T) {
+	mutableI32 := wasm.GlobalType{ValType: wasm.ValueTypeI32, Mutable: true}
+	tests := []struct {
+		name        string
+		module      *wasm.Module
+		expectedErr string
+	}{
+		{
+			name: "import",
+			module: &wasm.Module{
+				ImportSection: []wasm.Import{{Module: "env", Name: "g", Type: wasm.ExternTypeGlobal, DescGlobal: mutableI32}},
+			},
+			expectedErr: `invalid import["env"."g"] global: feature "mutable-global" is disabled`,
+		},
+		{
+			name: "export",
+			module: &wasm.Module{
+				GlobalSection: []wasm.Global{{
+					Type: mutableI32,
+					Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}},
+				}},
+				ExportSection: []wasm.Export{{Type: wasm.ExternTypeGlobal, Name: "g", Index: 0}},
+			},
+			expectedErr: `invalid export["g"] global[0]: feature "mutable-global" is disabled`,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		input := binaryencoding.EncodeModule(tc.module)
+
+		t.Run(tc.name+" enabled", func(t *testing.T) {
+			m, err := DecodeAndValidateModule(input, api.CoreFeaturesV1, wasm.MemoryLimitPages, false, false, false)
+			require.NoError(t, err)
+			require.Equal(t, input, binaryencoding.EncodeModule(m))
+		})
+
+		t.Run(tc.name+" disabled", func(t *testing.T) {
+			features := api.CoreFeaturesV1.SetEnabled(api.CoreFeatureMutableGlobal, false)
+			_, err := DecodeAndValidateModule(input, features, wasm.MemoryLimitPages, false, false, false)
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestDecodeModule_V128(t *testing.T) {
+	v128 := wasm.ValueTypeV128
+	body := []byte{
+		wasm.OpcodeI32Const, 0,
+		wasm.OpcodeVecPrefix, wasm.OpcodeVecV128Load, 0x04, 0x00, // align=2^4, offset=0
+		wasm.OpcodeLocalSet, 0,
+		wasm.OpcodeLocalGet, 0,
+		wasm.OpcodeEnd,
+	}
+	input := append(append(Magic, version...),
+		wasm.SectionIDType, 0x05, 0x01, 0x60, 0x00, 0x01, v128, // (type (func (result v128)))
+		wasm.SectionIDFunction, 0x02, 0x01, 0x00, // one function of type 0
+		wasm.SectionIDMemory, 0x03, 0x01, 0x00, 0x01, // (memory 1)
+		wasm.SectionIDCode, 0x10, 0x01, 0x0e, // one function of 14 bytes
+		0x01, 0x01, v128, // (local v128)
+	)
+	input = append(input, body...)
+
+	m, err := DecodeModule(input, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, false)
+	require.NoError(t, err)
+	require.Equal(t, []wasm.ValueType{v128}, m.CodeSection[0].LocalTypes)
+	require.Equal(t, body, m.CodeSection[0].Body)
+	require.NoError(t, m.Validate(api.CoreFeaturesV2))
+
+	// Decoding doesn't check features used in function bodies, but validation does.
+	m, err = DecodeModule(input, api.CoreFeaturesV1, wasm.MemoryLimitPages, false, false, false)
+	require.NoError(t, err)
+	require.EqualError(t, m.Validate(api.CoreFeaturesV1), "invalid function[0]: v128.load invalid as feature \"simd\" is disabled")
+}
+
 func TestDecodeModule_Errors(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -246,13 +653,38 @@ func TestDecodeModule_Errors(t *testing.T) {
 			input: append(append(Magic, version...),
 				wasm.SectionIDType, 4, 1, 0x60, 0, 0,
 				wasm.SectionIDFunction, 2, 1, 0,
-				wasm.SectionIDCode, 4, 1,
-				2, 0, wasm.OpcodeEnd,
 				wasm.SectionIDStart, 1, 0,
 				wasm.SectionIDStart, 1, 0,
+				wasm.SectionIDCode, 4, 1,
+				2, 0, wasm.OpcodeEnd,
 			),
 			expectedErr: `multiple start sections are invalid`,
 		},
+		{
+			name: "multiple type sections separated by a custom section",
+			input: append(append(Magic, version...),
+				wasm.SectionIDType, 1, 0,
+				wasm.SectionIDCustom, 2, 1, 'a',
+				wasm.SectionIDType, 1, 0,
+			),
+			expectedErr: `multiple type sections are invalid`,
+		},
+		{
+			name: "section out of order",
+			input: append(append(Magic, version...),
+				wasm.SectionIDFunction, 1, 0,
+				wasm.SectionIDType, 1, 0,
+			),
+			expectedErr: `section type: must precede section function`,
+		},
+		{
+			name: "data count section after code section",
+			input: append(append(Magic, version...),
+				wasm.SectionIDCode, 1, 0,
+				wasm.SectionIDDataCount, 1, 0,
+			),
+			expectedErr: `section data_count: must precede section code`,
+		},
 		{
 			name: "redundant name section",
 			input: append(append(Magic, version...),
@@ -264,14 +696,214 @@ func TestDecodeModule_Errors(t *testing.T) {
 				subsectionIDModuleName, 0x02, 0x01, 'x'),
 			expectedErr: "section custom: redundant custom section name",
 		},
+		{
+			name: "truncated LEB128 at section boundary",
+			input: append(append(Magic, version...),
+				wasm.SectionIDType, 4, 1, 0x60, 0, 0,
+				wasm.SectionIDFunction, 2, 1, 0x80, // type index continues past the section
+				wasm.SectionIDCode, 4, 1,
+				2, 0, wasm.OpcodeEnd,
+			),
+			expectedErr: "section function: get type index: EOF",
+		},
+		{
+			name: "section size exceeds binary",
+			input: append(append(Magic, version...),
+				wasm.SectionIDType, 5, 1, 0x60, 0, 0,
+			),
+			expectedErr: "section type: size 5 exceeds the remaining 4 bytes",
+		},
+		{
+			name: "maximum section size exceeds binary",
+			input: append(append(Magic, version...),
+				wasm.SectionIDType, 0xff, 0xff, 0xff, 0xff, 0x0f, // 4294967295 bytes in this section
+				1, 0x60, 0, 0,
+			),
+			expectedErr: "section type: size 4294967295 exceeds the remaining 4 bytes",
+		},
+		{
+			name: "code section size exceeds binary",
+			input: append(append(Magic, version...),
+				wasm.SectionIDType, 4, 1, 0x60, 0, 0,
+				wasm.SectionIDFunction, 2, 1, 0,
+				wasm.SectionIDCode, 5, 1, 3, 0, wasm.OpcodeEnd, // one byte short
+			),
+			expectedErr: "section code: size 5 exceeds the remaining 4 bytes",
+		},
+		{
+			name: "custom section size exceeds binary",
+			input: append(append(Magic, version...),
+				wasm.SectionIDCustom, 0xf, // 15 bytes in this section
+				0x04, 'm', 'e', 'm',
+			),
+			expectedErr: "section custom: size 15 exceeds the remaining 4 bytes",
+		},
+		{
+			name: "trailing byte",
+			input: append(append(Magic, version...),
+				wasm.SectionIDType, 4, 1, 0x60, 0, 0,
+				0x01, // stray byte
+			),
+			expectedErr: "trailing bytes after the last section at 0xe",
+		},
+		{
+			name: "trailing bytes in a section size",
+			input: append(append(Magic, version...),
+				wasm.SectionIDType, 4, 1, 0x60, 0, 0,
+				0x01, 0x80,
+			),
+			expectedErr: "trailing bytes after the last section at 0xe",
+		},
+		{
+			name: "imported and defined memory",
+			input: append(append(Magic, version...),
+				wasm.SectionIDImport, 0x08, // 8 bytes in this section
+				0x01,                 // 1 import
+				0x01, 'm', 0x01, 'n', // "m"."n"
+				wasm.ExternTypeMemory, 0x00, 0x01, // (memory 1)
+				wasm.SectionIDMemory, 0x03, 0x01, 0x00, 0x01, // 1 memory: (memory 1)
+			),
+			expectedErr: "section memory: at most one memory allowed in module, but imported 1 and defined 1",
+		},
+		{
+			name: "two imported memories",
+			input: append(append(Magic, version...),
+				wasm.SectionIDImport, 0x0f, // 15 bytes in this section
+				0x02,                 // 2 imports
+				0x01, 'm', 0x01, 'n', // "m"."n"
+				wasm.ExternTypeMemory, 0x00, 0x01, // (memory 1)
+				0x01, 'm', 0x01, 'o', // "m"."o"
+				wasm.ExternTypeMemory, 0x00, 0x01, // (memory 1)
+			),
+			expectedErr: "at most one memory allowed in module, but imported 2 and defined 0",
+		},
+		{
+			name: "imported and defined table",
+			input: append(append(Magic, version...),
+				wasm.SectionIDImport, 0x09, // 9 bytes in this section
+				0x01,                 // 1 import
+				0x01, 'm', 0x01, 't', // "m"."t"
+				wasm.ExternTypeTable, wasm.RefTypeFuncref, 0x00, 0x01, // (table 1 funcref)
+				wasm.SectionIDTable, 0x04, 0x01, wasm.RefTypeFuncref, 0x00, 0x01, // 1 table: (table 1 funcref)
+			),
+			expectedErr: "section table: at most one table allowed in module as feature \"reference-types\" is disabled",
+		},
+		{
+			name: "two imported tables",
+			input: append(append(Magic, version...),
+				wasm.SectionIDImport, 0x11, // 17 bytes in this section
+				0x02,                 // 2 imports
+				0x01, 'm', 0x01, 't', // "m"."t"
+				wasm.ExternTypeTable, wasm.RefTypeFuncref, 0x00, 0x01, // (table 1 funcref)
+				0x01, 'm', 0x01, 'u', // "m"."u"
+				wasm.ExternTypeTable, wasm.RefTypeFuncref, 0x00, 0x01, // (table 1 funcref)
+			),
+			expectedErr: "at most one table allowed in module as feature \"reference-types\" is disabled",
+		},
+		{
+			name: "redundant unknown section",
+			input: append(append(Magic, version...),
+				0x42, 1, 0,
+				0x42, 1, 0,
+			),
+			expectedErr: "redundant section with id 0x42",
+		},
 	}
 
 	for _, tt := range tests {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			_, e := DecodeModule(tc.input, api.CoreFeaturesV1, wasm.MemoryLimitPages, false, false, false)
+			opts := DecodeOptions{StoreUnknownSections: true}
+			_, e := DecodeModuleWithOptions(tc.input, api.CoreFeaturesV1, wasm.MemoryLimitPages, opts)
+			require.EqualError(t, e, tc.expectedErr)
+
+			// The same errors are returned when decoding from a stream.
+			_, e = DecodeModuleReader(iotest.OneByteReader(bytes.NewReader(tc.input)), api.CoreFeaturesV1, wasm.MemoryLimitPages, opts)
 			require.EqualError(t, e, tc.expectedErr)
 		})
 	}
 }
+
+// TestDecodeModule_Truncated ensures decoding every prefix of a valid module fails cleanly, the same way from both a
+// byte slice and a stream, instead of panicking or reading past the end of the input.
+func TestDecodeModule_Truncated(t *testing.T) {
+	input := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{wasm.ValueTypeI32}}},
+		FunctionSection: []wasm.Index{0},
+		MemorySection:   &wasm.Memory{Min: 1},
+		ExportSection:   []wasm.Export{{Type: wasm.ExternTypeFunc, Name: "f", Index: 0}},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeDrop, wasm.OpcodeEnd}}},
+		DataSection:     []wasm.DataSegment{{OffsetExpression: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}}, Init: []byte("hello")}},
+		CustomSections:  []*wasm.CustomSection{{Name: "meme", Data: []byte{1, 2, 3}}},
+	})
+
+	for n := len(Magic) + len(version); n < len(input); n++ {
+		truncated := input[:n]
+		_, expectedErr := DecodeModule(truncated, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, true)
+		_, err := DecodeModuleReader(iotest.OneByteReader(bytes.NewReader(truncated)),
+			api.CoreFeaturesV2, wasm.MemoryLimitPages, DecodeOptions{StoreCustomSections: true})
+		if expectedErr == nil {
+			require.NoError(t, err, "truncated to %d bytes", n) // ends at a section boundary
+		} else {
+			require.EqualError(t, err, expectedErr.Error(), "truncated to %d bytes", n)
+		}
+	}
+}
+
+func TestDecodeModuleReader(t *testing.T) {
+	i32 := wasm.ValueTypeI32
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeEnd}}},
+		ExportSection:   []wasm.Export{{Type: wasm.ExternTypeFunc, Name: "id", Index: 0}},
+		NameSection: &wasm.NameSection{
+			ModuleName:    "simple",
+			FunctionNames: wasm.NameMap{{Index: 0, Name: "id"}},
+		},
+		CustomSections: []*wasm.CustomSection{
+			{Name: "small", Data: []byte{1, 2, 3}},
+			{Name: "large", Data: bytes.Repeat([]byte{0xfe}, 1<<20)},
+		},
+	}
+	bin := binaryencoding.EncodeModule(m)
+
+	tests := []struct {
+		name                string
+		storeCustomSections bool
+	}{
+		{name: "discards custom sections", storeCustomSections: false},
+		{name: "stores custom sections", storeCustomSections: true},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			expected, err := DecodeModule(bin, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, tc.storeCustomSections)
+			require.NoError(t, err)
+
+			// OneByteReader proves partial reads are handled, and hides any io.Seeker implementation.
+			r := iotest.OneByteReader(bytes.NewReader(bin))
+			actual, err := DecodeModuleReader(r, api.CoreFeaturesV2, wasm.MemoryLimitPages, DecodeOptions{StoreCustomSections: tc.storeCustomSections})
+			require.NoError(t, err)
+			require.Equal(t, "", wasmdiff.Diff(expected, actual))
+			require.Equal(t, expected, actual)
+			require.Equal(t, tc.storeCustomSections, len(actual.CustomSections) == 2)
+		})
+	}
+
+	t.Run("compiled by toolchains", func(t *testing.T) {
+		for _, bin := range [][]byte{dwarftestdata.TinyGoWasm, dwarftestdata.ZigWasm, dwarftestdata.ZigCCWasm} {
+			opts := DecodeOptions{StoreCustomSections: true, StoreRawSections: true}
+			expected, err := DecodeModuleWithOptions(bin, api.CoreFeaturesV2, wasm.MemoryLimitPages, opts)
+			require.NoError(t, err)
+
+			actual, err := DecodeModuleReader(bytes.NewReader(bin), api.CoreFeaturesV2, wasm.MemoryLimitPages, opts)
+			require.NoError(t, err)
+			require.Equal(t, "", wasmdiff.Diff(expected, actual))
+			require.Equal(t, expected, actual)
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package binary
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// DumpSections writes a table of the sections in the binary to w, one line per section, without decoding their
+// contents. Each line includes the section ID, its name (prefixed by "custom:" for custom sections), the byte offset
+// of the section ID and the size of the section contents.
+//
+// This is intended for inspecting a binary, for example when it fails to decode.
+func DumpSections(w io.Writer, binary []byte) error {
+	r := bytes.NewReader(binary)
+
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(r, buf); err != nil || !bytes.Equal(buf, Magic) {
+		return ErrInvalidMagicNumber
+	}
+	if _, err := io.ReadFull(r, buf); err != nil || !bytes.Equal(buf, version) {
+		return ErrInvalidVersion
+	}
+
+	if _, err := fmt.Fprintf(w, "%-4s %-24s %-10s %s\n", "id", "name", "offset", "size"); err != nil {
+		return err
+	}
+	for {
+		offset := len(binary) - r.Len()
+		sectionID, err := r.ReadByte()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("read section id: %w", err)
+		}
+
+		sectionSize, _, err := leb128.DecodeUint32(r)
+		if err != nil {
+			return fmt.Errorf("get size of section %s: %v", wasm.SectionIDName(sectionID), err)
+		}
+		if uint64(sectionSize) > uint64(r.Len()) {
+			return fmt.Errorf("section %s: size %d exceeds the remaining %d bytes",
+				wasm.SectionIDName(sectionID), sectionSize, r.Len())
+		}
+
+		name := wasm.SectionIDName(sectionID)
+		if sectionID == wasm.SectionIDCustom {
+			sectionStart := len(binary) - r.Len()
+			sr := bytes.NewReader(binary[sectionStart : sectionStart+int(sectionSize)])
+			customName, _, err := decodeUTF8(sr, "custom section name")
+			if err != nil {
+				return fmt.Errorf("section %s: %v", name, err)
+			}
+			name = "custom:" + customName
+		}
+
+		if _, err = fmt.Fprintf(w, "%-4d %-24s %#-10x %d\n", sectionID, name, offset, sectionSize); err != nil {
+			return err
+		}
+		_, _ = r.Seek(int64(sectionSize), io.SeekCurrent)
+	}
+}
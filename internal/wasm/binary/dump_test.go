@@ -0,0 +1,53 @@
+package binary
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestDumpSections(t *testing.T) {
+	bin := append(append(Magic, version...),
+		0x01, 0x04, 0x01, 0x60, 0x00, 0x00, // type section at 0x8: (type (func))
+		0x03, 0x02, 0x01, 0x00, // function section at 0xe: one function of type 0
+		0x0a, 0x04, 0x01, 0x02, 0x00, 0x0b, // code section at 0x12: one empty body
+		0x00, 0x04, 0x03, 'f', 'o', 'o', // custom section "foo" at 0x18
+	)
+
+	var buf bytes.Buffer
+	require.NoError(t, DumpSections(&buf, bin))
+	require.Equal(t, `id   name                     offset     size
+1    type                     0x8        4
+3    function                 0xe        2
+10   code                     0x12       4
+0    custom:foo               0x18       4
+`, buf.String())
+}
+
+func TestDumpSections_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       []byte
+		expectedErr string
+	}{
+		{
+			name:        "wrong magic",
+			input:       []byte("wasm\x01\x00\x00\x00"),
+			expectedErr: "invalid magic number",
+		},
+		{
+			name:        "section size exceeds binary",
+			input:       append(append(Magic, version...), 0x01, 0x05, 0x01),
+			expectedErr: "section type: size 5 exceeds the remaining 1 bytes",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			err := DumpSections(&bytes.Buffer{}, tc.input)
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
@@ -3,40 +3,85 @@ package binary
 import (
 	"bytes"
 	"fmt"
+	"math"
 
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/leb128"
 )
 
+// limitsType is a decoded `limitsType`. min and max are 64-bit so that they can hold the limits of a 64-bit memory.
+type limitsType struct {
+	min uint64
+	max *uint64
+	// shared is only true for the flags 0x02 and 0x03 added by the threads proposal. Callers must reject it unless
+	// experimental.CoreFeaturesThreads is enabled.
+	shared bool
+	// is64 is only true for the flags 0x04 to 0x07 added by the memory64 proposal, which are rejected unless
+	// experimental.CoreFeaturesMemory64 is enabled.
+	is64 bool
+}
+
+// uint32s returns min and max, or an error if either doesn't fit in 32 bits, which is only possible when is64.
+func (l *limitsType) uint32s() (min uint32, max *uint32, err error) {
+	if l.min > math.MaxUint32 {
+		return 0, nil, fmt.Errorf("min %d is larger than %d", l.min, uint32(math.MaxUint32))
+	}
+	min = uint32(l.min)
+	if l.max != nil {
+		if *l.max > math.MaxUint32 {
+			return 0, nil, fmt.Errorf("max %d is larger than %d", *l.max, uint32(math.MaxUint32))
+		}
+		m := uint32(*l.max)
+		max = &m
+	}
+	return
+}
+
 // decodeLimitsType returns the `limitsType` (min, max) decoded with the WebAssembly 1.0 (20191205) Binary Format.
 //
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#limits%E2%91%A6
-func decodeLimitsType(r *bytes.Reader) (min uint32, max *uint32, err error) {
+// See https://github.com/WebAssembly/threads/blob/main/proposals/threads/Overview.md#spec-changes
+// See https://github.com/WebAssembly/memory64/blob/main/proposals/memory64/Overview.md#binary-format
+func decodeLimitsType(r *bytes.Reader, enabledFeatures api.CoreFeatures) (ret limitsType, err error) {
 	var flag byte
 	if flag, err = r.ReadByte(); err != nil {
 		err = fmt.Errorf("read leading byte: %v", err)
 		return
 	}
 
-	switch flag {
-	case 0x00:
-		min, _, err = leb128.DecodeUint32(r)
-		if err != nil {
-			err = fmt.Errorf("read min of limit: %v", err)
-		}
-	case 0x01:
-		min, _, err = leb128.DecodeUint32(r)
-		if err != nil {
-			err = fmt.Errorf("read min of limit: %v", err)
-			return
-		}
-		var m uint32
-		if m, _, err = leb128.DecodeUint32(r); err != nil {
+	if flag > 0x07 || (flag > 0x03 && !enabledFeatures.IsEnabled(experimental.CoreFeaturesMemory64)) {
+		err = fmt.Errorf("%v for limits: %#x not in (0x00, 0x01, 0x02, 0x03)", ErrInvalidByte, flag)
+		return
+	}
+	ret.shared = flag&0x02 != 0
+	ret.is64 = flag&0x04 != 0
+
+	decode := decodeUint32AsUint64
+	if ret.is64 {
+		decode = decodeUint64
+	}
+	if ret.min, err = decode(r); err != nil {
+		err = fmt.Errorf("read min of limit: %v", err)
+		return
+	}
+	if flag&0x01 != 0 {
+		var m uint64
+		if m, err = decode(r); err != nil {
 			err = fmt.Errorf("read max of limit: %v", err)
-		} else {
-			max = &m
+			return
 		}
-	default:
-		err = fmt.Errorf("%v for limits: %#x != 0x00 or 0x01", ErrInvalidByte, flag)
+		ret.max = &m
 	}
 	return
 }
+
+func decodeUint32AsUint64(r *bytes.Reader) (uint64, error) {
+	v, _, err := leb128.DecodeUint32(r)
+	return uint64(v), err
+}
+
+func decodeUint64(r *bytes.Reader) (uint64, error) {
+	v, _, err := leb128.DecodeUint64(r)
+	return v, err
+}
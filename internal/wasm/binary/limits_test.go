@@ -6,6 +6,8 @@ import (
 	"math"
 	"testing"
 
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 )
@@ -56,10 +58,117 @@ func TestLimitsType(t *testing.T) {
 		})
 
 		t.Run(fmt.Sprintf("decode - %s", tc.name), func(t *testing.T) {
-			min, max, err := decodeLimitsType(bytes.NewReader(b))
+			limits, err := decodeLimitsType(bytes.NewReader(b), api.CoreFeaturesV2)
+			require.NoError(t, err)
+			min, max, err := limits.uint32s()
 			require.NoError(t, err)
 			require.Equal(t, min, tc.min)
 			require.Equal(t, max, tc.max)
+			require.False(t, limits.shared)
+			require.False(t, limits.is64)
+		})
+	}
+}
+
+func TestDecodeLimitsType_Shared(t *testing.T) {
+	ten := uint32(10)
+
+	tests := []struct {
+		name  string
+		input []byte
+		min   uint32
+		max   *uint32
+	}{
+		{
+			name:  "shared min 1",
+			input: []byte{0x2, 1},
+			min:   1,
+		},
+		{
+			name:  "shared min 1, max 10",
+			input: []byte{0x3, 1, 10},
+			min:   1,
+			max:   &ten,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			limits, err := decodeLimitsType(bytes.NewReader(tc.input), api.CoreFeaturesV2)
+			require.NoError(t, err)
+			min, max, err := limits.uint32s()
+			require.NoError(t, err)
+			require.Equal(t, tc.min, min)
+			require.Equal(t, tc.max, max)
+			require.True(t, limits.shared)
+		})
+	}
+}
+
+func TestDecodeLimitsType_Memory64(t *testing.T) {
+	ten := uint64(10)
+	large := uint64(math.MaxUint32) + 1
+
+	tests := []struct {
+		name   string
+		input  []byte
+		min    uint64
+		max    *uint64
+		shared bool
+	}{
+		{
+			name:  "min 1",
+			input: []byte{0x4, 1},
+			min:   1,
+		},
+		{
+			name:  "min 1, max 10",
+			input: []byte{0x5, 1, 10},
+			min:   1,
+			max:   &ten,
+		},
+		{
+			name:   "shared min 1, max 10",
+			input:  []byte{0x7, 1, 10},
+			min:    1,
+			max:    &ten,
+			shared: true,
+		},
+		{
+			name:  "min larger than 32-bit",
+			input: []byte{0x4, 0x80, 0x80, 0x80, 0x80, 0x10},
+			min:   large,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			features := api.CoreFeaturesV2 | experimental.CoreFeaturesMemory64
+			limits, err := decodeLimitsType(bytes.NewReader(tc.input), features)
+			require.NoError(t, err)
+			require.Equal(t, limitsType{min: tc.min, max: tc.max, shared: tc.shared, is64: true}, limits)
+
+			_, err = decodeLimitsType(bytes.NewReader(tc.input), api.CoreFeaturesV2)
+			require.EqualError(t, err, fmt.Sprintf("invalid byte for limits: %#x not in (0x00, 0x01, 0x02, 0x03)", tc.input[0]))
 		})
 	}
 }
+
+func TestDecodeLimitsType_Errors(t *testing.T) {
+	features := api.CoreFeaturesV2 | experimental.CoreFeaturesMemory64
+
+	_, err := decodeLimitsType(bytes.NewReader([]byte{0x8, 0}), features)
+	require.EqualError(t, err, "invalid byte for limits: 0x8 not in (0x00, 0x01, 0x02, 0x03)")
+
+	_, err = decodeLimitsType(bytes.NewReader([]byte{0x0, 0x80, 0x80, 0x80, 0x80, 0x10}), features)
+	require.EqualError(t, err, "read min of limit: overflows a 32-bit integer")
+
+	limits, err := decodeLimitsType(bytes.NewReader([]byte{0x4, 0x80, 0x80, 0x80, 0x80, 0x10}), features)
+	require.NoError(t, err)
+	_, _, err = limits.uint32s()
+	require.EqualError(t, err, "min 4294967296 is larger than 4294967295")
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
@@ -163,6 +165,16 @@ func TestMemoryType(t *testing.T) {
 			memoryLimitPages: 512,
 			expected:         []byte{0x1, 0, 0x80, 0x80, 0x4},
 		},
+		{
+			name:     "shared",
+			input:    &wasm.Memory{Min: 1, Cap: 1, Max: 1, IsMaxEncoded: true, IsShared: true},
+			expected: []byte{0x3, 1, 1},
+		},
+		{
+			name:     "64-bit",
+			input:    &wasm.Memory{Min: 1, Cap: 1, Max: 1, IsMaxEncoded: true, Is64: true},
+			expected: []byte{0x5, 1, 1},
+		},
 	}
 
 	for _, tt := range tests {
@@ -182,7 +194,7 @@ func TestMemoryType(t *testing.T) {
 				expectedDecoded.Max = tmax
 			}
 
-			binary, err := decodeMemory(bytes.NewReader(b), newMemorySizer(tmax, false), tmax)
+			binary, err := decodeMemory(bytes.NewReader(b), api.CoreFeaturesV2|experimental.CoreFeaturesThreads|experimental.CoreFeaturesMemory64, newMemorySizer(tmax, false), tmax)
 			require.NoError(t, err)
 			require.Equal(t, binary, expectedDecoded)
 		})
@@ -195,30 +207,58 @@ func TestDecodeMemoryType_Errors(t *testing.T) {
 	tests := []struct {
 		name        string
 		input       []byte
+		features    api.CoreFeatures
 		expectedErr string
 	}{
 		{
 			name:        "max < min",
 			input:       []byte{0x1, 0x80, 0x80, 0x4, 0},
+			features:    api.CoreFeaturesV2,
 			expectedErr: "min 65536 pages (4 Gi) > max 0 pages (0 Ki)",
 		},
 		{
 			name:        "min > limit",
 			input:       []byte{0x0, 0xff, 0xff, 0xff, 0xff, 0xf},
+			features:    api.CoreFeaturesV2,
 			expectedErr: "min 4294967295 pages (3 Ti) over limit of 65536 pages (4 Gi)",
 		},
 		{
 			name:        "max > limit",
 			input:       []byte{0x1, 0, 0xff, 0xff, 0xff, 0xff, 0xf},
+			features:    api.CoreFeaturesV2,
 			expectedErr: "max 4294967295 pages (3 Ti) over limit of 65536 pages (4 Gi)",
 		},
+		{
+			name:        "shared but no threads",
+			input:       []byte{0x3, 1, 1},
+			features:    api.CoreFeaturesV2,
+			expectedErr: "shared memory requested but threads feature not enabled",
+		},
+		{
+			name:        "shared but no max",
+			input:       []byte{0x2, 1},
+			features:    api.CoreFeaturesV2 | experimental.CoreFeaturesThreads,
+			expectedErr: "shared memory requires a maximum size to be specified",
+		},
+		{
+			name:        "64-bit but no memory64",
+			input:       []byte{0x5, 1, 1},
+			features:    api.CoreFeaturesV2,
+			expectedErr: "invalid byte for limits: 0x5 not in (0x00, 0x01, 0x02, 0x03)",
+		},
+		{
+			name:        "64-bit min > limit",
+			input:       []byte{0x4, 0x80, 0x80, 0x80, 0x80, 0x10},
+			features:    api.CoreFeaturesV2 | experimental.CoreFeaturesMemory64,
+			expectedErr: "min 4294967296 is larger than 4294967295",
+		},
 	}
 
 	for _, tt := range tests {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			_, err := decodeMemory(bytes.NewReader(tc.input), newMemorySizer(max, false), max)
+			_, err := decodeMemory(bytes.NewReader(tc.input), tc.features, newMemorySizer(max, false), max)
 			require.EqualError(t, err, tc.expectedErr)
 		})
 	}
@@ -17,6 +17,12 @@ const (
 	// subsectionIDLocalNames contain a map of function indices to a map of local indices to their names, in ascending
 	// order by function and local index
 	subsectionIDLocalNames = uint8(2)
+	// subsectionIDGlobalNames is a map of indices to global names, in ascending order by global index. This is defined
+	// in the extended name section proposal.
+	subsectionIDGlobalNames = uint8(7)
+	// subsectionIDDataNames is a map of indices to data segment names, in ascending order by data index. This is
+	// defined in the extended name section proposal.
+	subsectionIDDataNames = uint8(9)
 )
 
 // decodeNameSection deserializes the data associated with the "name" key in SectionIDCustom according to the
@@ -25,8 +31,13 @@ const (
 // * ModuleName decode from subsection 0
 // * FunctionNames decode from subsection 1
 // * LocalNames decode from subsection 2
+// * GlobalNames decode from subsection 7
+// * DataNames decode from subsection 9
+//
+// Other subsections, such as those added by the extended name section proposal for labels or types, are skipped.
 //
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#binary-namesec
+// See https://github.com/WebAssembly/extended-name-section/blob/main/proposals/extended-name-section/Overview.md
 func decodeNameSection(r *bytes.Reader, limit uint64) (result *wasm.NameSection, err error) {
 	// TODO: add leb128 functions that work on []byte and offset. While using a reader allows us to reuse reader-based
 	// leb128 functions, it is less efficient, causes untestable code and in some cases more complex vs plain []byte.
@@ -65,6 +76,14 @@ func decodeNameSection(r *bytes.Reader, limit uint64) (result *wasm.NameSection,
 			if result.LocalNames, err = decodeLocalNames(r); err != nil {
 				return nil, err
 			}
+		case subsectionIDGlobalNames:
+			if result.GlobalNames, err = decodeNameMap(r, subsectionIDGlobalNames, "global"); err != nil {
+				return nil, err
+			}
+		case subsectionIDDataNames:
+			if result.DataNames, err = decodeNameMap(r, subsectionIDDataNames, "data"); err != nil {
+				return nil, err
+			}
 		default: // Skip other subsections.
 			// Note: Not Seek because it doesn't err when given an offset past EOF. Rather, it leads to undefined state.
 			if _, err = io.CopyN(io.Discard, r, int64(subsectionSize)); err != nil {
@@ -98,6 +117,29 @@ func decodeFunctionNames(r *bytes.Reader) (wasm.NameMap, error) {
 	return result, nil
 }
 
+// decodeNameMap decodes a name map whose indices are of the given kind, e.g. "global".
+func decodeNameMap(r *bytes.Reader, subsectionID uint8, kind string) (wasm.NameMap, error) {
+	count, _, err := leb128.DecodeUint32(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the %s count of subsection[%d]: %w", kind, subsectionID, err)
+	}
+
+	result := make(wasm.NameMap, count)
+	for i := uint32(0); i < count; i++ {
+		index, _, err := leb128.DecodeUint32(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read a %s index in subsection[%d]: %w", kind, subsectionID, err)
+		}
+
+		name, _, err := decodeUTF8(r, "%s[%d] name", kind, index)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = wasm.NameAssoc{Index: index, Name: name}
+	}
+	return result, nil
+}
+
 func decodeLocalNames(r *bytes.Reader) (wasm.IndirectNameMap, error) {
 	functionCount, err := decodeFunctionCount(r, subsectionIDLocalNames)
 	if err != nil {
@@ -59,6 +59,17 @@ func TestDecodeNameSection(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "global and data names",
+			input: &wasm.NameSection{
+				ModuleName:  "simple",
+				GlobalNames: wasm.NameMap{{Index: wasm.Index(0), Name: "__stack_pointer"}},
+				DataNames: wasm.NameMap{
+					{Index: wasm.Index(0), Name: ".rodata"},
+					{Index: wasm.Index(1), Name: ".data"},
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -116,6 +127,21 @@ func TestDecodeNameSection_Errors(t *testing.T) {
 			input:       []byte{subsectionIDLocalNames, ignoredSubsectionSize},
 			expectedErr: "failed to read the function count of subsection[2]: EOF",
 		},
+		{
+			name:        "EOF after global names subsection size",
+			input:       []byte{subsectionIDGlobalNames, ignoredSubsectionSize},
+			expectedErr: "failed to read the global count of subsection[7]: EOF",
+		},
+		{
+			name:        "EOF after data names count",
+			input:       []byte{subsectionIDDataNames, ignoredSubsectionSize, 1},
+			expectedErr: "failed to read a data index in subsection[9]: EOF",
+		},
+		{
+			name:        "EOF after global name index",
+			input:       []byte{subsectionIDGlobalNames, ignoredSubsectionSize, 1, 0},
+			expectedErr: "failed to read global[0] name size: EOF",
+		},
 		{
 			name:        "EOF skipping unknown subsection size",
 			input:       []byte{4, 100},
@@ -64,9 +64,30 @@ func decodeImportSection(
 		}
 		perModule[imp.Module] = append(perModule[imp.Module], imp)
 	}
+
+	if err = requireAtMostOne(wasm.ExternTypeMemory, memoryCount, 0, enabledFeatures); err == nil {
+		err = requireAtMostOne(wasm.ExternTypeTable, tableCount, 0, enabledFeatures)
+	}
 	return
 }
 
+// requireAtMostOne returns an error if the module has more than one memory, or more than one table unless
+// api.CoreFeatureReferenceTypes is enabled. Both the imported and the defined ones count.
+func requireAtMostOne(externType wasm.ExternType, imported, defined uint32, enabledFeatures api.CoreFeatures) error {
+	if imported+defined <= 1 {
+		return nil
+	}
+	switch externType {
+	case wasm.ExternTypeMemory:
+		return fmt.Errorf("at most one memory allowed in module, but imported %d and defined %d", imported, defined)
+	case wasm.ExternTypeTable:
+		if err := enabledFeatures.RequireEnabled(api.CoreFeatureReferenceTypes); err != nil {
+			return fmt.Errorf("at most one table allowed in module as %w", err)
+		}
+	}
+	return nil
+}
+
 func decodeFunctionSection(r *bytes.Reader) ([]uint32, error) {
 	vs, _, err := leb128.DecodeUint32(r)
 	if err != nil {
@@ -82,15 +103,14 @@ func decodeFunctionSection(r *bytes.Reader) ([]uint32, error) {
 	return result, err
 }
 
-func decodeTableSection(r *bytes.Reader, enabledFeatures api.CoreFeatures) ([]wasm.Table, error) {
+// decodeTableSection decodes the tables defined in the module, which already imported importedCount tables.
+func decodeTableSection(r *bytes.Reader, enabledFeatures api.CoreFeatures, importedCount uint32) ([]wasm.Table, error) {
 	vs, _, err := leb128.DecodeUint32(r)
 	if err != nil {
 		return nil, fmt.Errorf("error reading size")
 	}
-	if vs > 1 {
-		if err := enabledFeatures.RequireEnabled(api.CoreFeatureReferenceTypes); err != nil {
-			return nil, fmt.Errorf("at most one table allowed in module as %w", err)
-		}
+	if err = requireAtMostOne(wasm.ExternTypeTable, importedCount, vs, enabledFeatures); err != nil {
+		return nil, err
 	}
 
 	ret := make([]wasm.Table, vs)
@@ -103,23 +123,26 @@ func decodeTableSection(r *bytes.Reader, enabledFeatures api.CoreFeatures) ([]wa
 	return ret, nil
 }
 
+// decodeMemorySection decodes the memory defined in the module, which already imported importedCount memories.
 func decodeMemorySection(
 	r *bytes.Reader,
+	enabledFeatures api.CoreFeatures,
 	memorySizer memorySizer,
 	memoryLimitPages uint32,
+	importedCount uint32,
 ) (*wasm.Memory, error) {
 	vs, _, err := leb128.DecodeUint32(r)
 	if err != nil {
 		return nil, fmt.Errorf("error reading size")
 	}
-	if vs > 1 {
-		return nil, fmt.Errorf("at most one memory allowed in module, but read %d", vs)
+	if err = requireAtMostOne(wasm.ExternTypeMemory, importedCount, vs, enabledFeatures); err != nil {
+		return nil, err
 	} else if vs == 0 {
 		// memory count can be zero.
 		return nil, nil
 	}
 
-	return decodeMemory(r, memorySizer, memoryLimitPages)
+	return decodeMemory(r, enabledFeatures, memorySizer, memoryLimitPages)
 }
 
 func decodeGlobalSection(r *bytes.Reader, enabledFeatures api.CoreFeatures) ([]wasm.Global, error) {
@@ -183,16 +206,20 @@ func decodeElementSection(r *bytes.Reader, enabledFeatures api.CoreFeatures) ([]
 	return result, nil
 }
 
-func decodeCodeSection(r *bytes.Reader) ([]wasm.Code, error) {
+func decodeCodeSection(r *bytes.Reader, maxLocals uint32, lazy bool) ([]wasm.Code, error) {
 	codeSectionStart := uint64(r.Len())
 	vs, _, err := leb128.DecodeUint32(r)
 	if err != nil {
 		return nil, fmt.Errorf("get size of vector: %w", err)
 	}
 
+	decode := decodeCode
+	if lazy {
+		decode = decodeCodeLazily
+	}
 	result := make([]wasm.Code, vs)
 	for i := uint32(0); i < vs; i++ {
-		err = decodeCode(r, codeSectionStart, &result[i])
+		err = decode(r, codeSectionStart, maxLocals, &result[i])
 		if err != nil {
 			return nil, fmt.Errorf("read %d-th code segment: %v", i, err)
 		}
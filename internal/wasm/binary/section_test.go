@@ -45,7 +45,7 @@ func TestTableSection(t *testing.T) {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			tables, err := decodeTableSection(bytes.NewReader(tc.input), api.CoreFeatureReferenceTypes)
+			tables, err := decodeTableSection(bytes.NewReader(tc.input), api.CoreFeatureReferenceTypes, 0)
 			require.NoError(t, err)
 			require.Equal(t, tc.expected, tables)
 		})
@@ -75,7 +75,7 @@ func TestTableSection_Errors(t *testing.T) {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			_, err := decodeTableSection(bytes.NewReader(tc.input), tc.features)
+			_, err := decodeTableSection(bytes.NewReader(tc.input), tc.features, 0)
 			require.EqualError(t, err, tc.expectedErr)
 		})
 	}
@@ -104,7 +104,7 @@ func TestMemorySection(t *testing.T) {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			memories, err := decodeMemorySection(bytes.NewReader(tc.input), newMemorySizer(max, false), max)
+			memories, err := decodeMemorySection(bytes.NewReader(tc.input), api.CoreFeaturesV2, newMemorySizer(max, false), max, 0)
 			require.NoError(t, err)
 			require.Equal(t, tc.expected, memories)
 		})
@@ -126,7 +126,7 @@ func TestMemorySection_Errors(t *testing.T) {
 				0x01,       // (memory 1)
 				0x02, 0x03, // (memory 2 3)
 			},
-			expectedErr: "at most one memory allowed in module, but read 2",
+			expectedErr: "at most one memory allowed in module, but imported 0 and defined 2",
 		},
 	}
 
@@ -134,7 +134,7 @@ func TestMemorySection_Errors(t *testing.T) {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			_, err := decodeMemorySection(bytes.NewReader(tc.input), newMemorySizer(max, false), max)
+			_, err := decodeMemorySection(bytes.NewReader(tc.input), api.CoreFeaturesV2, newMemorySizer(max, false), max, 0)
 			require.EqualError(t, err, tc.expectedErr)
 		})
 	}
@@ -208,6 +208,15 @@ func TestDecodeExportSection_Errors(t *testing.T) {
 			},
 			expectedErr: "export[1] duplicates name \"a\"",
 		},
+		{
+			name: "invalid UTF-8 name",
+			input: []byte{
+				0x01,            // 1 export
+				0x02, 'a', 0xff, // Size of name, name with an invalid UTF-8 byte
+				wasm.ExternTypeFunc, 0x00, // func[0]
+			},
+			expectedErr: "read export: export name is not valid UTF-8",
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,168 @@
+package binary
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/tetratelabs/wazero/internal/leb128"
+)
+
+// sectionSource is what decodeModule reads sections from: either a byte slice or a stream which can't seek.
+type sectionSource interface {
+	io.Reader
+	io.ByteReader
+
+	// section returns the next size bytes, which are the contents of a section. These must not be modified, as they
+	// may share the memory of the binary.
+	//
+	// When nameOnly is true, the section is custom and the result may only include its name, which allows discard to
+	// skip the rest without reading it into memory.
+	section(size uint32, nameOnly bool) ([]byte, error)
+
+	// rest returns a reader of the next limit bytes of the section sr was returned for.
+	rest(sr *bytes.Reader, limit uint32) (*bytes.Reader, error)
+
+	// discard skips the next limit bytes of the section sr was returned for.
+	discard(sr *bytes.Reader, limit uint32) error
+}
+
+// errorSectionSize is returned when a section is larger than the bytes remaining.
+func errorSectionSize(size uint32, remaining int) error {
+	return fmt.Errorf("size %d exceeds the remaining %d bytes", size, remaining)
+}
+
+// bytesSource is a sectionSource which returns sections sharing the underlying binary, so that reading a section
+// doesn't copy it.
+type bytesSource struct {
+	*bytes.Reader
+	binary []byte
+}
+
+func newBytesSource(binary []byte) *bytesSource {
+	return &bytesSource{Reader: bytes.NewReader(binary), binary: binary}
+}
+
+// section implements sectionSource.section
+func (s *bytesSource) section(size uint32, _ bool) ([]byte, error) {
+	if uint64(size) > uint64(s.Len()) {
+		return nil, errorSectionSize(size, s.Len())
+	}
+	start := len(s.binary) - s.Len()
+	end := start + int(size)
+	_, _ = s.Seek(int64(size), io.SeekCurrent)
+	return s.binary[start:end:end], nil // Cap the capacity, so an append can't overwrite the next section.
+}
+
+// rest implements sectionSource.rest
+func (s *bytesSource) rest(sr *bytes.Reader, _ uint32) (*bytes.Reader, error) {
+	return sr, nil
+}
+
+// discard implements sectionSource.discard
+func (s *bytesSource) discard(sr *bytes.Reader, limit uint32) error {
+	_, err := io.CopyN(io.Discard, sr, int64(limit))
+	return err
+}
+
+// streamSource is a sectionSource which reads sections from an io.Reader which doesn't need to implement io.Seeker,
+// such as a network stream. Sections are copied into memory, except the ones skipped with discard.
+type streamSource struct {
+	r io.Reader
+	// pending is the number of bytes of the current section not yet read from r.
+	pending uint32
+	buf     [1]byte
+}
+
+func newStreamSource(r io.Reader) *streamSource {
+	return &streamSource{r: r}
+}
+
+// Read implements io.Reader
+func (s *streamSource) Read(p []byte) (int, error) {
+	return s.r.Read(p)
+}
+
+// ReadByte implements io.ByteReader
+func (s *streamSource) ReadByte() (byte, error) {
+	if _, err := io.ReadFull(s.r, s.buf[:]); err != nil {
+		return 0, err
+	}
+	return s.buf[0], nil
+}
+
+// section implements sectionSource.section
+func (s *streamSource) section(size uint32, nameOnly bool) ([]byte, error) {
+	if !nameOnly {
+		return s.readFull(size)
+	}
+
+	// Read the name size, then the name, without exceeding the section.
+	var header []byte
+	for len(header) < int(size) {
+		b, err := s.ReadByte()
+		if err == io.EOF {
+			return nil, errorSectionSize(size, len(header))
+		} else if err != nil {
+			return nil, err
+		}
+		header = append(header, b)
+		if b&0x80 == 0 || len(header) == 5 { // the last byte of a uint32 in LEB128
+			break
+		}
+	}
+	nameLen, _, err := leb128.LoadUint32(header)
+	if err == nil {
+		n := uint64(size) - uint64(len(header))
+		if uint64(nameLen) < n {
+			n = uint64(nameLen)
+		}
+		var name []byte
+		if name, err = io.ReadAll(io.LimitReader(s.r, int64(n))); err != nil {
+			return nil, err
+		}
+		header = append(header, name...)
+		if uint64(len(name)) < n {
+			return nil, errorSectionSize(size, len(header))
+		}
+	}
+	s.pending = size - uint32(len(header))
+	return header, nil
+}
+
+// rest implements sectionSource.rest
+func (s *streamSource) rest(_ *bytes.Reader, limit uint32) (*bytes.Reader, error) {
+	if limit != s.pending {
+		return nil, fmt.Errorf("expected %d remaining bytes, but %d were requested", s.pending, limit)
+	}
+	s.pending = 0
+	buf, err := s.readFull(limit)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(buf), nil
+}
+
+// discard implements sectionSource.discard
+func (s *streamSource) discard(_ *bytes.Reader, limit uint32) error {
+	if limit != s.pending {
+		return fmt.Errorf("expected %d remaining bytes, but %d were requested", s.pending, limit)
+	}
+	s.pending = 0
+	// Note: Not Seek, as the reader may not implement io.Seeker.
+	_, err := io.CopyN(io.Discard, s.r, int64(limit))
+	return err
+}
+
+// readFull reads the next size bytes, or fails like bytesSource.section when fewer remain.
+//
+// Note: This doesn't allocate size bytes up front, as the size may be corrupt or malicious.
+func (s *streamSource) readFull(size uint32) ([]byte, error) {
+	buf, err := io.ReadAll(io.LimitReader(s.r, int64(size)))
+	if err != nil {
+		return nil, err
+	} else if len(buf) < int(size) {
+		return nil, errorSectionSize(size, len(buf))
+	}
+	return buf, nil
+}
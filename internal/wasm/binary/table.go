@@ -23,10 +23,19 @@ func decodeTable(r *bytes.Reader, enabledFeatures api.CoreFeatures, ret *wasm.Ta
 		}
 	}
 
-	ret.Min, ret.Max, err = decodeLimitsType(r)
+	limits, err := decodeLimitsType(r, enabledFeatures)
 	if err != nil {
 		return fmt.Errorf("read limits: %v", err)
 	}
+	if limits.shared {
+		return fmt.Errorf("tables cannot be marked as shared")
+	}
+	if limits.is64 {
+		return fmt.Errorf("tables cannot have 64-bit limits")
+	}
+	if ret.Min, ret.Max, err = limits.uint32s(); err != nil {
+		return fmt.Errorf("read limits: %v", err)
+	}
 	if ret.Min > wasm.MaximumFunctionIndex {
 		return fmt.Errorf("table min must be at most %d", wasm.MaximumFunctionIndex)
 	}
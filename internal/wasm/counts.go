@@ -45,6 +45,11 @@ func (m *Module) SectionElementCount(sectionID SectionID) uint32 { // element as
 		return uint32(len(m.CodeSection))
 	case SectionIDData:
 		return uint32(len(m.DataSection))
+	case SectionIDDataCount:
+		if m.DataCountSection != nil {
+			return 1
+		}
+		return 0
 	default:
 		panic(fmt.Errorf("BUG: unknown section: %d", sectionID))
 	}
@@ -8,7 +8,7 @@ import (
 
 func TestModule_SectionElementCount(t *testing.T) {
 	i32, f32 := ValueTypeI32, ValueTypeF32
-	zero := uint32(0)
+	zero, one := uint32(0), uint32(1)
 	empty := ConstantExpression{Opcode: OpcodeI32Const, Data: const0}
 
 	tests := []struct {
@@ -81,6 +81,15 @@ func TestModule_SectionElementCount(t *testing.T) {
 			},
 			expected: map[string]uint32{"data": 1, "memory": 1},
 		},
+		{
+			name: "MemorySection, DataCountSection and DataSection",
+			input: &Module{
+				MemorySection:    &Memory{Min: 1},
+				DataCountSection: &one,
+				DataSection:      []DataSegment{{OffsetExpression: empty}},
+			},
+			expected: map[string]uint32{"data": 1, "data_count": 1, "memory": 1},
+		},
 		{
 			name: "TableSection and ElementSection",
 			input: &Module{
@@ -104,7 +113,7 @@ func TestModule_SectionElementCount(t *testing.T) {
 
 		t.Run(tc.name, func(t *testing.T) {
 			actual := map[string]uint32{}
-			for i := SectionID(0); i <= SectionIDData; i++ {
+			for i := SectionID(0); i <= SectionIDDataCount; i++ {
 				if size := tc.input.SectionElementCount(i); size > 0 {
 					actual[SectionIDName(i)] = size
 				}
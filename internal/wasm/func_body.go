@@ -0,0 +1,128 @@
+package wasm
+
+import (
+	"fmt"
+
+	"github.com/tetratelabs/wazero/internal/leb128"
+)
+
+// ReplaceFunctionBody replaces the body of the function defined in this module at funcIdx, in the function index
+// space, with body. localTypes are the locals declared after the parameters, replacing the existing ones.
+//
+// The type of the function is unchanged, so TypeSection and FunctionSection remain consistent. However, body isn't
+// validated, so call Validate before compiling the module.
+//
+// Note: Local names in NameSection aren't updated, so they may no longer match the locals.
+func (m *Module) ReplaceFunctionBody(funcIdx Index, localTypes []ValueType, body []byte) error {
+	code, err := m.definedCode(funcIdx)
+	if err != nil {
+		return err
+	}
+	if err = requireEnd(body); err != nil {
+		return err
+	}
+	*code = Code{LocalTypes: localTypes, Body: body}
+	return nil
+}
+
+// PrependToFunctionBody inserts instructions at the beginning of the body of the function defined in this module at
+// funcIdx, in the function index space, for example to instrument it with a counter. localTypes are declared after
+// the existing locals, so the original body is unaffected, and instructions can use them at the local index of the
+// parameters and existing locals plus the position in localTypes.
+//
+// instructions must leave the stack as it was, and must not end with OpcodeEnd, as that would end the function.
+// Like ReplaceFunctionBody, this doesn't validate the result.
+//
+// When localTypes is empty, the decoded LocalEntries are kept, and BodyOffsetInCodeSection is moved back by the length
+// of instructions, so that DWARF line information of the original instructions remains valid. Otherwise, LocalEntries
+// are dropped as they no longer match the locals.
+func (m *Module) PrependToFunctionBody(funcIdx Index, localTypes []ValueType, instructions []byte) error {
+	code, err := m.definedCode(funcIdx)
+	if err != nil {
+		return err
+	}
+	if err = requireEnd(code.Body); err != nil {
+		return err
+	}
+	body := make([]byte, 0, len(instructions)+len(code.Body))
+	body = append(append(body, instructions...), code.Body...)
+	locals, entries := code.LocalTypes, code.LocalEntries
+	if len(localTypes) > 0 {
+		locals = make([]ValueType, 0, len(code.LocalTypes)+len(localTypes))
+		locals = append(append(locals, code.LocalTypes...), localTypes...)
+		entries = nil // no longer match the locals.
+	}
+	offset := code.BodyOffsetInCodeSection
+	if n := uint64(len(instructions)); offset >= n { // Otherwise, the offsets are stale, like after RemapFunctionIndexes.
+		offset -= n
+	}
+	*code = Code{LocalTypes: locals, LocalEntries: entries, Body: body, BodyOffsetInCodeSection: offset}
+	return nil
+}
+
+// InjectEntryCall imports a function of type (i32) -> () as moduleName.name, then prepends a call to it to the body of
+// each function defined in this module, passing the index of the calling function. For example, the import can be a
+// host function which counts calls per function, to profile a guest without changing its source.
+//
+// The import is the last imported function, so defined functions move up by one in the function index space. All
+// references to them are updated with RemapFunctionIndexes, and the index passed to the import is the updated one.
+//
+// Note: Like RemoveUnusedFunctions, this must be called before the module is compiled.
+func (m *Module) InjectEntryCall(moduleName, name string) error {
+	importCount := m.ImportFunctionCount
+	if uint32(len(m.CodeSection)) != uint32(len(m.FunctionSection)) {
+		return fmt.Errorf("function and code section have inconsistent lengths: %d != %d",
+			len(m.FunctionSection), len(m.CodeSection))
+	}
+
+	mapping := make(map[Index]Index, len(m.CodeSection))
+	for i := range m.CodeSection {
+		funcIdx := importCount + Index(i)
+		mapping[funcIdx] = funcIdx + 1
+	}
+	if err := m.RemapFunctionIndexes(mapping); err != nil {
+		return err
+	}
+
+	typeIdx := m.addType(&FunctionType{Params: []ValueType{ValueTypeI32}})
+	m.addImport(Import{Type: ExternTypeFunc, Module: moduleName, Name: name, DescFunc: typeIdx})
+	m.buildImportPerModule()
+
+	callEntry := append([]byte{OpcodeCall}, leb128.EncodeUint32(importCount)...)
+	for i := range m.CodeSection {
+		if m.CodeSection[i].GoFunc != nil {
+			continue
+		}
+		funcIdx := importCount + 1 + Index(i)
+		instructions := append([]byte{OpcodeI32Const}, leb128.EncodeInt32(int32(funcIdx))...)
+		if err := m.PrependToFunctionBody(funcIdx, nil, append(instructions, callEntry...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// definedCode returns the code of the function defined in this module at funcIdx, in the function index space, or an
+// error if it is imported, out of range or implemented in Go.
+func (m *Module) definedCode(funcIdx Index) (*Code, error) {
+	if funcIdx < m.ImportFunctionCount {
+		return nil, fmt.Errorf("function[%d] is imported", funcIdx)
+	}
+	codeIdx := funcIdx - m.ImportFunctionCount
+	if codeIdx >= uint32(len(m.CodeSection)) || codeIdx >= uint32(len(m.FunctionSection)) {
+		return nil, fmt.Errorf("function index out of range: %d", funcIdx)
+	}
+	code := &m.CodeSection[codeIdx]
+	if code.GoFunc != nil {
+		return nil, fmt.Errorf("function[%d] is implemented in Go", funcIdx)
+	}
+	return code, nil
+}
+
+// requireEnd returns an error unless body ends with OpcodeEnd.
+func requireEnd(body []byte) error {
+	if len(body) == 0 || body[len(body)-1] != OpcodeEnd {
+		return fmt.Errorf("body must end with %s", OpcodeEndName)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package wasm
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestModule_ReplaceFunctionBody(t *testing.T) {
+	m := &Module{
+		TypeSection:         []FunctionType{v_v, v_i32},
+		ImportSection:       []Import{{Type: ExternTypeFunc, Module: "env", Name: "f", DescFunc: 0}},
+		ImportFunctionCount: 1,
+		FunctionSection:     []Index{1},
+		CodeSection: []Code{{
+			LocalTypes:              []ValueType{ValueTypeI64, ValueTypeI64},
+			LocalEntries:            []LocalEntry{{Count: 1, Type: ValueTypeI64}, {Count: 1, Type: ValueTypeI64}},
+			Body:                    []byte{OpcodeI32Const, 1, OpcodeEnd},
+			BodyOffsetInCodeSection: 10,
+		}},
+	}
+
+	body := []byte{OpcodeLocalGet, 0, OpcodeEnd}
+	err := m.ReplaceFunctionBody(1, []ValueType{ValueTypeI32}, body)
+	require.NoError(t, err)
+	require.Equal(t, []Code{{LocalTypes: []ValueType{ValueTypeI32}, Body: body}}, m.CodeSection)
+	require.Equal(t, []Index{1}, m.FunctionSection)
+	require.NoError(t, m.Validate(api.CoreFeaturesV2))
+
+	err = m.ReplaceFunctionBody(0, nil, body)
+	require.EqualError(t, err, "function[0] is imported")
+
+	err = m.ReplaceFunctionBody(2, nil, body)
+	require.EqualError(t, err, "function index out of range: 2")
+
+	err = m.ReplaceFunctionBody(1, nil, []byte{OpcodeNop})
+	require.EqualError(t, err, "body must end with end")
+}
+
+func TestModule_PrependToFunctionBody(t *testing.T) {
+	m := &Module{
+		TypeSection:     []FunctionType{i32_i32},
+		FunctionSection: []Index{0},
+		CodeSection: []Code{{
+			LocalTypes: []ValueType{ValueTypeI64},
+			Body:       []byte{OpcodeLocalGet, 0, OpcodeEnd},
+		}},
+		GlobalSection: []Global{{
+			Type: GlobalType{ValType: ValueTypeI32, Mutable: true},
+			Init: ConstantExpression{Opcode: OpcodeI32Const, Data: const0},
+		}},
+	}
+
+	// Count calls in global 0, using a new local at index 2: after the parameter and the existing local.
+	counter := []byte{
+		OpcodeGlobalGet, 0, OpcodeI32Const, 1, OpcodeI32Add, OpcodeLocalTee, 2,
+		OpcodeGlobalSet, 0,
+	}
+	err := m.PrependToFunctionBody(0, []ValueType{ValueTypeI32}, counter)
+	require.NoError(t, err)
+	require.Equal(t, []Code{{
+		LocalTypes: []ValueType{ValueTypeI64, ValueTypeI32},
+		Body:       append(append([]byte{}, counter...), OpcodeLocalGet, 0, OpcodeEnd),
+	}}, m.CodeSection)
+	require.NoError(t, m.Validate(api.CoreFeaturesV2))
+
+	t.Run("decoded", func(t *testing.T) {
+		m := &Module{
+			TypeSection:     []FunctionType{v_v},
+			FunctionSection: []Index{0},
+			CodeSection: []Code{{
+				LocalTypes:              []ValueType{ValueTypeI64, ValueTypeI64},
+				LocalEntries:            []LocalEntry{{Count: 1, Type: ValueTypeI64}, {Count: 1, Type: ValueTypeI64}},
+				Body:                    []byte{OpcodeEnd},
+				BodyOffsetInCodeSection: 10,
+			}},
+		}
+
+		// Without new locals, the encoding of locals and the offsets of the original instructions are kept.
+		err := m.PrependToFunctionBody(0, nil, []byte{OpcodeNop, OpcodeNop})
+		require.NoError(t, err)
+		require.Equal(t, []Code{{
+			LocalTypes:              []ValueType{ValueTypeI64, ValueTypeI64},
+			LocalEntries:            []LocalEntry{{Count: 1, Type: ValueTypeI64}, {Count: 1, Type: ValueTypeI64}},
+			Body:                    []byte{OpcodeNop, OpcodeNop, OpcodeEnd},
+			BodyOffsetInCodeSection: 8,
+		}}, m.CodeSection)
+
+		// New locals don't match the decoded entries anymore.
+		err = m.PrependToFunctionBody(0, []ValueType{ValueTypeI32}, []byte{OpcodeNop})
+		require.NoError(t, err)
+		require.Equal(t, []Code{{
+			LocalTypes:              []ValueType{ValueTypeI64, ValueTypeI64, ValueTypeI32},
+			Body:                    []byte{OpcodeNop, OpcodeNop, OpcodeNop, OpcodeEnd},
+			BodyOffsetInCodeSection: 7,
+		}}, m.CodeSection)
+		require.NoError(t, m.Validate(api.CoreFeaturesV2))
+	})
+
+	t.Run("go function", func(t *testing.T) {
+		m := &Module{
+			TypeSection:     []FunctionType{v_v},
+			FunctionSection: []Index{0},
+			CodeSection:     []Code{MustParseGoReflectFuncCode(func() {})},
+		}
+		err := m.PrependToFunctionBody(0, nil, []byte{OpcodeNop})
+		require.EqualError(t, err, "function[0] is implemented in Go")
+	})
+}
+
+func TestModule_InjectEntryCall(t *testing.T) {
+	m := &Module{
+		TypeSection: []FunctionType{v_v, i32_i32},
+		ImportSection: []Import{
+			{Type: ExternTypeFunc, Module: "env", Name: "f", DescFunc: 0},
+			{Type: ExternTypeMemory, Module: "env", Name: "memory", DescMem: &Memory{}},
+		},
+		ImportFunctionCount: 1,
+		ImportMemoryCount:   1,
+		FunctionSection:     []Index{0, 1},
+		CodeSection: []Code{
+			{Body: []byte{OpcodeI32Const, 1, OpcodeCall, 2, OpcodeDrop, OpcodeCall, 0, OpcodeEnd}},
+			{Body: []byte{OpcodeLocalGet, 0, OpcodeEnd}},
+		},
+		ExportSection: []Export{{Type: ExternTypeFunc, Name: "run", Index: 1}},
+	}
+	m.Exports = map[string]*Export{"run": &m.ExportSection[0]}
+
+	err := m.InjectEntryCall("profiler", "enter")
+	require.NoError(t, err)
+
+	require.Equal(t, 3, len(m.TypeSection))
+	require.True(t, m.TypeSection[2].EqualsSignature(i32_v.Params, i32_v.Results))
+	require.Equal(t, uint32(2), m.ImportFunctionCount)
+	require.Equal(t, Import{Type: ExternTypeFunc, Module: "profiler", Name: "enter", DescFunc: 2, IndexPerType: 1},
+		m.ImportSection[2])
+	require.Equal(t, []*Import{&m.ImportSection[2]}, m.ImportPerModule["profiler"])
+	require.Equal(t, []Code{
+		{Body: []byte{
+			OpcodeI32Const, 2, OpcodeCall, 1, // injected: the import is func[1] and this is now func[2]
+			OpcodeI32Const, 1, OpcodeCall, 3, OpcodeDrop, OpcodeCall, 0, OpcodeEnd,
+		}},
+		{Body: []byte{
+			OpcodeI32Const, 3, OpcodeCall, 1, // injected
+			OpcodeLocalGet, 0, OpcodeEnd,
+		}},
+	}, m.CodeSection)
+	require.Equal(t, Index(2), m.Exports["run"].Index)
+	require.NoError(t, m.Validate(api.CoreFeaturesV2))
+}
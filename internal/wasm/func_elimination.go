@@ -0,0 +1,279 @@
+package wasm
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/tetratelabs/wazero/internal/leb128"
+)
+
+// RemoveUnusedFunctions removes functions defined in this module which are unreachable, then removes any types which
+// are no longer used. Remaining function and type indexes are compacted.
+//
+// A function is reachable when it is exported, the start function, in an element segment or referenced by a global
+// initializer, or when it is the target of OpcodeCall or OpcodeRefFunc in the body of a reachable function. Imported
+// functions are never removed, as doing so would change what the module requires to instantiate.
+//
+// Note: This must be called before the module is compiled, as derived data such as FunctionDefinitionSection is not
+// updated.
+func (m *Module) RemoveUnusedFunctions() error {
+	importCount := m.ImportFunctionCount
+	total := importCount + uint32(len(m.FunctionSection))
+	if uint32(len(m.CodeSection)) != uint32(len(m.FunctionSection)) {
+		return fmt.Errorf("function and code section have inconsistent lengths: %d != %d",
+			len(m.FunctionSection), len(m.CodeSection))
+	}
+
+	reachable, err := m.reachableFunctions(total)
+	if err != nil {
+		return err
+	}
+
+	mapping := make(map[Index]Index, total)
+	functions := make([]Index, 0, len(m.FunctionSection))
+	codes := make([]Code, 0, len(m.CodeSection))
+	for i := range m.FunctionSection {
+		funcIdx := importCount + Index(i)
+		if !reachable[funcIdx] {
+			continue
+		}
+		mapping[funcIdx] = importCount + Index(len(functions))
+		functions = append(functions, m.FunctionSection[i])
+		codes = append(codes, m.CodeSection[i])
+	}
+	m.FunctionSection, m.CodeSection = functions, codes
+
+	if n := m.NameSection; n != nil {
+		isRemoved := func(funcIdx Index) bool { return funcIdx >= importCount && !reachable[funcIdx] }
+		n.FunctionNames = filterNameMap(n.FunctionNames, isRemoved)
+		n.LocalNames = filterIndirectNameMap(n.LocalNames, isRemoved)
+		n.ResultNames = filterIndirectNameMap(n.ResultNames, isRemoved)
+	}
+
+	if err = m.RemapFunctionIndexes(mapping); err != nil {
+		return err
+	}
+	return m.removeUnusedTypes()
+}
+
+// reachableFunctions returns whether each function index is reachable, as documented on RemoveUnusedFunctions.
+func (m *Module) reachableFunctions(total uint32) ([]bool, error) {
+	reachable := make([]bool, total)
+	var pending []Index
+	mark := func(funcIdx Index) error {
+		if funcIdx >= total {
+			return fmt.Errorf("function index out of range: %d", funcIdx)
+		}
+		if !reachable[funcIdx] {
+			reachable[funcIdx] = true
+			pending = append(pending, funcIdx)
+		}
+		return nil
+	}
+
+	for i := Index(0); i < m.ImportFunctionCount; i++ {
+		if err := mark(i); err != nil {
+			return nil, err
+		}
+	}
+	for i := range m.ExportSection {
+		if exp := &m.ExportSection[i]; exp.Type == ExternTypeFunc {
+			if err := mark(exp.Index); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if m.StartSection != nil {
+		if err := mark(*m.StartSection); err != nil {
+			return nil, err
+		}
+	}
+	for i := range m.ElementSection {
+		for _, funcIdx := range m.ElementSection[i].Init {
+			if funcIdx&(ElementInitNullReference|ElementInitImportedGlobalFunctionReference) != 0 {
+				continue // Not a function index.
+			}
+			if err := mark(funcIdx); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for i := range m.GlobalSection {
+		if expr := &m.GlobalSection[i].Init; expr.Opcode == OpcodeRefFunc {
+			funcIdx, _, err := leb128.LoadUint32(expr.Data)
+			if err != nil {
+				return nil, fmt.Errorf("global[%d]: read function index: %w", i, err)
+			}
+			if err = mark(funcIdx); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for len(pending) > 0 {
+		funcIdx := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if funcIdx < m.ImportFunctionCount {
+			continue // Imported functions have no body.
+		}
+		codeIdx := funcIdx - m.ImportFunctionCount
+		code := &m.CodeSection[codeIdx]
+		if code.GoFunc != nil {
+			continue
+		}
+		immediates, err := indexImmediates(code.Body)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", m.funcDesc(SectionIDCode, codeIdx), err)
+		}
+		for _, imm := range immediates {
+			if imm.kind != indexKindFunction {
+				continue
+			}
+			if err = mark(imm.index); err != nil {
+				return nil, fmt.Errorf("%s: %w", m.funcDesc(SectionIDCode, codeIdx), err)
+			}
+		}
+	}
+	return reachable, nil
+}
+
+// CallEdges are the functions statically referenced by the body of a function.
+type CallEdges struct {
+	// Calls are the targets of OpcodeCall, in ascending order without duplicates.
+	Calls []Index
+	// RefFuncs are the operands of OpcodeRefFunc, in ascending order without duplicates.
+	RefFuncs []Index
+}
+
+// CallEdges returns the functions referenced by each function in this module, indexed by function index. This is a
+// read-only walk of CodeSection, for example to analyze the call graph.
+//
+// Note: Entries for imported functions and those implemented in Go are empty, as they have no body. Indirect calls
+// are not included, as their targets are only known at runtime.
+func (m *Module) CallEdges() ([]CallEdges, error) {
+	importCount := m.ImportFunctionCount
+	ret := make([]CallEdges, importCount+uint32(len(m.CodeSection)))
+	for i := range m.CodeSection {
+		code := &m.CodeSection[i]
+		if code.GoFunc != nil {
+			continue
+		}
+		immediates, err := indexImmediates(code.Body)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", m.funcDesc(SectionIDCode, Index(i)), err)
+		}
+		edges := &ret[importCount+Index(i)]
+		for _, imm := range immediates {
+			if imm.kind != indexKindFunction {
+				continue
+			}
+			// The index immediately follows the opcode, so the byte before it is the opcode.
+			if code.Body[imm.pc-1] == OpcodeCall {
+				edges.Calls = append(edges.Calls, imm.index)
+			} else {
+				edges.RefFuncs = append(edges.RefFuncs, imm.index)
+			}
+		}
+		edges.Calls = sortedUniqueIndexes(edges.Calls)
+		edges.RefFuncs = sortedUniqueIndexes(edges.RefFuncs)
+	}
+	return ret, nil
+}
+
+// sortedUniqueIndexes sorts indexes in place and returns it without duplicates.
+func sortedUniqueIndexes(indexes []Index) []Index {
+	if len(indexes) < 2 {
+		return indexes
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+	ret := indexes[:1]
+	for _, idx := range indexes[1:] {
+		if idx != ret[len(ret)-1] {
+			ret = append(ret, idx)
+		}
+	}
+	return ret
+}
+
+// removeUnusedTypes removes types not referenced by an imported or defined function, OpcodeCallIndirect or block
+// type, and compacts the remaining type indexes.
+func (m *Module) removeUnusedTypes() error {
+	used := make([]bool, len(m.TypeSection))
+	mark := func(typeIdx Index) error {
+		if typeIdx >= uint32(len(used)) {
+			return fmt.Errorf("type index out of range: %d", typeIdx)
+		}
+		used[typeIdx] = true
+		return nil
+	}
+
+	for i := range m.ImportSection {
+		if imp := &m.ImportSection[i]; imp.Type == ExternTypeFunc {
+			if err := mark(imp.DescFunc); err != nil {
+				return err
+			}
+		}
+	}
+	for _, typeIdx := range m.FunctionSection {
+		if err := mark(typeIdx); err != nil {
+			return err
+		}
+	}
+	bodyImmediates := make([][]indexImmediate, len(m.CodeSection))
+	for i := range m.CodeSection {
+		code := &m.CodeSection[i]
+		if code.GoFunc != nil {
+			continue
+		}
+		immediates, err := indexImmediates(code.Body)
+		if err != nil {
+			return fmt.Errorf("%s: %w", m.funcDesc(SectionIDCode, Index(i)), err)
+		}
+		for _, imm := range immediates {
+			if imm.kind != indexKindType {
+				continue
+			}
+			if err = mark(imm.index); err != nil {
+				return fmt.Errorf("%s: %w", m.funcDesc(SectionIDCode, Index(i)), err)
+			}
+		}
+		bodyImmediates[i] = immediates
+	}
+
+	mapping := make([]Index, len(m.TypeSection))
+	types := make([]FunctionType, 0, len(m.TypeSection))
+	for i := range m.TypeSection {
+		if used[i] {
+			mapping[i] = Index(len(types))
+			types = append(types, m.TypeSection[i])
+		}
+	}
+	if len(types) == len(m.TypeSection) {
+		return nil // Nothing to remove.
+	}
+	m.TypeSection = types
+	m.remapTypeIndexes(mapping, bodyImmediates)
+	return nil
+}
+
+// filterNameMap returns the entries of m whose index is not removed.
+func filterNameMap(m NameMap, isRemoved func(Index) bool) NameMap {
+	ret := m[:0]
+	for _, na := range m {
+		if !isRemoved(na.Index) {
+			ret = append(ret, na)
+		}
+	}
+	return ret
+}
+
+// filterIndirectNameMap is like filterNameMap, except for an IndirectNameMap.
+func filterIndirectNameMap(m IndirectNameMap, isRemoved func(Index) bool) IndirectNameMap {
+	ret := m[:0]
+	for _, na := range m {
+		if !isRemoved(na.Index) {
+			ret = append(ret, na)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,126 @@
+package wasm
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestModule_RemoveUnusedFunctions(t *testing.T) {
+	m := &Module{
+		TypeSection: []FunctionType{v_v, i32_v, v_i32},
+		ImportSection: []Import{
+			{Type: ExternTypeFunc, Module: "env", Name: "log", DescFunc: 1},
+		},
+		ImportFunctionCount: 1,
+		FunctionSection:     []Index{0, 2, 0, 0},
+		CodeSection: []Code{
+			// func[1] run: calls the helper and the import
+			{Body: []byte{OpcodeCall, 4, OpcodeI32Const, 0, OpcodeCall, 0, OpcodeEnd}},
+			// func[2] unused: the only user of type v_i32
+			{Body: []byte{OpcodeCall, 3, OpcodeI32Const, 1, OpcodeEnd}},
+			// func[3] only called by the unused function
+			{Body: []byte{OpcodeEnd}},
+			// func[4] helper
+			{Body: []byte{OpcodeBlock, 0x00 /* type v_v */, OpcodeEnd, OpcodeEnd}},
+		},
+		ExportSection: []Export{{Type: ExternTypeFunc, Name: "run", Index: 1}},
+		NameSection: &NameSection{
+			FunctionNames: NameMap{
+				{Index: 0, Name: "log"},
+				{Index: 1, Name: "run"},
+				{Index: 2, Name: "unused"},
+				{Index: 3, Name: "unused_callee"},
+				{Index: 4, Name: "helper"},
+			},
+		},
+	}
+	m.Exports = map[string]*Export{"run": &m.ExportSection[0]}
+
+	err := m.RemoveUnusedFunctions()
+	require.NoError(t, err)
+
+	require.Equal(t, []FunctionType{v_v, i32_v}, m.TypeSection)
+	require.Equal(t, []Index{0, 0}, m.FunctionSection)
+	require.Equal(t, []Code{
+		{Body: []byte{OpcodeCall, 2, OpcodeI32Const, 0, OpcodeCall, 0, OpcodeEnd}},
+		{Body: []byte{OpcodeBlock, 0x00, OpcodeEnd, OpcodeEnd}},
+	}, m.CodeSection)
+	require.Equal(t, Index(1), m.ImportSection[0].DescFunc)
+	require.Equal(t, Index(1), m.Exports["run"].Index)
+	require.Equal(t, NameMap{
+		{Index: 0, Name: "log"},
+		{Index: 1, Name: "run"},
+		{Index: 2, Name: "helper"},
+	}, m.NameSection.FunctionNames)
+
+	require.NoError(t, m.Validate(api.CoreFeaturesV2))
+}
+
+func TestModule_RemoveUnusedFunctions_Roots(t *testing.T) {
+	start := Index(0)
+	m := &Module{
+		TypeSection:     []FunctionType{v_v},
+		FunctionSection: []Index{0, 0, 0, 0},
+		CodeSection: []Code{
+			{Body: []byte{OpcodeEnd}}, // start
+			{Body: []byte{OpcodeEnd}}, // unused
+			{Body: []byte{OpcodeEnd}}, // in an element segment
+			{Body: []byte{OpcodeEnd}}, // referenced by a global
+		},
+		StartSection:   &start,
+		ElementSection: []ElementSegment{{Init: []Index{2, ElementInitNullReference}}},
+		GlobalSection: []Global{
+			{Type: GlobalType{ValType: ValueTypeFuncref}, Init: ConstantExpression{Opcode: OpcodeRefFunc, Data: []byte{3}}},
+		},
+	}
+
+	err := m.RemoveUnusedFunctions()
+	require.NoError(t, err)
+
+	require.Equal(t, []Index{0, 0, 0}, m.FunctionSection)
+	require.Equal(t, Index(0), *m.StartSection)
+	require.Equal(t, []Index{1, ElementInitNullReference}, m.ElementSection[0].Init)
+	require.Equal(t, []byte{2}, m.GlobalSection[0].Init.Data)
+}
+
+func TestModule_CallEdges(t *testing.T) {
+	m := &Module{
+		TypeSection:     []FunctionType{v_v},
+		FunctionSection: []Index{0, 0, 0},
+		CodeSection: []Code{
+			// func[0] calls func[2] twice and func[1], and takes a reference to itself.
+			{Body: []byte{
+				OpcodeCall, 2, OpcodeCall, 1, OpcodeCall, 2,
+				OpcodeRefFunc, 0, OpcodeDrop, OpcodeEnd,
+			}},
+			// func[1] calls func[2].
+			{Body: []byte{OpcodeCall, 2, OpcodeEnd}},
+			// func[2] is a leaf.
+			{Body: []byte{OpcodeEnd}},
+		},
+	}
+
+	edges, err := m.CallEdges()
+	require.NoError(t, err)
+	require.Equal(t, []CallEdges{
+		{Calls: []Index{1, 2}, RefFuncs: []Index{0}},
+		{Calls: []Index{2}},
+		{},
+	}, edges)
+
+	t.Run("imported functions have no edges", func(t *testing.T) {
+		m := &Module{
+			TypeSection:         []FunctionType{v_v},
+			ImportSection:       []Import{{Type: ExternTypeFunc, Module: "env", Name: "log", DescFunc: 0}},
+			ImportFunctionCount: 1,
+			FunctionSection:     []Index{0},
+			CodeSection:         []Code{{Body: []byte{OpcodeCall, 0, OpcodeEnd}}},
+		}
+
+		edges, err := m.CallEdges()
+		require.NoError(t, err)
+		require.Equal(t, []CallEdges{{}, {Calls: []Index{0}}}, edges)
+	})
+}
@@ -0,0 +1,335 @@
+package wasm
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/tetratelabs/wazero/internal/leb128"
+)
+
+// RemapFunctionIndexes rewrites every reference to a function index in this module according to mapping, where the key
+// is the old index and the value is the new one. Indexes not in mapping are left as-is.
+//
+// References rewritten are the immediates of OpcodeCall and OpcodeRefFunc in CodeSection, ElementSection Init,
+// OpcodeRefFunc global initializers, function exports, StartSection and function indexes in NameSection.
+//
+// Note: This doesn't move any imports or functions. Callers which remove or reorder functions, such as a dead code
+// elimination pass, are expected to update ImportSection, FunctionSection and CodeSection to match mapping.
+// Note: Code.BodyOffsetInCodeSection isn't updated, so DWARF line information is stale after a body changes size.
+func (m *Module) RemapFunctionIndexes(mapping map[Index]Index) error {
+	remap := func(idx Index) Index {
+		if newIdx, ok := mapping[idx]; ok {
+			return newIdx
+		}
+		return idx
+	}
+
+	for i := range m.CodeSection {
+		c := &m.CodeSection[i]
+		if c.GoFunc != nil {
+			continue
+		}
+		body, err := remapFunctionIndexImmediates(c.Body, remap)
+		if err != nil {
+			return fmt.Errorf("%s: %w", m.funcDesc(SectionIDCode, Index(i)), err)
+		}
+		c.Body = body
+	}
+
+	for i := range m.ElementSection {
+		init := m.ElementSection[i].Init
+		for j, idx := range init {
+			if idx&(ElementInitNullReference|ElementInitImportedGlobalFunctionReference) != 0 {
+				continue // Not a function index.
+			}
+			init[j] = remap(idx)
+		}
+	}
+
+	for i := range m.GlobalSection {
+		expr := &m.GlobalSection[i].Init
+		if expr.Opcode != OpcodeRefFunc {
+			continue
+		}
+		idx, _, err := leb128.LoadUint32(expr.Data)
+		if err != nil {
+			return fmt.Errorf("global[%d]: read function index: %w", i, err)
+		}
+		expr.Data = leb128.EncodeUint32(remap(idx))
+	}
+
+	// Note: Exports points into ExportSection, so it is updated as well.
+	for i := range m.ExportSection {
+		if exp := &m.ExportSection[i]; exp.Type == ExternTypeFunc {
+			exp.Index = remap(exp.Index)
+		}
+	}
+
+	if m.StartSection != nil {
+		start := remap(*m.StartSection)
+		m.StartSection = &start
+	}
+
+	if n := m.NameSection; n != nil {
+		for i := range n.FunctionNames {
+			n.FunctionNames[i].Index = remap(n.FunctionNames[i].Index)
+		}
+		for i := range n.LocalNames {
+			n.LocalNames[i].Index = remap(n.LocalNames[i].Index)
+		}
+		for i := range n.ResultNames {
+			n.ResultNames[i].Index = remap(n.ResultNames[i].Index)
+		}
+	}
+	return nil
+}
+
+// remapFunctionIndexImmediates returns body with the function index of each OpcodeCall and OpcodeRefFunc replaced by
+// the result of remap. The original body is returned when nothing changed.
+func remapFunctionIndexImmediates(body []byte, remap func(Index) Index) ([]byte, error) {
+	immediates, err := indexImmediates(body)
+	if err != nil {
+		return nil, err
+	}
+	return remapIndexImmediates(body, immediates, func(imm indexImmediate) Index {
+		if imm.kind != indexKindFunction {
+			return imm.index
+		}
+		return remap(imm.index)
+	}), nil
+}
+
+// remapIndexImmediates returns body with each of the immediates replaced by the result of remap. The original body is
+// returned when nothing changed.
+func remapIndexImmediates(body []byte, immediates []indexImmediate, remap func(indexImmediate) Index) []byte {
+	var ret []byte
+	var last uint64
+	for _, imm := range immediates {
+		newIdx := remap(imm)
+		if newIdx == imm.index {
+			continue
+		}
+		if ret == nil {
+			ret = make([]byte, 0, len(body))
+		}
+		ret = append(ret, body[last:imm.pc]...)
+		if imm.signed {
+			ret = append(ret, leb128.EncodeInt64(int64(newIdx))...)
+		} else {
+			ret = append(ret, leb128.EncodeUint32(newIdx)...)
+		}
+		last = imm.pc + imm.len
+	}
+	if ret == nil {
+		return body
+	}
+	return append(ret, body[last:]...)
+}
+
+// indexKind is the index space of an indexImmediate.
+type indexKind byte
+
+const (
+	indexKindFunction indexKind = iota
+	indexKindType
+	indexKindGlobal
+	indexKindTable
+	indexKindElement
+	indexKindData
+)
+
+// indexImmediate is the position of an index immediate in a function body.
+type indexImmediate struct {
+	// pc is the offset of the first byte of the LEB128 encoded index.
+	pc uint64
+	// len is the count of bytes of the LEB128 encoded index.
+	len uint64
+	// index is the decoded index.
+	index Index
+	// kind is the index space the index belongs to.
+	kind indexKind
+	// signed is true when the index is encoded as a signed integer, as is the case for a block type.
+	signed bool
+}
+
+// indexImmediates returns the immediates in the body which index a function, type, global, table, element segment or
+// data segment, in order of appearance. Other immediates, such as local indexes, are skipped.
+//
+// Note: The body must have been validated, as this only decodes enough of each instruction to find the next one.
+func indexImmediates(body []byte) ([]indexImmediate, error) {
+	return walkInstructions(body, nil)
+}
+
+// walkInstructions is like indexImmediates, except it also calls visit, when non-nil, with the opcode and pc of each
+// instruction. subOp is the opcode following OpcodeMiscPrefix, OpcodeVecPrefix or OpcodeAtomicPrefix, or zero.
+func walkInstructions(body []byte, visit func(op Opcode, subOp uint32, pc uint64)) (ret []indexImmediate, err error) {
+	// read appends the index at pc and returns the pc after it.
+	read := func(pc uint64, kind indexKind) (uint64, error) {
+		idx, n, err := leb128.LoadUint32(body[pc:])
+		if err != nil {
+			return 0, err
+		}
+		ret = append(ret, indexImmediate{pc: pc, len: n, index: idx, kind: kind})
+		return pc + n, nil
+	}
+
+	for pc := uint64(0); pc < uint64(len(body)); pc++ {
+		op := body[pc]
+		if visit != nil {
+			var subOp uint32
+			switch op {
+			case OpcodeMiscPrefix:
+				if subOp, _, err = leb128.LoadUint32(body[pc+1:]); err != nil {
+					return nil, fmt.Errorf("read immediates of %s: %w", InstructionName(op), err)
+				}
+			case OpcodeVecPrefix, OpcodeAtomicPrefix:
+				if pc+1 >= uint64(len(body)) {
+					return nil, fmt.Errorf("read immediates of %s: %w", InstructionName(op), io.ErrUnexpectedEOF)
+				}
+				subOp = uint32(body[pc+1])
+			}
+			visit(op, subOp, pc)
+		}
+		pc++
+		switch {
+		case op == OpcodeCall || op == OpcodeRefFunc:
+			pc, err = read(pc, indexKindFunction)
+		case op == OpcodeCallIndirect:
+			if pc, err = read(pc, indexKindType); err == nil {
+				pc, err = read(pc, indexKindTable)
+			}
+		case op == OpcodeBlock || op == OpcodeLoop || op == OpcodeIf:
+			if pc >= uint64(len(body)) {
+				err = io.ErrUnexpectedEOF
+				break
+			}
+			switch body[pc] {
+			case 0x40, ValueTypeI32, ValueTypeI64, ValueTypeF32, ValueTypeF64, ValueTypeV128, ValueTypeFuncref, ValueTypeExternref:
+				pc++
+			default: // type index encoded as a signed 33-bit integer
+				var idx int64
+				var n uint64
+				if idx, n, err = leb128.DecodeInt33AsInt64(bytes.NewReader(body[pc:])); err == nil {
+					ret = append(ret, indexImmediate{pc: pc, len: n, index: Index(idx), kind: indexKindType, signed: true})
+					pc += n
+				}
+			}
+		case op == OpcodeGlobalGet || op == OpcodeGlobalSet:
+			pc, err = read(pc, indexKindGlobal)
+		case op == OpcodeTableGet || op == OpcodeTableSet:
+			pc, err = read(pc, indexKindTable)
+		case op == OpcodeBr || op == OpcodeBrIf,
+			op == OpcodeLocalGet || op == OpcodeLocalSet || op == OpcodeLocalTee:
+			pc, err = skipLEB128s(body, pc, 1)
+		case op == OpcodeBrTable:
+			var count uint32
+			var n uint64
+			if count, n, err = leb128.LoadUint32(body[pc:]); err == nil {
+				pc, err = skipLEB128s(body, pc+n, uint64(count)+1) // +1 for the default label
+			}
+		case OpcodeI32Load <= op && op <= OpcodeI64Store32: // memarg
+			pc, err = skipLEB128s(body, pc, 2)
+		case op == OpcodeTypedSelect:
+			var count uint32
+			var n uint64
+			if count, n, err = leb128.LoadUint32(body[pc:]); err == nil {
+				pc += n + uint64(count)
+			}
+		case op == OpcodeMemorySize || op == OpcodeMemoryGrow: // memory index
+			pc, err = skipLEB128s(body, pc, 1)
+		case op == OpcodeRefNull:
+			pc++
+		case op == OpcodeI32Const || op == OpcodeI64Const:
+			var n uint64
+			if _, n, err = leb128.LoadInt64(body[pc:]); err == nil {
+				pc += n
+			}
+		case op == OpcodeF32Const:
+			pc += 4
+		case op == OpcodeF64Const:
+			pc += 8
+		case op == OpcodeMiscPrefix:
+			var miscOp uint32
+			var n uint64
+			if miscOp, n, err = leb128.LoadUint32(body[pc:]); err != nil {
+				break
+			}
+			pc += n
+			switch OpcodeMisc(miscOp) {
+			case OpcodeMiscMemoryInit:
+				if pc, err = read(pc, indexKindData); err == nil {
+					pc++ // reserved memory index
+				}
+			case OpcodeMiscDataDrop:
+				pc, err = read(pc, indexKindData)
+			case OpcodeMiscMemoryCopy:
+				pc += 2 // reserved memory indexes
+			case OpcodeMiscMemoryFill:
+				pc++ // reserved memory index
+			case OpcodeMiscTableInit:
+				if pc, err = read(pc, indexKindElement); err == nil {
+					pc, err = read(pc, indexKindTable)
+				}
+			case OpcodeMiscElemDrop:
+				pc, err = read(pc, indexKindElement)
+			case OpcodeMiscTableCopy:
+				if pc, err = read(pc, indexKindTable); err == nil {
+					pc, err = read(pc, indexKindTable)
+				}
+			case OpcodeMiscTableGrow, OpcodeMiscTableSize, OpcodeMiscTableFill:
+				pc, err = read(pc, indexKindTable)
+			}
+		case op == OpcodeVecPrefix:
+			pc, err = skipVecImmediates(body, pc)
+		case op == OpcodeAtomicPrefix:
+			if pc >= uint64(len(body)) {
+				err = io.ErrUnexpectedEOF
+			} else if body[pc] == OpcodeAtomicFence {
+				pc += 2
+			} else {
+				pc, err = skipLEB128s(body, pc+1, 2)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read immediates of %s: %w", InstructionName(op), err)
+		}
+		// Move back to the last byte of this instruction, as the loop increments pc.
+		pc--
+	}
+	return
+}
+
+// skipVecImmediates returns the pc after the opcode and immediates of the OpcodeVecPrefix instruction at pc.
+func skipVecImmediates(body []byte, pc uint64) (uint64, error) {
+	if pc >= uint64(len(body)) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	vecOp := body[pc]
+	pc++
+	switch {
+	case vecOp <= OpcodeVecV128Store, vecOp == OpcodeVecV128Load32zero, vecOp == OpcodeVecV128Load64zero:
+		return skipLEB128s(body, pc, 2) // memarg
+	case OpcodeVecV128Load8Lane <= vecOp && vecOp <= OpcodeVecV128Store64Lane:
+		// memarg followed by the lane index
+		pc, err := skipLEB128s(body, pc, 2)
+		return pc + 1, err
+	case vecOp == OpcodeVecV128Const || vecOp == OpcodeVecV128i8x16Shuffle:
+		return pc + 16, nil
+	case OpcodeVecI8x16ExtractLaneS <= vecOp && vecOp <= OpcodeVecF64x2ReplaceLane:
+		return pc + 1, nil // lane index
+	}
+	return pc, nil
+}
+
+// skipLEB128s returns the pc after count LEB128 encoded integers starting at pc.
+func skipLEB128s(body []byte, pc, count uint64) (uint64, error) {
+	for i := uint64(0); i < count; i++ {
+		_, n, err := leb128.LoadUint64(body[pc:])
+		if err != nil {
+			return 0, err
+		}
+		pc += n
+	}
+	return pc, nil
+}
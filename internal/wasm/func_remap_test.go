@@ -0,0 +1,210 @@
+package wasm
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestModule_RemapFunctionIndexes(t *testing.T) {
+	start := Index(1)
+	m := &Module{
+		TypeSection:     []FunctionType{v_v},
+		FunctionSection: []Index{0, 0},
+		CodeSection: []Code{
+			{Body: []byte{OpcodeCall, 1, OpcodeRefFunc, 0, OpcodeDrop, OpcodeEnd}},
+			{Body: []byte{OpcodeEnd}},
+		},
+		GlobalSection: []Global{
+			{Type: GlobalType{ValType: ValueTypeFuncref}, Init: ConstantExpression{Opcode: OpcodeRefFunc, Data: []byte{1}}},
+		},
+		ElementSection: []ElementSegment{
+			{Init: []Index{0, 1, ElementInitNullReference, ElementInitImportedGlobalFunctionReference | 1}},
+		},
+		ExportSection: []Export{
+			{Type: ExternTypeFunc, Name: "run", Index: 0},
+			{Type: ExternTypeGlobal, Name: "global", Index: 0},
+		},
+		StartSection: &start,
+		NameSection: &NameSection{
+			FunctionNames: NameMap{{Index: 0, Name: "run"}, {Index: 1, Name: "helper"}},
+		},
+	}
+	m.Exports = map[string]*Export{"run": &m.ExportSection[0], "global": &m.ExportSection[1]}
+
+	// 200 needs two bytes in LEB128, so the body grows.
+	err := m.RemapFunctionIndexes(map[Index]Index{0: 1, 1: 200})
+	require.NoError(t, err)
+
+	require.Equal(t, []byte{OpcodeCall, 0xc8, 0x01, OpcodeRefFunc, 1, OpcodeDrop, OpcodeEnd}, m.CodeSection[0].Body)
+	require.Equal(t, []byte{OpcodeEnd}, m.CodeSection[1].Body)
+	require.Equal(t, leb128.EncodeUint32(200), m.GlobalSection[0].Init.Data)
+	require.Equal(t, []Index{1, 200, ElementInitNullReference, ElementInitImportedGlobalFunctionReference | 1},
+		m.ElementSection[0].Init)
+	require.Equal(t, Index(1), m.Exports["run"].Index)
+	require.Equal(t, Index(0), m.Exports["global"].Index)
+	require.Equal(t, Index(200), *m.StartSection)
+	require.Equal(t, Index(1), start) // The original pointer is not modified.
+	require.Equal(t, NameMap{{Index: 1, Name: "run"}, {Index: 200, Name: "helper"}}, m.NameSection.FunctionNames)
+}
+
+func TestModule_RemapFunctionIndexes_Errors(t *testing.T) {
+	m := &Module{
+		TypeSection:     []FunctionType{v_v},
+		FunctionSection: []Index{0},
+		CodeSection:     []Code{{Body: []byte{OpcodeBlock}}},
+	}
+	err := m.RemapFunctionIndexes(map[Index]Index{0: 1})
+	require.EqualError(t, err, "code[0]: read immediates of block: unexpected EOF")
+}
+
+func Test_indexImmediates(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     []byte
+		expected []indexImmediate
+	}{
+		{
+			name: "no calls",
+			body: []byte{OpcodeI32Const, 0x10, OpcodeDrop, OpcodeEnd}, // 0x10 is OpcodeCall, but an immediate here.
+		},
+		{
+			name: "call",
+			body: []byte{OpcodeCall, 0x80, 0x01, OpcodeEnd},
+			expected: []indexImmediate{
+				{pc: 1, len: 2, index: 128, kind: indexKindFunction},
+			},
+		},
+		{
+			name: "calls after other immediates",
+			body: []byte{
+				OpcodeBlock, 0x40,
+				OpcodeI64Const, 0x90, 0x90, 0x10, // multi-byte immediate including the byte 0x10
+				OpcodeDrop,
+				OpcodeF32Const, 0x10, 0x10, 0x10, 0x10,
+				OpcodeDrop,
+				OpcodeF64Const, 0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x10,
+				OpcodeDrop,
+				OpcodeI32Const, 0,
+				OpcodeBrTable, 2, 0x10, 0x10, 0,
+				OpcodeEnd,
+				OpcodeCall, 3,
+				OpcodeI32Const, 0,
+				OpcodeI32Load, 0x02, 0x10,
+				OpcodeDrop,
+				OpcodeI32Const, 0, OpcodeI32Const, 0, OpcodeI32Const, 0,
+				OpcodeMiscPrefix, OpcodeMiscMemoryCopy, 0, 0,
+				OpcodeVecPrefix, OpcodeVecV128Const, 0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x10,
+				0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x10,
+				OpcodeDrop,
+				OpcodeRefFunc, 4,
+				OpcodeDrop,
+				OpcodeEnd,
+			},
+			expected: []indexImmediate{
+				{pc: 32, len: 1, index: 3, kind: indexKindFunction},
+				{pc: 69, len: 1, index: 4, kind: indexKindFunction},
+			},
+		},
+		{
+			name: "call after a multi-byte memory index",
+			body: []byte{
+				OpcodeMemorySize, 0x80, 0x10, // 0x10 is OpcodeCall, but part of the memory index here.
+				OpcodeDrop,
+				OpcodeCall, 1,
+				OpcodeEnd,
+			},
+			expected: []indexImmediate{
+				{pc: 5, len: 1, index: 1, kind: indexKindFunction},
+			},
+		},
+		{
+			name: "type and table indexes",
+			body: []byte{
+				OpcodeBlock, 0xc0, 0x00, // type 64 which needs two bytes as a signed integer
+				OpcodeI32Const, 0,
+				OpcodeCallIndirect, 0x80, 0x01, 0,
+				OpcodeEnd,
+				OpcodeEnd,
+			},
+			expected: []indexImmediate{
+				{pc: 1, len: 2, index: 64, kind: indexKindType, signed: true},
+				{pc: 6, len: 2, index: 128, kind: indexKindType},
+				{pc: 8, len: 1, index: 0, kind: indexKindTable},
+			},
+		},
+		{
+			name: "global, table, element and data indexes",
+			body: []byte{
+				OpcodeGlobalGet, 1,
+				OpcodeGlobalSet, 2,
+				OpcodeI32Const, 0,
+				OpcodeTableGet, 3,
+				OpcodeDrop,
+				OpcodeI32Const, 0, OpcodeI32Const, 0, OpcodeI32Const, 0,
+				OpcodeMiscPrefix, OpcodeMiscMemoryInit, 4, 0,
+				OpcodeMiscPrefix, OpcodeMiscDataDrop, 5,
+				OpcodeI32Const, 0, OpcodeI32Const, 0, OpcodeI32Const, 0,
+				OpcodeMiscPrefix, OpcodeMiscTableInit, 6, 7,
+				OpcodeMiscPrefix, OpcodeMiscElemDrop, 8,
+				OpcodeEnd,
+			},
+			expected: []indexImmediate{
+				{pc: 1, len: 1, index: 1, kind: indexKindGlobal},
+				{pc: 3, len: 1, index: 2, kind: indexKindGlobal},
+				{pc: 7, len: 1, index: 3, kind: indexKindTable},
+				{pc: 17, len: 1, index: 4, kind: indexKindData},
+				{pc: 21, len: 1, index: 5, kind: indexKindData},
+				{pc: 30, len: 1, index: 6, kind: indexKindElement},
+				{pc: 31, len: 1, index: 7, kind: indexKindTable},
+				{pc: 34, len: 1, index: 8, kind: indexKindElement},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := indexImmediates(tc.body)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func Test_indexImmediates_Errors(t *testing.T) {
+	tests := []struct {
+		name, expectedErr string
+		body              []byte
+	}{
+		{
+			name:        "block without a block type",
+			body:        []byte{OpcodeBlock},
+			expectedErr: "read immediates of block: unexpected EOF",
+		},
+		{
+			name:        "if without a block type",
+			body:        []byte{OpcodeI32Const, 0, OpcodeIf},
+			expectedErr: "read immediates of if: unexpected EOF",
+		},
+		{
+			name:        "vector prefix without an opcode",
+			body:        []byte{OpcodeVecPrefix},
+			expectedErr: "read immediates of vector_prefix: unexpected EOF",
+		},
+		{
+			name:        "atomic prefix without an opcode",
+			body:        []byte{OpcodeAtomicPrefix},
+			expectedErr: "read immediates of atomic_prefix: unexpected EOF",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := indexImmediates(tc.body)
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
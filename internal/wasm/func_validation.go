@@ -8,12 +8,17 @@ import (
 	"strings"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/leb128"
 )
 
 // The wazero specific limitation described at RATIONALE.md.
 const maximumValuesOnStack = 1 << 27
 
+// MaximumBlockDepth is the default limit of nested blocks, loops and ifs in a function, which prevents pathological
+// nesting from exhausting resources of compilers which handle nested blocks recursively.
+const MaximumBlockDepth = uint32(1 << 16)
+
 // validateFunction validates the instruction sequence of a function.
 // following the specification https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#instructions%E2%91%A2.
 //
@@ -25,7 +30,7 @@ const maximumValuesOnStack = 1 << 27
 // * declaredFunctionIndexes is the set of function indexes declared by declarative element segments which can be acceed by OpcodeRefFunc instruction.
 //
 // Returns an error if the instruction sequence is not valid,
-// or potentially it can exceed the maximum number of values on the stack.
+// or potentially it can exceed the maximum number of values on the stack or nested blocks.
 func (m *Module) validateFunction(sts *stacks, enabledFeatures api.CoreFeatures, idx Index, functions []Index,
 	globals []GlobalType, memory *Memory, tables []Table, declaredFunctionIndexes map[Index]struct{}, br *bytes.Reader,
 ) error {
@@ -50,6 +55,14 @@ func readMemArg(pc uint64, body []byte) (align, offset uint32, read uint64, err
 	return align, offset, read, nil
 }
 
+// atomicOpcodeName returns the name of the atomic instruction for error messages, or its hex value if it is invalid.
+func atomicOpcodeName(oc OpcodeAtomic) string {
+	if name, ok := atomicInstructionName[oc]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%x", oc)
+}
+
 // validateFunctionWithMaxStackValues is like validateFunction, but allows overriding maxStackValues for testing.
 //
 // * stacks is to track the state of Wasm value and control frame stacks at anypoint of execution, and reused to reduce allocation.
@@ -92,6 +105,11 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			fmt.Printf("handling %s, stack=%s, blocks: %v\n", instName, valueTypeStack.stack, controlBlockStack)
 		}
 
+		// The function ends when its outermost block does. OpcodeElse and OpcodeEnd are reported as redundant below.
+		if len(controlBlockStack.stack) == 0 && op != OpcodeElse && op != OpcodeEnd {
+			return fmt.Errorf("unexpected %s instruction after the end of the function at %#x", InstructionName(op), pc)
+		}
+
 		if OpcodeI32Load <= op && op <= OpcodeI64Store32 {
 			if memory == nil {
 				return fmt.Errorf("memory must exist for %s", InstructionName(op))
@@ -283,7 +301,13 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			if err != nil {
 				return fmt.Errorf("read immediate: %v", err)
 			}
-			if val != 0 || num != 1 {
+			if enabledFeatures.IsEnabled(experimental.CoreFeaturesMultiMemory) {
+				// The multi-memory proposal turns the reserved byte into a memory index. A module has at most one
+				// memory, which is the only one in range.
+				if val != 0 {
+					return fmt.Errorf("memory index %d out of range for %s", val, InstructionName(op))
+				}
+			} else if val != 0 || num != 1 {
 				return fmt.Errorf("memory instruction reserved bytes not zero with 1 byte")
 			}
 			switch Opcode(op) {
@@ -352,7 +376,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 					expType = localTypes[index-inputLen]
 				}
 				if err := valueTypeStack.popAndVerifyType(expType); err != nil {
-					return err
+					return fmt.Errorf("cannot pop the operand for %s: %v", OpcodeLocalSetName, err)
 				}
 			case OpcodeLocalTee:
 				inputLen := uint32(len(functionType.Params))
@@ -367,8 +391,9 @@ func (m *Module) validateFunctionWithMaxStackValues(
 					expType = localTypes[index-inputLen]
 				}
 				if err := valueTypeStack.popAndVerifyType(expType); err != nil {
-					return err
+					return fmt.Errorf("cannot pop the operand for %s: %v", OpcodeLocalTeeName, err)
 				}
+				// Unlike local.set, the value stays on the stack, with the type of the local.
 				valueTypeStack.push(expType)
 			case OpcodeGlobalGet:
 				if index >= uint32(len(globals)) {
@@ -535,7 +560,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			}
 			pc += num - 1
 			if int(index) >= len(functions) {
-				return fmt.Errorf("invalid function index")
+				return fmt.Errorf("invalid function index: %d", index)
 			}
 			funcType := &m.TypeSection[functions[index]]
 			for i := 0; i < len(funcType.Params); i++ {
@@ -1394,7 +1419,9 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			if err != nil {
 				return fmt.Errorf("read block: %w", err)
 			}
-			controlBlockStack.push(pc, 0, 0, bt, num, 0)
+			if err = controlBlockStack.push(pc, 0, 0, bt, num, 0); err != nil {
+				return err
+			}
 			if err = valueTypeStack.popParams(op, bt.Params, false); err != nil {
 				return err
 			}
@@ -1410,7 +1437,9 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			if err != nil {
 				return fmt.Errorf("read block: %w", err)
 			}
-			controlBlockStack.push(pc, 0, 0, bt, num, op)
+			if err = controlBlockStack.push(pc, 0, 0, bt, num, op); err != nil {
+				return err
+			}
 			if err = valueTypeStack.popParams(op, bt.Params, false); err != nil {
 				return err
 			}
@@ -1426,7 +1455,9 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			if err != nil {
 				return fmt.Errorf("read block: %w", err)
 			}
-			controlBlockStack.push(pc, 0, 0, bt, num, op)
+			if err = controlBlockStack.push(pc, 0, 0, bt, num, op); err != nil {
+				return err
+			}
 			if err = valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
 				return fmt.Errorf("cannot pop the operand for 'if': %v", err)
 			}
@@ -1536,17 +1567,131 @@ func (m *Module) validateFunctionWithMaxStackValues(
 					tp != api.ValueTypeExternref && tp != ValueTypeFuncref && tp != ValueTypeV128 {
 					return fmt.Errorf("invalid type %s for %s", ValueTypeName(tp), OpcodeTypedSelectName)
 				}
-			} else if isReferenceValueType(v1) || isReferenceValueType(v2) {
-				return fmt.Errorf("reference types cannot be used for non typed select instruction")
+				// Unlike select, both operands must be the declared type.
+				if (v1 != tp && v1 != valueTypeUnknown) || (v2 != tp && v2 != valueTypeUnknown) {
+					return fmt.Errorf("type mismatch on %s operands: expected %s", OpcodeTypedSelectName, ValueTypeName(tp))
+				}
+				valueTypeStack.push(tp)
+			} else {
+				if isReferenceValueType(v1) || isReferenceValueType(v2) {
+					return fmt.Errorf("reference types cannot be used for non typed select instruction")
+				}
+				if v1 != v2 && v1 != valueTypeUnknown && v2 != valueTypeUnknown {
+					return fmt.Errorf("type mismatch on 1st and 2nd select operands")
+				}
+				if v1 == valueTypeUnknown {
+					valueTypeStack.push(v2)
+				} else {
+					valueTypeStack.push(v1)
+				}
+			}
+		} else if op == OpcodeAtomicPrefix {
+			pc++
+			// Atomic instructions come with two bytes where the first byte is always OpcodeAtomicPrefix,
+			// and the second byte determines the actual instruction.
+			atomicOpcode := body[pc]
+			if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesThreads); err != nil {
+				return fmt.Errorf("%s invalid as %v", atomicOpcodeName(atomicOpcode), err)
 			}
+			pc++
 
-			if v1 != v2 && v1 != valueTypeUnknown && v2 != valueTypeUnknown {
-				return fmt.Errorf("type mismatch on 1st and 2nd select operands")
+			if atomicOpcode == OpcodeAtomicFence {
+				// No memory requirement and no arguments or return, however the immediate byte value must be 0.
+				if pc >= uint64(len(body)) || body[pc] != 0x0 {
+					return fmt.Errorf("invalid immediate value for %s", atomicOpcodeName(atomicOpcode))
+				}
+				continue
 			}
-			if v1 == valueTypeUnknown {
-				valueTypeStack.push(v2)
-			} else {
-				valueTypeStack.push(v1)
+
+			// All atomic operations except fence (checked above) require memory
+			if memory == nil {
+				return fmt.Errorf("memory must exist for %s", atomicOpcodeName(atomicOpcode))
+			}
+			align, _, read, err := readMemArg(pc, body)
+			if err != nil {
+				return err
+			}
+			pc += read - 1
+
+			// bytesAccessed is the natural alignment, which atomic instructions must declare exactly.
+			var bytesAccessed uint32
+			var params []ValueType
+			var result ValueType // zero means no result.
+			switch atomicOpcode {
+			case OpcodeAtomicMemoryNotify:
+				bytesAccessed, params, result = 4, []ValueType{ValueTypeI32, ValueTypeI32}, ValueTypeI32
+			case OpcodeAtomicMemoryWait32:
+				bytesAccessed, params, result = 4, []ValueType{ValueTypeI32, ValueTypeI32, ValueTypeI64}, ValueTypeI32
+			case OpcodeAtomicMemoryWait64:
+				bytesAccessed, params, result = 8, []ValueType{ValueTypeI32, ValueTypeI64, ValueTypeI64}, ValueTypeI32
+			case OpcodeAtomicI32Load:
+				bytesAccessed, params, result = 4, []ValueType{ValueTypeI32}, ValueTypeI32
+			case OpcodeAtomicI64Load:
+				bytesAccessed, params, result = 8, []ValueType{ValueTypeI32}, ValueTypeI64
+			case OpcodeAtomicI32Load8U:
+				bytesAccessed, params, result = 1, []ValueType{ValueTypeI32}, ValueTypeI32
+			case OpcodeAtomicI32Load16U:
+				bytesAccessed, params, result = 2, []ValueType{ValueTypeI32}, ValueTypeI32
+			case OpcodeAtomicI64Load8U:
+				bytesAccessed, params, result = 1, []ValueType{ValueTypeI32}, ValueTypeI64
+			case OpcodeAtomicI64Load16U:
+				bytesAccessed, params, result = 2, []ValueType{ValueTypeI32}, ValueTypeI64
+			case OpcodeAtomicI64Load32U:
+				bytesAccessed, params, result = 4, []ValueType{ValueTypeI32}, ValueTypeI64
+			case OpcodeAtomicI32Store:
+				bytesAccessed, params = 4, []ValueType{ValueTypeI32, ValueTypeI32}
+			case OpcodeAtomicI64Store:
+				bytesAccessed, params = 8, []ValueType{ValueTypeI32, ValueTypeI64}
+			case OpcodeAtomicI32Store8:
+				bytesAccessed, params = 1, []ValueType{ValueTypeI32, ValueTypeI32}
+			case OpcodeAtomicI32Store16:
+				bytesAccessed, params = 2, []ValueType{ValueTypeI32, ValueTypeI32}
+			case OpcodeAtomicI64Store8:
+				bytesAccessed, params = 1, []ValueType{ValueTypeI32, ValueTypeI64}
+			case OpcodeAtomicI64Store16:
+				bytesAccessed, params = 2, []ValueType{ValueTypeI32, ValueTypeI64}
+			case OpcodeAtomicI64Store32:
+				bytesAccessed, params = 4, []ValueType{ValueTypeI32, ValueTypeI64}
+			default:
+				if atomicOpcode < OpcodeAtomicI32RmwAdd || atomicOpcode > OpcodeAtomicI64Rmw32CmpxchgU {
+					return fmt.Errorf("invalid atomic opcode: 0x%x", atomicOpcode)
+				}
+				// Read-modify-write instructions are laid out in groups of seven, which only differ in the width.
+				var valType ValueType
+				switch (atomicOpcode - OpcodeAtomicI32RmwAdd) % 7 {
+				case 0:
+					bytesAccessed, valType = 4, ValueTypeI32
+				case 1:
+					bytesAccessed, valType = 8, ValueTypeI64
+				case 2:
+					bytesAccessed, valType = 1, ValueTypeI32
+				case 3:
+					bytesAccessed, valType = 2, ValueTypeI32
+				case 4:
+					bytesAccessed, valType = 1, ValueTypeI64
+				case 5:
+					bytesAccessed, valType = 2, ValueTypeI64
+				case 6:
+					bytesAccessed, valType = 4, ValueTypeI64
+				}
+				if atomicOpcode >= OpcodeAtomicI32RmwCmpxchg {
+					params = []ValueType{ValueTypeI32, valType, valType}
+				} else {
+					params = []ValueType{ValueTypeI32, valType}
+				}
+				result = valType
+			}
+
+			if 1<<align != bytesAccessed {
+				return fmt.Errorf("invalid memory alignment")
+			}
+			for i := len(params) - 1; i >= 0; i-- {
+				if err := valueTypeStack.popAndVerifyType(params[i]); err != nil {
+					return fmt.Errorf("cannot pop the operand for %s: %v", atomicOpcodeName(atomicOpcode), err)
+				}
+			}
+			if result != 0 {
+				valueTypeStack.push(result)
 			}
 		} else if op == OpcodeUnreachable {
 			// unreachable instruction is stack-polymorphic.
@@ -1557,8 +1702,15 @@ func (m *Module) validateFunctionWithMaxStackValues(
 		}
 	}
 
-	if len(controlBlockStack.stack) > 0 {
-		return fmt.Errorf("ill-nested block exists")
+	if n := len(controlBlockStack.stack); n > 1 { // The innermost unclosed block is the one missing its end.
+		bl := &controlBlockStack.stack[n-1]
+		name := OpcodeBlockName
+		if bl.op != 0 {
+			name = InstructionName(bl.op)
+		}
+		return fmt.Errorf("missing end of %s at %#x", name, bl.startAt)
+	} else if n == 1 {
+		return errors.New("missing end of function")
 	}
 	if valueTypeStack.maximumStackPointer > maxStackValues {
 		return fmt.Errorf("function may have %d stack values, which exceeds limit %d", valueTypeStack.maximumStackPointer, maxStackValues)
@@ -1637,6 +1789,8 @@ func (sts *stacks) reset(functionType *FunctionType) {
 
 type controlBlockStack struct {
 	stack []controlBlock
+	// maxDepth limits the nesting of blocks pushed onto the stack. Zero defaults to MaximumBlockDepth.
+	maxDepth uint32
 }
 
 func (s *controlBlockStack) pop() *controlBlock {
@@ -1646,7 +1800,15 @@ func (s *controlBlockStack) pop() *controlBlock {
 	return ret
 }
 
-func (s *controlBlockStack) push(startAt, elseAt, endAt uint64, blockType *FunctionType, blockTypeBytes uint64, op Opcode) {
+// push pushes a block, loop or if, failing if that nests more than maxDepth of them.
+func (s *controlBlockStack) push(startAt, elseAt, endAt uint64, blockType *FunctionType, blockTypeBytes uint64, op Opcode) error {
+	maxDepth := s.maxDepth
+	if maxDepth == 0 {
+		maxDepth = MaximumBlockDepth
+	}
+	if uint32(len(s.stack)) > maxDepth { // The function itself is at the bottom of the stack.
+		return fmt.Errorf("too many nested blocks: exceeds limit %d", maxDepth)
+	}
 	s.stack = append(s.stack, controlBlock{
 		startAt:        startAt,
 		elseAt:         elseAt,
@@ -1655,6 +1817,7 @@ func (s *controlBlockStack) push(startAt, elseAt, endAt uint64, blockType *Funct
 		blockTypeBytes: blockTypeBytes,
 		op:             op,
 	})
+	return nil
 }
 
 type valueTypeStack struct {
@@ -1913,10 +2076,13 @@ func DecodeBlockType(types []FunctionType, r *bytes.Reader, enabledFeatures api.
 	case -17: // 0x6f in original byte = externref
 		ret = blockType_v_externref
 	default:
+		if raw < 0 { // Only the value types above are encoded as negative numbers.
+			return nil, 0, fmt.Errorf("invalid block type: %d", raw)
+		}
 		if err = enabledFeatures.RequireEnabled(api.CoreFeatureMultiValue); err != nil {
 			return nil, num, fmt.Errorf("block with function type return invalid as %v", err)
 		}
-		if raw < 0 || (raw >= int64(len(types))) {
+		if raw >= int64(len(types)) {
 			return nil, 0, fmt.Errorf("type index out of range: %d", raw)
 		}
 		ret = &types[raw]
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 )
@@ -48,6 +49,63 @@ func TestModule_ValidateFunction_validateFunctionWithMaxStackValues(t *testing.T
 	})
 }
 
+func TestModule_ValidateFunction_BlockDepth(t *testing.T) {
+	// nestedBody returns a function body with depth blocks, loops and ifs nested in each other.
+	nestedBody := func(depth int) (body []byte) {
+		for i := 0; i < depth; i++ {
+			switch i % 3 {
+			case 0:
+				body = append(body, OpcodeBlock, 0x40)
+			case 1:
+				body = append(body, OpcodeLoop, 0x40)
+			case 2:
+				body = append(body, OpcodeI32Const, 1, OpcodeIf, 0x40)
+			}
+		}
+		for i := 0; i < depth; i++ {
+			body = append(body, OpcodeEnd)
+		}
+		return append(body, OpcodeEnd)
+	}
+
+	validate := func(depth int) error {
+		m := &Module{
+			TypeSection:     []FunctionType{v_v},
+			FunctionSection: []Index{0},
+			CodeSection:     []Code{{Body: nestedBody(depth)}},
+		}
+		return m.validateFunction(&stacks{}, api.CoreFeaturesV1,
+			0, []Index{0}, nil, nil, nil, nil, bytes.NewReader(nil))
+	}
+
+	maxDepth := int(MaximumBlockDepth)
+	expectedErr := fmt.Sprintf("too many nested blocks: exceeds limit %d", maxDepth)
+
+	t.Run("not exceed", func(t *testing.T) {
+		require.NoError(t, validate(maxDepth))
+	})
+	t.Run("exceed", func(t *testing.T) {
+		// the innermost is a loop, if and block respectively.
+		for _, depth := range []int{maxDepth + 1, maxDepth + 2, maxDepth + 3} {
+			require.EqualError(t, validate(depth), expectedErr)
+		}
+	})
+	t.Run("pathological nesting", func(t *testing.T) {
+		require.EqualError(t, validate(100_000), expectedErr)
+	})
+	t.Run("configured limit", func(t *testing.T) {
+		m := &Module{
+			TypeSection:     []FunctionType{v_v},
+			FunctionSection: []Index{0},
+			CodeSection:     []Code{{Body: nestedBody(11)}},
+		}
+		require.NoError(t, m.ValidateWithOptions(api.CoreFeaturesV1, ValidateOptions{MaxBlockDepth: 11}))
+		require.NoError(t, m.Validate(api.CoreFeaturesV1))
+		err := m.ValidateWithOptions(api.CoreFeaturesV1, ValidateOptions{MaxBlockDepth: 10})
+		require.EqualError(t, err, "invalid function[0]: too many nested blocks: exceeds limit 10")
+	})
+}
+
 func TestModule_ValidateFunction_SignExtensionOps(t *testing.T) {
 	tests := []struct {
 		input                Opcode
@@ -2643,6 +2701,21 @@ func TestModule_funcValidation_TableGetSet(t *testing.T) {
 	}
 }
 
+func TestModule_funcValidation_TypedSelect(t *testing.T) {
+	m := &Module{
+		TypeSection:     []FunctionType{{Results: []ValueType{ValueTypeExternref}}},
+		FunctionSection: []Index{0},
+		CodeSection: []Code{{Body: []byte{
+			OpcodeRefNull, RefTypeExternref, OpcodeRefNull, RefTypeExternref, OpcodeI32Const, 0,
+			OpcodeTypedSelect, 1, ValueTypeExternref,
+			OpcodeEnd,
+		}}},
+	}
+	err := m.validateFunction(&stacks{}, api.CoreFeatureReferenceTypes,
+		0, []Index{0}, nil, nil, nil, nil, bytes.NewReader(nil))
+	require.NoError(t, err)
+}
+
 func TestModule_funcValidation_Select_error(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -2680,6 +2753,28 @@ func TestModule_funcValidation_Select_error(t *testing.T) {
 			flag:        api.CoreFeatureReferenceTypes,
 			expectedErr: `invalid type unknown for typed_select`,
 		},
+		{
+			name: "typed_select (operands not the declared type)",
+			body: []byte{
+				OpcodeI32Const, 0, OpcodeI32Const, 0, OpcodeI32Const, 0,
+				OpcodeTypedSelect, 1, ValueTypeExternref,
+				OpcodeDrop,
+				OpcodeEnd,
+			},
+			flag:        api.CoreFeatureReferenceTypes,
+			expectedErr: `type mismatch on typed_select operands: expected externref`,
+		},
+		{
+			name: "select (reference operands)",
+			body: []byte{
+				OpcodeRefNull, RefTypeExternref, OpcodeRefNull, RefTypeExternref, OpcodeI32Const, 0,
+				OpcodeSelect,
+				OpcodeDrop,
+				OpcodeEnd,
+			},
+			flag:        api.CoreFeatureReferenceTypes,
+			expectedErr: `reference types cannot be used for non typed select instruction`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -2697,6 +2792,157 @@ func TestModule_funcValidation_Select_error(t *testing.T) {
 	}
 }
 
+func TestModule_funcValidation_IfWithoutElse(t *testing.T) {
+	tests := []struct {
+		name        string
+		types       []FunctionType
+		body        []byte
+		expectedErr string
+	}{
+		{
+			name:  "void",
+			types: []FunctionType{v_v},
+			body: []byte{
+				OpcodeI32Const, 1,
+				OpcodeIf, 0x40, // (if (then nop))
+				OpcodeNop,
+				OpcodeEnd,
+				OpcodeEnd,
+			},
+		},
+		{
+			name:  "params equal results",
+			types: []FunctionType{v_v, i32_i32},
+			body: []byte{
+				OpcodeI32Const, 1,
+				OpcodeI32Const, 1,
+				OpcodeIf, 0x01, // (if (param i32) (result i32) (then i32.const 2 i32.add))
+				OpcodeI32Const, 2,
+				OpcodeI32Add,
+				OpcodeEnd,
+				OpcodeDrop,
+				OpcodeEnd,
+			},
+		},
+		{
+			name:  "i32 result",
+			types: []FunctionType{v_v},
+			body: []byte{
+				OpcodeI32Const, 1,
+				OpcodeIf, ValueTypeI32, // (if (result i32) (then i32.const 2))
+				OpcodeI32Const, 2,
+				OpcodeEnd,
+				OpcodeDrop,
+				OpcodeEnd,
+			},
+			expectedErr: `not enough results in else block
+	have ()
+	want (i32)`,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			m := &Module{
+				TypeSection:     tc.types,
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{{Body: tc.body}},
+			}
+			err := m.validateFunction(&stacks{}, api.CoreFeaturesV2,
+				0, []Index{0}, nil, nil, nil, nil, bytes.NewReader(nil))
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestModule_funcValidation_LocalTee(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		// (func (param i32) (result i32) (local i32)
+		//   (i32.add (local.tee 1 (local.get 0)) (local.get 1)))
+		m := &Module{
+			TypeSection:     []FunctionType{i32_i32},
+			FunctionSection: []Index{0},
+			CodeSection: []Code{{LocalTypes: []ValueType{ValueTypeI32}, Body: []byte{
+				OpcodeLocalGet, 0,
+				OpcodeLocalTee, 1, // leaves the value on the stack.
+				OpcodeLocalGet, 1,
+				OpcodeI32Add,
+				OpcodeEnd,
+			}}},
+		}
+		err := m.validateFunction(&stacks{}, api.CoreFeaturesV1,
+			0, []Index{0}, nil, nil, nil, nil, bytes.NewReader(nil))
+		require.NoError(t, err)
+	})
+
+	tests := []struct {
+		name        string
+		body        []byte
+		expectedErr string
+	}{
+		{
+			name: "operand not the local type",
+			body: []byte{
+				OpcodeI32Const, 1,
+				OpcodeLocalTee, 1, // local[1] is f32
+				OpcodeDrop,
+				OpcodeEnd,
+			},
+			expectedErr: "cannot pop the operand for local.tee: type mismatch: expected f32, but was i32",
+		},
+		{
+			name: "result has the local type",
+			body: []byte{
+				OpcodeF32Const, 0, 0, 0, 0,
+				OpcodeLocalTee, 1, // local[1] is f32
+				OpcodeLocalGet, 0,
+				OpcodeI32Add,
+				OpcodeDrop,
+				OpcodeEnd,
+			},
+			expectedErr: "cannot pop the 2nd operand for i32.add: type mismatch: expected i32, but was f32",
+		},
+		{
+			name: "missing operand",
+			body: []byte{
+				OpcodeLocalTee, 0,
+				OpcodeDrop,
+				OpcodeEnd,
+			},
+			expectedErr: "cannot pop the operand for local.tee: i32 missing",
+		},
+		{
+			name: "local index out of range",
+			body: []byte{
+				OpcodeLocalGet, 0,
+				OpcodeLocalTee, 2,
+				OpcodeDrop,
+				OpcodeEnd,
+			},
+			expectedErr: "invalid local index for local.tee 2 >= 2(=len(locals)+len(parameters))",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			m := &Module{
+				TypeSection:     []FunctionType{i32_v},
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{{LocalTypes: []ValueType{ValueTypeF32}, Body: tc.body}},
+			}
+			err := m.validateFunction(&stacks{}, api.CoreFeaturesV1,
+				0, []Index{0}, nil, nil, nil, nil, bytes.NewReader(nil))
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
 func TestModule_funcValidation_SIMD(t *testing.T) {
 	addV128Const := func(in []byte) []byte {
 		return append(in, OpcodeVecPrefix,
@@ -3329,6 +3575,325 @@ func TestModule_funcValidation_SIMD_error(t *testing.T) {
 	}
 }
 
+func TestModule_funcValidation_Atomic(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		tests := []struct {
+			name string
+			body []byte
+		}{
+			{
+				name: "i32.atomic.load",
+				body: []byte{
+					OpcodeI32Const, 0x0,
+					OpcodeAtomicPrefix, OpcodeAtomicI32Load, 0x2, 0x8, // alignment=2 (natural alignment) staticOffset=8
+					OpcodeDrop,
+					OpcodeEnd,
+				},
+			},
+			{
+				name: "i64.atomic.rmw16.add_u",
+				body: []byte{
+					OpcodeI32Const, 0x0,
+					OpcodeI64Const, 0x1,
+					OpcodeAtomicPrefix, OpcodeAtomicI64Rmw16AddU, 0x1, 0x0,
+					OpcodeDrop,
+					OpcodeEnd,
+				},
+			},
+			{
+				name: "i32.atomic.rmw.cmpxchg",
+				body: []byte{
+					OpcodeI32Const, 0x0,
+					OpcodeI32Const, 0x1,
+					OpcodeI32Const, 0x2,
+					OpcodeAtomicPrefix, OpcodeAtomicI32RmwCmpxchg, 0x2, 0x0,
+					OpcodeDrop,
+					OpcodeEnd,
+				},
+			},
+			{
+				name: "memory.atomic.notify",
+				body: []byte{
+					OpcodeI32Const, 0x0,
+					OpcodeI32Const, 0x1,
+					OpcodeAtomicPrefix, OpcodeAtomicMemoryNotify, 0x2, 0x0,
+					OpcodeDrop,
+					OpcodeEnd,
+				},
+			},
+			{
+				name: "memory.atomic.wait64",
+				body: []byte{
+					OpcodeI32Const, 0x0,
+					OpcodeI64Const, 0x1,
+					OpcodeI64Const, 0x2,
+					OpcodeAtomicPrefix, OpcodeAtomicMemoryWait64, 0x3, 0x0,
+					OpcodeDrop,
+					OpcodeEnd,
+				},
+			},
+			{
+				name: "atomic.fence",
+				body: []byte{
+					OpcodeAtomicPrefix, OpcodeAtomicFence, 0x0,
+					OpcodeEnd,
+				},
+			},
+		}
+
+		for _, tt := range tests {
+			tc := tt
+			t.Run(tc.name, func(t *testing.T) {
+				m := &Module{
+					TypeSection:     []FunctionType{v_v},
+					FunctionSection: []Index{0},
+					CodeSection:     []Code{{Body: tc.body}},
+				}
+				err := m.validateFunction(&stacks{}, api.CoreFeaturesV2|experimental.CoreFeaturesThreads,
+					0, []Index{0}, nil, &Memory{IsShared: true}, nil, nil, bytes.NewReader(nil))
+				require.NoError(t, err)
+			})
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			body        []byte
+			flag        api.CoreFeatures
+			memory      *Memory
+			expectedErr string
+		}{
+			{
+				name: "threads disabled",
+				body: []byte{
+					OpcodeI32Const, 0x0,
+					OpcodeAtomicPrefix, OpcodeAtomicI32Load, 0x2, 0x0,
+					OpcodeDrop,
+					OpcodeEnd,
+				},
+				flag:        api.CoreFeaturesV2,
+				memory:      &Memory{},
+				expectedErr: `i32.atomic.load invalid as feature "threads" is disabled`,
+			},
+			{
+				name: "no memory",
+				body: []byte{
+					OpcodeI32Const, 0x0,
+					OpcodeI32Const, 0x1,
+					OpcodeAtomicPrefix, OpcodeAtomicMemoryNotify, 0x2, 0x0,
+					OpcodeDrop,
+					OpcodeEnd,
+				},
+				flag:        api.CoreFeaturesV2 | experimental.CoreFeaturesThreads,
+				expectedErr: "memory must exist for memory.atomic.notify",
+			},
+			{
+				name: "alignment less than natural",
+				body: []byte{
+					OpcodeI32Const, 0x0,
+					OpcodeAtomicPrefix, OpcodeAtomicI32Load, 0x1, 0x0,
+					OpcodeDrop,
+					OpcodeEnd,
+				},
+				flag:        api.CoreFeaturesV2 | experimental.CoreFeaturesThreads,
+				memory:      &Memory{},
+				expectedErr: "invalid memory alignment",
+			},
+			{
+				name: "operand type mismatch",
+				body: []byte{
+					OpcodeI32Const, 0x0,
+					OpcodeI32Const, 0x1,
+					OpcodeAtomicPrefix, OpcodeAtomicI64Store, 0x3, 0x0,
+					OpcodeEnd,
+				},
+				flag:        api.CoreFeaturesV2 | experimental.CoreFeaturesThreads,
+				memory:      &Memory{},
+				expectedErr: "cannot pop the operand for i64.atomic.store: type mismatch: expected i64, but was i32",
+			},
+			{
+				name: "fence non-zero immediate",
+				body: []byte{
+					OpcodeAtomicPrefix, OpcodeAtomicFence, 0x1,
+					OpcodeEnd,
+				},
+				flag:        api.CoreFeaturesV2 | experimental.CoreFeaturesThreads,
+				expectedErr: "invalid immediate value for atomic.fence",
+			},
+			{
+				name: "unknown opcode",
+				body: []byte{
+					OpcodeI32Const, 0x0,
+					OpcodeAtomicPrefix, 0x4f, 0x2, 0x0,
+					OpcodeEnd,
+				},
+				flag:        api.CoreFeaturesV2 | experimental.CoreFeaturesThreads,
+				memory:      &Memory{},
+				expectedErr: "invalid atomic opcode: 0x4f",
+			},
+			{
+				name: "unknown opcode disabled",
+				body: []byte{
+					OpcodeAtomicPrefix, 0x4f, 0x2, 0x0,
+					OpcodeEnd,
+				},
+				flag:        api.CoreFeaturesV2,
+				memory:      &Memory{},
+				expectedErr: `0x4f invalid as feature "threads" is disabled`,
+			},
+			{
+				name: "unknown opcode no memory",
+				body: []byte{
+					OpcodeAtomicPrefix, 0x4f, 0x2, 0x0,
+					OpcodeEnd,
+				},
+				flag:        api.CoreFeaturesV2 | experimental.CoreFeaturesThreads,
+				expectedErr: "memory must exist for 0x4f",
+			},
+		}
+
+		for _, tt := range tests {
+			tc := tt
+			t.Run(tc.name, func(t *testing.T) {
+				m := &Module{
+					TypeSection:     []FunctionType{v_v},
+					FunctionSection: []Index{0},
+					CodeSection:     []Code{{Body: tc.body}},
+				}
+				err := m.validateFunction(&stacks{}, tc.flag,
+					0, []Index{0}, nil, tc.memory, nil, nil, bytes.NewReader(nil))
+				require.EqualError(t, err, tc.expectedErr)
+			})
+		}
+	})
+}
+
+func TestModule_funcValidation_MemoryAlignment(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        []byte
+		expectedErr string
+	}{
+		{
+			name: "i32.load8_u natural alignment",
+			body: []byte{
+				OpcodeI32Const, 0,
+				OpcodeI32Load8U, 0x0, 0x0, // align=0 (1 byte)
+				OpcodeDrop,
+				OpcodeEnd,
+			},
+		},
+		{
+			name: "i32.load8_u over-aligned",
+			body: []byte{
+				OpcodeI32Const, 0,
+				OpcodeI32Load8U, 0x1, 0x0, // align=1 (2 bytes)
+				OpcodeDrop,
+				OpcodeEnd,
+			},
+			expectedErr: "invalid memory alignment",
+		},
+		{
+			name: "i64.store over-aligned",
+			body: []byte{
+				OpcodeI32Const, 0,
+				OpcodeI64Const, 0,
+				OpcodeI64Store, 0x4, 0x0, // align=4 (16 bytes)
+				OpcodeEnd,
+			},
+			expectedErr: "invalid memory alignment",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			m := &Module{
+				TypeSection:     []FunctionType{v_v},
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{{Body: tc.body}},
+			}
+			err := m.validateFunction(&stacks{}, api.CoreFeaturesV2,
+				0, []Index{0}, nil, &Memory{}, nil, nil, bytes.NewReader(nil))
+			if tc.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestModule_funcValidation_MemorySizeGrow_reservedByte(t *testing.T) {
+	tests := []struct {
+		name string
+		body []byte
+		// expectedMultiMemoryErr is the error when experimental.CoreFeaturesMultiMemory is enabled.
+		expectedMultiMemoryErr string
+	}{
+		{
+			name:                   "memory.size nonzero",
+			body:                   []byte{OpcodeMemorySize, 0x1, OpcodeDrop, OpcodeEnd},
+			expectedMultiMemoryErr: "memory index 1 out of range for memory.size",
+		},
+		{
+			name:                   "memory.grow nonzero",
+			body:                   []byte{OpcodeI32Const, 0, OpcodeMemoryGrow, 0x1, OpcodeDrop, OpcodeEnd},
+			expectedMultiMemoryErr: "memory index 1 out of range for memory.grow",
+		},
+		{
+			name: "memory.size zero in two bytes",
+			body: []byte{OpcodeMemorySize, 0x80, 0x00, OpcodeDrop, OpcodeEnd},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			m := &Module{
+				TypeSection:     []FunctionType{v_v},
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{{Body: tc.body}},
+			}
+			for _, features := range []api.CoreFeatures{api.CoreFeaturesV1, api.CoreFeaturesV2 | experimental.CoreFeaturesThreads} {
+				err := m.validateFunction(&stacks{}, features,
+					0, []Index{0}, nil, &Memory{}, nil, nil, bytes.NewReader(nil))
+				require.EqualError(t, err, "memory instruction reserved bytes not zero with 1 byte")
+			}
+
+			err := m.validateFunction(&stacks{}, api.CoreFeaturesV2|experimental.CoreFeaturesMultiMemory,
+				0, []Index{0}, nil, &Memory{}, nil, nil, bytes.NewReader(nil))
+			if tc.expectedMultiMemoryErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expectedMultiMemoryErr)
+			}
+		})
+	}
+}
+
+func TestModule_funcValidation_BrTable_inconsistentArity(t *testing.T) {
+	m := &Module{
+		TypeSection:     []FunctionType{v_v},
+		FunctionSection: []Index{0},
+		CodeSection: []Code{{Body: []byte{
+			OpcodeBlock, ValueTypeI32, // label 1 has one result
+			OpcodeBlock, 0x40, // label 0 has no results
+			OpcodeI32Const, 0,
+			OpcodeBrTable, 1, 1, 0, // case label 1, default label 0
+			OpcodeEnd,
+			OpcodeI32Const, 0,
+			OpcodeEnd,
+			OpcodeDrop,
+			OpcodeEnd,
+		}}},
+	}
+	err := m.validateFunction(&stacks{}, api.CoreFeaturesV2,
+		0, []Index{0}, nil, nil, nil, nil, bytes.NewReader(nil))
+	require.EqualError(t, err, "inconsistent block type length for br_table at 1; [] (ln=0) != [127] (l=1)")
+}
+
 func TestDecodeBlockType(t *testing.T) {
 	t.Run("primitive", func(t *testing.T) {
 		for _, tc := range []struct {
@@ -3379,6 +3944,49 @@ func TestDecodeBlockType(t *testing.T) {
 			require.Equal(t, expected, actual)
 		}
 	})
+	t.Run("multi-byte type index", func(t *testing.T) {
+		// 64 needs two bytes in signed LEB128, as a single 0x40 byte is -64: the empty block type.
+		types := make([]FunctionType, 65)
+		types[64] = FunctionType{Results: []ValueType{ValueTypeI64}}
+		actual, read, err := DecodeBlockType(types, bytes.NewReader([]byte{0xc0, 0x00}), api.CoreFeatureMultiValue)
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), read)
+		require.Equal(t, &types[64], actual)
+	})
+	t.Run("errors", func(t *testing.T) {
+		types := []FunctionType{{}}
+		for _, tc := range []struct {
+			name            string
+			in              []byte
+			enabledFeatures api.CoreFeatures
+			expectedErr     string
+		}{
+			{
+				name:            "type index without multi-value",
+				in:              []byte{0x00},
+				enabledFeatures: api.CoreFeaturesV1,
+				expectedErr:     "block with function type return invalid as feature \"multi-value\" is disabled",
+			},
+			{
+				name:            "type index out of range",
+				in:              []byte{0x01},
+				enabledFeatures: api.CoreFeaturesV2,
+				expectedErr:     "type index out of range: 1",
+			},
+			{
+				name:            "not a value type",
+				in:              []byte{0x60}, // func type prefix
+				enabledFeatures: api.CoreFeaturesV2,
+				expectedErr:     "invalid block type: -32",
+			},
+		} {
+			tc := tc
+			t.Run(tc.name, func(t *testing.T) {
+				_, _, err := DecodeBlockType(types, bytes.NewReader(tc.in), tc.enabledFeatures)
+				require.EqualError(t, err, tc.expectedErr)
+			})
+		}
+	})
 }
 
 // TestFuncValidation_UnreachableBrTable_NotModifyTypes ensures that we do not modify the
@@ -3576,6 +4184,49 @@ func TestModule_funcValidation_loopWithParams(t *testing.T) {
 	}
 }
 
+func TestFunctionValidation_unbalancedEnd(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        []byte
+		expectedErr string
+	}{
+		{
+			name:        "missing end of function",
+			body:        []byte{OpcodeBlock, 0x40, OpcodeEnd},
+			expectedErr: "missing end of function",
+		},
+		{
+			name:        "missing end of block",
+			body:        []byte{OpcodeBlock, 0x40, OpcodeLoop, 0x40, OpcodeEnd},
+			expectedErr: "missing end of block at 0x0",
+		},
+		{
+			name:        "missing end of loop",
+			body:        []byte{OpcodeLoop, 0x40, OpcodeBlock, 0x40, OpcodeEnd},
+			expectedErr: "missing end of loop at 0x0",
+		},
+		{
+			name:        "instruction after end of function",
+			body:        []byte{OpcodeEnd, OpcodeI32Const, OpcodeEnd},
+			expectedErr: "unexpected i32.const instruction after the end of the function at 0x1",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			m := &Module{
+				TypeSection:     []FunctionType{{}},
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{{Body: tc.body}},
+			}
+			err := m.validateFunction(&stacks{}, api.CoreFeaturesV2,
+				0, nil, nil, nil, nil, nil, bytes.NewReader(nil))
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
 // TestFunctionValidation_redundantEnd is found in th validation fuzzing #879.
 func TestFunctionValidation_redundantEnd(t *testing.T) {
 	m := &Module{
@@ -0,0 +1,84 @@
+package wasm
+
+import "fmt"
+
+// GasCostTable returns the cost of an instruction. subOp is the opcode following OpcodeMiscPrefix, OpcodeVecPrefix or
+// OpcodeAtomicPrefix, or zero for other instructions.
+type GasCostTable func(op Opcode, subOp uint32) uint64
+
+// CostTable maps an opcode to its cost, for metering policies that price some instructions, such as OpcodeMemoryGrow,
+// higher than others. Opcodes missing from the table cost one. Instructions after a prefix, such as OpcodeMiscPrefix,
+// are priced by the prefix.
+//
+// Use CostTable.Cost as the GasCostTable of EstimateGasCost.
+type CostTable map[Opcode]uint64
+
+// DefaultCostTable returns a new CostTable which charges one for every instruction. The result can be modified to
+// price some instructions differently, without affecting other callers.
+func DefaultCostTable() CostTable {
+	return CostTable{}
+}
+
+// Cost is a GasCostTable which returns the cost of op.
+func (t CostTable) Cost(op Opcode, _ uint32) uint64 {
+	if cost, ok := t[op]; ok {
+		return cost
+	}
+	return 1
+}
+
+// BasicBlockCost is the estimated cost of a basic block in a function body.
+type BasicBlockCost struct {
+	// Start is the offset in the body of the first instruction in the block.
+	Start uint64
+	// End is the offset in the body after the last instruction in the block.
+	End uint64
+	// Cost is the sum of the cost of each instruction in the block.
+	Cost uint64
+}
+
+// EstimateGasCost returns the cost of each basic block in the body of the function at funcIdx in order, according to
+// costs. This allows pricing a function before it executes, for example multiplying the cost of the blocks in a loop
+// by an expected count of iterations.
+//
+// A basic block ends after an instruction which may transfer control or is the target of a branch: OpcodeLoop,
+// OpcodeIf, OpcodeElse, OpcodeEnd, OpcodeBr, OpcodeBrIf, OpcodeBrTable, OpcodeReturn and OpcodeUnreachable. Calls
+// don't end a basic block as execution continues after them.
+//
+// Note: The module must have been validated.
+func (m *Module) EstimateGasCost(funcIdx Index, costs GasCostTable) ([]BasicBlockCost, error) {
+	if funcIdx < m.ImportFunctionCount {
+		return nil, fmt.Errorf("function[%d] is imported", funcIdx)
+	}
+	codeIdx := funcIdx - m.ImportFunctionCount
+	if codeIdx >= uint32(len(m.CodeSection)) {
+		return nil, fmt.Errorf("function[%d] out of range", funcIdx)
+	}
+	code := &m.CodeSection[codeIdx]
+	if code.GoFunc != nil {
+		return nil, fmt.Errorf("function[%d] is a host function", funcIdx)
+	}
+
+	var ret []BasicBlockCost
+	var current BasicBlockCost
+	var endBlock bool
+	_, err := walkInstructions(code.Body, func(op Opcode, subOp uint32, pc uint64) {
+		if endBlock { // The previous instruction ended a block, so this starts a new one.
+			current.End = pc
+			ret = append(ret, current)
+			current = BasicBlockCost{Start: pc}
+		}
+		current.Cost += costs(op, subOp)
+		switch op {
+		case OpcodeLoop, OpcodeIf, OpcodeElse, OpcodeEnd, OpcodeBr, OpcodeBrIf, OpcodeBrTable, OpcodeReturn, OpcodeUnreachable:
+			endBlock = true
+		default:
+			endBlock = false
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", m.funcDesc(SectionIDCode, codeIdx), err)
+	}
+	current.End = uint64(len(code.Body))
+	return append(ret, current), nil
+}
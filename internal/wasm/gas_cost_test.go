@@ -0,0 +1,116 @@
+package wasm
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestModule_EstimateGasCost(t *testing.T) {
+	// testCosts charges 10 per load, 3 for memory.fill and 1 per other instruction.
+	testCosts := func(op Opcode, subOp uint32) uint64 {
+		switch {
+		case OpcodeI32Load <= op && op <= OpcodeI64Load32U:
+			return 10
+		case op == OpcodeMiscPrefix && subOp == uint32(OpcodeMiscMemoryFill):
+			return 3
+		}
+		return 1
+	}
+
+	tests := []struct {
+		name     string
+		body     []byte
+		expected []BasicBlockCost
+	}{
+		{
+			name: "straight line",
+			body: []byte{
+				OpcodeI32Const, 0, // 1
+				OpcodeI32Load, 0x2, 0x0, // 10
+				OpcodeI32Const, 0, OpcodeI32Const, 0, OpcodeI32Const, 0, // 3
+				OpcodeMiscPrefix, OpcodeMiscMemoryFill, 0, // 3
+				OpcodeCall, 0, // 1
+				OpcodeEnd, // 1
+			},
+			expected: []BasicBlockCost{{Start: 0, End: 17, Cost: 19}},
+		},
+		{
+			name: "loop",
+			body: []byte{
+				OpcodeI32Const, 0, // 1
+				OpcodeDrop,       // 1
+				OpcodeLoop, 0x40, // 1
+				OpcodeI32Const, 0, // 1
+				OpcodeI32Load, 0x2, 0x0, // 10
+				OpcodeBrIf, 0, // 1
+				OpcodeEnd, // 1
+				OpcodeEnd, // 1
+			},
+			expected: []BasicBlockCost{
+				{Start: 0, End: 5, Cost: 3},
+				{Start: 5, End: 12, Cost: 12},
+				{Start: 12, End: 13, Cost: 1},
+				{Start: 13, End: 14, Cost: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			m := &Module{
+				TypeSection:         []FunctionType{v_v},
+				ImportFunctionCount: 1,
+				FunctionSection:     []Index{0},
+				CodeSection:         []Code{{Body: tc.body}},
+			}
+			actual, err := m.EstimateGasCost(1, testCosts)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestCostTable(t *testing.T) {
+	m := &Module{
+		TypeSection:     []FunctionType{v_v},
+		FunctionSection: []Index{0},
+		CodeSection: []Code{{Body: []byte{
+			OpcodeI32Const, 1, // 1
+			OpcodeMemoryGrow, 0, // 1 by default, 100 in the custom table
+			OpcodeDrop, // 1
+			OpcodeEnd,  // 1
+		}}},
+	}
+	const fuel = 10
+	custom := DefaultCostTable()
+	custom[OpcodeMemoryGrow] = 100
+
+	actual, err := m.EstimateGasCost(0, custom.Cost)
+	require.NoError(t, err)
+	require.Equal(t, []BasicBlockCost{{Start: 0, End: 6, Cost: 103}}, actual)
+	require.True(t, actual[0].Cost > fuel) // exhausts the fuel the default table fits in
+
+	// Modifying the custom table didn't affect the default one.
+	actual, err = m.EstimateGasCost(0, DefaultCostTable().Cost)
+	require.NoError(t, err)
+	require.Equal(t, []BasicBlockCost{{Start: 0, End: 6, Cost: 4}}, actual)
+	require.True(t, actual[0].Cost <= fuel)
+}
+
+func TestModule_EstimateGasCost_Errors(t *testing.T) {
+	m := &Module{
+		ImportFunctionCount: 1,
+		FunctionSection:     []Index{0},
+		CodeSection:         []Code{{GoFunc: func() {}}},
+	}
+	costs := func(Opcode, uint32) uint64 { return 1 }
+
+	_, err := m.EstimateGasCost(0, costs)
+	require.EqualError(t, err, "function[0] is imported")
+	_, err = m.EstimateGasCost(1, costs)
+	require.EqualError(t, err, "function[1] is a host function")
+	_, err = m.EstimateGasCost(2, costs)
+	require.EqualError(t, err, "function[2] out of range")
+}
@@ -123,7 +123,15 @@ func callGoFunc(ctx context.Context, mod api.Module, fn *reflect.Value, stack []
 	}
 
 	// Execute the host function and push back the call result onto the stack.
-	for i, ret := range fn.Call(in) {
+	rets := fn.Call(in)
+	if rLen := len(rets); rLen > 0 && tp.Out(rLen-1) == errorType {
+		// A non-nil error traps, as documented on parseGoReflectFunc.
+		if err := rets[rLen-1]; !err.IsNil() {
+			panic(err.Interface())
+		}
+		rets = rets[:rLen-1]
+	}
+	for i, ret := range rets {
 		switch ret.Kind() {
 		case reflect.Float32:
 			stack[i] = uint64(math.Float32bits(float32(ret.Float())))
@@ -163,6 +171,8 @@ func MustParseGoReflectFuncCode(fn interface{}) Code {
 	return code
 }
 
+// parseGoReflectFunc derives the function type of fn, and Code which calls it via reflection. When the last result of fn
+// is an error, it isn't included in results. Instead, the call panics with the error when it is non-nil, which traps.
 func parseGoReflectFunc(fn interface{}) (params, results []ValueType, code Code, err error) {
 	fnV := reflect.ValueOf(fn)
 	p := fnV.Type()
@@ -215,6 +225,9 @@ func parseGoReflectFunc(fn interface{}) (params, results []ValueType, code Code,
 	}
 
 	rCount := p.NumOut()
+	if rCount > 0 && p.Out(rCount-1) == errorType {
+		rCount-- // The last result may be an error, which traps when non-nil instead of being a result.
+	}
 	if rCount > 0 {
 		results = make([]ValueType, rCount)
 	}
@@ -227,7 +240,7 @@ func parseGoReflectFunc(fn interface{}) (params, results []ValueType, code Code,
 
 		// Now, we will definitely err, decide which message is best
 		if rI.Implements(errorType) {
-			err = fmt.Errorf("result[%d] is an error, which is unsupported", i)
+			err = fmt.Errorf("result[%d] is an error, which is only supported as the last result", i)
 		} else {
 			err = fmt.Errorf("result[%d] is unsupported: %s", i, rI.Kind())
 		}
@@ -2,6 +2,7 @@ package wasm
 
 import (
 	"context"
+	"errors"
 	"math"
 	"testing"
 	"unsafe"
@@ -52,6 +53,16 @@ func Test_parseGoFunc(t *testing.T) {
 			expectNeedsModule: true,
 			expectedType:      &FunctionType{Params: []ValueType{i32, i64, f32, f64, externref}, Results: []ValueType{i32}},
 		},
+		{
+			name:         "error result",
+			input:        func() error { return nil },
+			expectedType: &FunctionType{},
+		},
+		{
+			name:         "multiple results and error",
+			input:        func(uint32) (uint32, uint32, error) { return 0, 0, nil },
+			expectedType: &FunctionType{Params: []ValueType{i32}, Results: []ValueType{i32, i32}},
+		},
 	}
 	for _, tt := range tests {
 		tc := tt
@@ -93,9 +104,9 @@ func Test_parseGoFunc_Errors(t *testing.T) {
 			expectedErr: "result[0] is unsupported: string",
 		},
 		{
-			name:        "error result",
-			input:       func() error { return nil },
-			expectedErr: "result[0] is an error, which is unsupported",
+			name:        "error result not last",
+			input:       func() (error, uint32) { return nil, 0 },
+			expectedErr: "result[0] is an error, which is only supported as the last result",
 		},
 		{
 			name:        "incorrect order",
@@ -210,6 +221,14 @@ func Test_callGoFunc(t *testing.T) {
 			},
 			expectedResults: []uint64{100},
 		},
+		{
+			name: "multiple results and nil error",
+			input: func(x, y uint32) (uint32, uint32, error) {
+				return y, x, nil
+			},
+			inputParams:     []uint64{1, 2},
+			expectedResults: []uint64{2, 1},
+		},
 	}
 	for _, tt := range tests {
 		tc := tt
@@ -243,3 +262,25 @@ func Test_callGoFunc(t *testing.T) {
 		})
 	}
 }
+
+func Test_callGoFunc_error(t *testing.T) {
+	expectedErr := errors.New("division by zero")
+	_, _, code, err := parseGoReflectFunc(func(x, y uint32) (uint32, error) {
+		if y == 0 {
+			return 0, expectedErr
+		}
+		return x / y, nil
+	})
+	require.NoError(t, err)
+
+	stack := []uint64{4, 2}
+	code.GoFunc.(api.GoFunction).Call(testCtx, stack)
+	require.Equal(t, uint64(2), stack[0])
+
+	// A non-nil error panics, so that the engine traps.
+	stack = []uint64{4, 0}
+	err = require.CapturePanic(func() {
+		code.GoFunc.(api.GoFunction).Call(testCtx, stack)
+	})
+	require.Equal(t, expectedErr, err)
+}
@@ -1,10 +1,12 @@
 package wasm
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/wasmdebug"
 )
 
@@ -44,6 +46,18 @@ func (f *HostFunc) WithGoModuleFunc(fn api.GoModuleFunc) *HostFunc {
 	return &ret
 }
 
+// HostGlobal is a global with an initial value, used for AddHostGlobals.
+type HostGlobal struct {
+	// ExportName is the name modules import this global with.
+	ExportName string
+
+	// Type is the type of the global. Only numeric value types are supported.
+	Type GlobalType
+
+	// Value is the initial value of the global, encoded as documented on api.ValueType.
+	Value uint64
+}
+
 // NewHostModule is defined internally for use in WASI tests and to keep the code size in the root directory small.
 func NewHostModule(
 	moduleName string,
@@ -177,3 +191,45 @@ func (m *Module) maybeAddType(params, results []ValueType, enabledFeatures api.C
 	m.TypeSection = append(m.TypeSection, FunctionType{Params: params, Results: results})
 	return result, nil
 }
+
+// AddHostGlobals adds and exports globals in a module returned by NewHostModule, in order of exportNames.
+func AddHostGlobals(m *Module, exportNames []string, nameToHostGlobal map[string]*HostGlobal) error {
+	if len(exportNames) == 0 {
+		return nil
+	}
+	moduleName := m.NameSection.ModuleName
+
+	m.GlobalSection = make([]Global, 0, len(exportNames))
+	for _, name := range exportNames {
+		hg := nameToHostGlobal[name]
+		if _, ok := m.Exports[hg.ExportName]; ok {
+			return fmt.Errorf("global[%s.%s] export name already used by a function", moduleName, name)
+		}
+
+		var init ConstantExpression
+		switch hg.Type.ValType {
+		case ValueTypeI32:
+			init = ConstantExpression{Opcode: OpcodeI32Const, Data: leb128.EncodeInt32(int32(hg.Value))}
+		case ValueTypeI64:
+			init = ConstantExpression{Opcode: OpcodeI64Const, Data: leb128.EncodeInt64(int64(hg.Value))}
+		case ValueTypeF32:
+			init = ConstantExpression{Opcode: OpcodeF32Const, Data: binary.LittleEndian.AppendUint32(nil, uint32(hg.Value))}
+		case ValueTypeF64:
+			init = ConstantExpression{Opcode: OpcodeF64Const, Data: binary.LittleEndian.AppendUint64(nil, hg.Value)}
+		default:
+			return fmt.Errorf("global[%s.%s] unsupported type %s", moduleName, name, ValueTypeName(hg.Type.ValType))
+		}
+
+		idx := Index(len(m.GlobalSection))
+		m.GlobalSection = append(m.GlobalSection, Global{Type: hg.Type, Init: init})
+		m.ExportSection = append(m.ExportSection, Export{Type: ExternTypeGlobal, Name: hg.ExportName, Index: idx})
+	}
+
+	// Rebuild the exports as appending may have moved ExportSection.
+	m.Exports = make(map[string]*Export, len(m.ExportSection))
+	for i := range m.ExportSection {
+		exp := &m.ExportSection[i]
+		m.Exports[exp.Name] = exp
+	}
+	return nil
+}
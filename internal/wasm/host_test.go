@@ -184,3 +184,77 @@ func TestNewHostModule_Errors(t *testing.T) {
 		})
 	}
 }
+
+func TestAddHostGlobals(t *testing.T) {
+	m, err := NewHostModule("env", []string{"fn"}, map[string]*HostFunc{
+		"fn": {ExportName: "fn", Code: Code{GoFunc: func() {}}},
+	}, api.CoreFeaturesV2)
+	require.NoError(t, err)
+
+	err = AddHostGlobals(m, []string{"counter", "pi"}, map[string]*HostGlobal{
+		"counter": {ExportName: "counter", Type: GlobalType{ValType: ValueTypeI32, Mutable: true}, Value: 0xffffffff},
+		"pi":      {ExportName: "pi", Type: GlobalType{ValType: ValueTypeF64}, Value: api.EncodeF64(3.14)},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, []Global{
+		{
+			Type: GlobalType{ValType: ValueTypeI32, Mutable: true},
+			Init: ConstantExpression{Opcode: OpcodeI32Const, Data: []byte{0x7f}}, // -1
+		},
+		{
+			Type: GlobalType{ValType: ValueTypeF64},
+			Init: ConstantExpression{Opcode: OpcodeF64Const, Data: []byte{0x1f, 0x85, 0xeb, 0x51, 0xb8, 0x1e, 0x09, 0x40}},
+		},
+	}, m.GlobalSection)
+	require.Equal(t, []Export{
+		{Type: ExternTypeFunc, Name: "fn", Index: 0},
+		{Type: ExternTypeGlobal, Name: "counter", Index: 0},
+		{Type: ExternTypeGlobal, Name: "pi", Index: 1},
+	}, m.ExportSection)
+	for i := range m.ExportSection {
+		exp := &m.ExportSection[i]
+		require.Equal(t, exp, m.Exports[exp.Name])
+	}
+	require.NoError(t, m.Validate(api.CoreFeaturesV2))
+}
+
+func TestAddHostGlobals_Errors(t *testing.T) {
+	tests := []struct {
+		name             string
+		exportNames      []string
+		nameToHostGlobal map[string]*HostGlobal
+		expectedErr      string
+	}{
+		{
+			name:        "name used by function",
+			exportNames: []string{"fn"},
+			nameToHostGlobal: map[string]*HostGlobal{
+				"fn": {ExportName: "fn", Type: GlobalType{ValType: ValueTypeI32}},
+			},
+			expectedErr: "global[env.fn] export name already used by a function",
+		},
+		{
+			name:        "unsupported type",
+			exportNames: []string{"ref"},
+			nameToHostGlobal: map[string]*HostGlobal{
+				"ref": {ExportName: "ref", Type: GlobalType{ValType: ValueTypeExternref}},
+			},
+			expectedErr: "global[env.ref] unsupported type externref",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := NewHostModule("env", []string{"fn"}, map[string]*HostFunc{
+				"fn": {ExportName: "fn", Code: Code{GoFunc: func() {}}},
+			}, api.CoreFeaturesV2)
+			require.NoError(t, err)
+
+			err = AddHostGlobals(m, tc.exportNames, tc.nameToHostGlobal)
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package wasm
+
+import "fmt"
+
+// InstructionCounts returns the count of each instruction in the bodies of all functions defined in the module, keyed
+// by its name, such as OpcodeI32AddName. Instructions with a prefix are keyed by the name of their sub-opcode, for
+// example OpcodeMemoryCopyName. Host functions are skipped.
+//
+// This is useful to understand what a module does before running it, for example to decide which engine to use.
+//
+// Note: The module must have been validated.
+func (m *Module) InstructionCounts() (map[string]uint64, error) {
+	ret := map[string]uint64{}
+	visit := func(op Opcode, subOp uint32, _ uint64) {
+		ret[instructionCountName(op, subOp)]++
+	}
+	for codeIdx := range m.CodeSection {
+		code := &m.CodeSection[codeIdx]
+		if code.GoFunc != nil {
+			continue
+		}
+		if _, err := walkInstructions(code.Body, visit); err != nil {
+			return nil, fmt.Errorf("%s: %w", m.funcDesc(SectionIDCode, Index(codeIdx)), err)
+		}
+	}
+	return ret, nil
+}
+
+// instructionCountName returns the name of the instruction op, or of subOp when op is a prefix.
+func instructionCountName(op Opcode, subOp uint32) string {
+	switch op {
+	case OpcodeMiscPrefix:
+		return MiscInstructionName(OpcodeMisc(subOp))
+	case OpcodeVecPrefix:
+		return VectorInstructionName(OpcodeVec(subOp))
+	case OpcodeAtomicPrefix:
+		return AtomicInstructionName(OpcodeAtomic(subOp))
+	default:
+		return InstructionName(op)
+	}
+}
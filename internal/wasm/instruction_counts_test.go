@@ -0,0 +1,76 @@
+package wasm
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestModule_InstructionCounts(t *testing.T) {
+	m := &Module{
+		TypeSection:     []FunctionType{i32_i32, v_v},
+		FunctionSection: []Index{0, 1},
+		MemorySection:   &Memory{Min: 1},
+		CodeSection: []Code{
+			{Body: []byte{
+				OpcodeLocalGet, 0, OpcodeLocalGet, 0, OpcodeI32Add,
+				OpcodeLocalGet, 0, OpcodeI32Add,
+				OpcodeEnd,
+			}},
+			{Body: []byte{
+				OpcodeI32Const, 1, OpcodeI32Const, 2, OpcodeI32Add, OpcodeDrop,
+				OpcodeI32Const, 0, OpcodeI32Const, 0, OpcodeI32Const, 0,
+				OpcodeMiscPrefix, OpcodeMiscMemoryCopy, 0, 0,
+				OpcodeEnd,
+			}},
+			{GoFunc: func() {}}, // host functions are skipped.
+		},
+	}
+
+	counts, err := m.InstructionCounts()
+	require.NoError(t, err)
+	require.Equal(t, map[string]uint64{
+		OpcodeLocalGetName:   3,
+		OpcodeI32AddName:     3,
+		OpcodeI32ConstName:   5,
+		OpcodeDropName:       1,
+		OpcodeMemoryCopyName: 1,
+		OpcodeEndName:        2,
+	}, counts)
+}
+
+func TestModule_InstructionCounts_Errors(t *testing.T) {
+	tests := []struct {
+		name, expectedErr string
+		body              []byte
+	}{
+		{
+			name:        "truncated immediate",
+			body:        []byte{OpcodeI32Const},
+			expectedErr: "code[0]: read immediates of i32.const: readByte failed: EOF",
+		},
+		{
+			name:        "vector prefix without an opcode",
+			body:        []byte{OpcodeVecPrefix},
+			expectedErr: "code[0]: read immediates of vector_prefix: unexpected EOF",
+		},
+		{
+			name:        "atomic prefix without an opcode",
+			body:        []byte{OpcodeAtomicPrefix},
+			expectedErr: "code[0]: read immediates of atomic_prefix: unexpected EOF",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			m := &Module{
+				TypeSection:     []FunctionType{v_v},
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{{Body: tc.body}},
+			}
+			_, err := m.InstructionCounts()
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
@@ -1,10 +1,12 @@
 package wasm
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"math"
 	"reflect"
+	"sync/atomic"
 	"unsafe"
 
 	"github.com/tetratelabs/wazero/api"
@@ -38,6 +40,14 @@ type MemoryInstance struct {
 	Min, Cap, Max uint32
 	// definition is known at compile time.
 	definition api.MemoryDefinition
+	// budget is non-nil when Store.SetMemoryBudget was in effect when this memory was created, and doesn't change
+	// after.
+	budget *memoryBudget
+	// budgetPages are the pages reserved from budget and not yet returned to it. This is updated atomically, as Grow
+	// may run concurrently with closing the module.
+	budgetPages atomic.Uint32
+	// budgetReleased is set once the pages were returned to budget, after which Grow returns pages right away.
+	budgetReleased atomic.Bool
 }
 
 // NewMemoryInstance creates a new instance based on the parameters in the SectionIDMemory.
@@ -114,6 +124,35 @@ func (m *MemoryInstance) Read(offset, byteCount uint32) ([]byte, bool) {
 	return m.Buffer[offset : offset+byteCount : offset+byteCount], true
 }
 
+// ReadString returns a copy of byteLen bytes at the offset as a string, or false if out of range.
+func (m *MemoryInstance) ReadString(offset, byteLen uint32) (string, bool) {
+	b, ok := m.Read(offset, byteLen)
+	if !ok {
+		return "", false
+	}
+	return string(b), true
+}
+
+// ReadNullTerminatedString returns a copy of the bytes at the offset up to, but not including, the first NUL byte.
+//
+// At most maxLen bytes are scanned for the terminator, so a missing one doesn't result in reading the rest of the
+// memory. This returns false if the offset is out of range or no NUL byte is found within maxLen bytes.
+func (m *MemoryInstance) ReadNullTerminatedString(offset, maxLen uint32) (string, bool) {
+	size := m.size()
+	if offset >= size {
+		return "", false
+	}
+	end := uint64(offset) + uint64(maxLen)
+	if end > uint64(size) {
+		end = uint64(size)
+	}
+	n := bytes.IndexByte(m.Buffer[offset:end], 0)
+	if n < 0 {
+		return "", false
+	}
+	return string(m.Buffer[offset : offset+uint32(n)]), true
+}
+
 // WriteByte implements the same method as documented on api.Memory.
 func (m *MemoryInstance) WriteByte(offset uint32, v byte) bool {
 	if offset >= m.size() {
@@ -161,6 +200,24 @@ func (m *MemoryInstance) Write(offset uint32, val []byte) bool {
 	return true
 }
 
+// WriteGrowing is like Write, except memory is grown to fit the write when it would otherwise be out of range. An error
+// is returned if that would exceed the maximum pages, in which case nothing is written.
+func (m *MemoryInstance) WriteGrowing(offset uint32, val []byte) error {
+	end := uint64(offset) + uint64(len(val)) // uint64 prevents overflow on add
+	if end > uint64(len(m.Buffer)) {
+		neededPages := (end + uint64(MemoryPageSize) - 1) >> MemoryPageSizeInBits
+		if neededPages > uint64(m.Max) {
+			return fmt.Errorf("write of %d bytes at offset %d needs %d pages, but the maximum is %d",
+				len(val), offset, neededPages, m.Max)
+		}
+		if _, ok := m.Grow(uint32(neededPages) - m.PageSize()); !ok {
+			return fmt.Errorf("failed to grow memory to %d pages", neededPages)
+		}
+	}
+	copy(m.Buffer[offset:], val)
+	return nil
+}
+
 // WriteString implements the same method as documented on api.Memory.
 func (m *MemoryInstance) WriteString(offset uint32, val string) bool {
 	if !m.hasSize(offset, uint64(len(val))) {
@@ -170,6 +227,22 @@ func (m *MemoryInstance) WriteString(offset uint32, val string) bool {
 	return true
 }
 
+// Snapshot returns a copy of the current contents of memory, which can later be passed to Restore to re-run a function
+// from the same state without re-instantiating the module.
+func (m *MemoryInstance) Snapshot() []byte {
+	return append([]byte(nil), m.Buffer...)
+}
+
+// Restore overwrites memory with a snapshot previously returned by Snapshot. An error is returned if the snapshot
+// size differs from the current memory size, e.g. because memory grew after the snapshot was taken.
+func (m *MemoryInstance) Restore(snapshot []byte) error {
+	if len(snapshot) != len(m.Buffer) {
+		return fmt.Errorf("snapshot size %d does not match memory size %d", len(snapshot), len(m.Buffer))
+	}
+	copy(m.Buffer, snapshot)
+	return nil
+}
+
 // MemoryPagesToBytesNum converts the given pages into the number of bytes contained in these pages.
 func MemoryPagesToBytesNum(pages uint32) (bytesNum uint64) {
 	return uint64(pages) << MemoryPageSizeInBits
@@ -186,6 +259,8 @@ func (m *MemoryInstance) Grow(delta uint32) (result uint32, ok bool) {
 	newPages := currentPages + delta
 	if newPages > m.Max {
 		return 0, false
+	} else if m.budget != nil && !m.reserveMemoryBudget(delta) {
+		return 0, false
 	} else if newPages > m.Cap { // grow the memory.
 		m.Buffer = append(m.Buffer, make([]byte, MemoryPagesToBytesNum(delta))...)
 		m.Cap = newPages
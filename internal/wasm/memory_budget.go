@@ -0,0 +1,79 @@
+package wasm
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// memoryBudget limits the sum of the pages of all MemoryInstance sharing it, which are the memories defined by modules
+// in the same Store.
+//
+// See Store.SetMemoryBudget
+type memoryBudget struct {
+	// limitPages is the maximum sum of pages.
+	limitPages uint64
+	// usedPages is the current sum of pages, updated atomically as modules sharing a budget run concurrently.
+	usedPages atomic.Uint64
+}
+
+// reserve adds pages to the used ones, unless that would exceed limitPages, in which case this returns false.
+func (b *memoryBudget) reserve(pages uint32) bool {
+	for {
+		used := b.usedPages.Load()
+		next := used + uint64(pages)
+		if next > b.limitPages {
+			return false
+		}
+		if b.usedPages.CompareAndSwap(used, next) {
+			return true
+		}
+	}
+}
+
+// release subtracts pages previously added by reserve.
+func (b *memoryBudget) release(pages uint32) {
+	b.usedPages.Add(^(uint64(pages) - 1))
+}
+
+// errorExhausted is returned when the minimum pages of a memory don't fit into the remaining budget.
+func (b *memoryBudget) errorExhausted(pages uint32) error {
+	return fmt.Errorf("memory budget exhausted: %d pages requested, but %d of %d pages are in use",
+		pages, b.usedPages.Load(), b.limitPages)
+}
+
+// SetMemoryBudget limits the sum of the memory pages of all modules instantiated after this call, including growth.
+// Instantiating a module whose minimum memory pages exceed the remaining budget fails, and once exhausted,
+// memory.grow returns -1. Pages are returned to the budget when the module defining the memory is closed.
+//
+// Zero removes the limit for modules instantiated after this call.
+func (s *Store) SetMemoryBudget(pages uint32) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if pages == 0 {
+		s.memoryBudget = nil
+	} else {
+		s.memoryBudget = &memoryBudget{limitPages: uint64(pages)}
+	}
+}
+
+// reserveMemoryBudget reserves pages for Grow from the budget of this memory, unless they don't fit.
+func (m *MemoryInstance) reserveMemoryBudget(pages uint32) bool {
+	if !m.budget.reserve(pages) {
+		return false
+	}
+	m.budgetPages.Add(pages)
+	// If the memory was released concurrently, the pages may have been added too late to be returned by it.
+	if m.budgetReleased.Load() {
+		m.budget.release(m.budgetPages.Swap(0))
+	}
+	return true
+}
+
+// releaseMemoryBudget returns the pages of this memory to its budget, if any. This is safe to call concurrently
+// with Grow, and more than once.
+func (m *MemoryInstance) releaseMemoryBudget() {
+	if m.budget != nil {
+		m.budgetReleased.Store(true)
+		m.budget.release(m.budgetPages.Swap(0))
+	}
+}
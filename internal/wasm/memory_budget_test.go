@@ -0,0 +1,226 @@
+package wasm
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestStore_SetMemoryBudget(t *testing.T) {
+	tests := []struct {
+		name string
+		// budget is the argument to Store.SetMemoryBudget.
+		budget uint32
+		// inUsePages are the pages of another module instantiated first.
+		inUsePages     uint32
+		input          *Module
+		grow           uint32
+		expectedErr    string
+		expectedGrowOK bool
+	}{
+		{
+			name:           "minimum and growth fit",
+			budget:         5,
+			inUsePages:     2,
+			input:          &Module{MemorySection: &Memory{Min: 2, Cap: 2, Max: 10}, MemoryDefinitionSection: []MemoryDefinition{{}}},
+			grow:           1,
+			expectedGrowOK: true,
+		},
+		{
+			name:       "growth exceeds budget",
+			budget:     5,
+			inUsePages: 2,
+			input:      &Module{MemorySection: &Memory{Min: 2, Cap: 2, Max: 10}, MemoryDefinitionSection: []MemoryDefinition{{}}},
+			grow:       2,
+		},
+		{
+			name:           "growth by zero pages when exhausted",
+			budget:         2,
+			input:          &Module{MemorySection: &Memory{Min: 2, Cap: 2, Max: 10}, MemoryDefinitionSection: []MemoryDefinition{{}}},
+			expectedGrowOK: true,
+		},
+		{
+			name:        "minimum exceeds budget",
+			budget:      5,
+			inUsePages:  4,
+			input:       &Module{MemorySection: &Memory{Min: 2, Cap: 2, Max: 10}, MemoryDefinitionSection: []MemoryDefinition{{}}},
+			expectedErr: "memory budget exhausted: 2 pages requested, but 4 of 5 pages are in use",
+		},
+		{
+			name:           "no budget",
+			inUsePages:     4,
+			input:          &Module{MemorySection: &Memory{Min: 2, Cap: 2, Max: 10}, MemoryDefinitionSection: []MemoryDefinition{{}}},
+			grow:           8,
+			expectedGrowOK: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			s := newStore()
+			s.SetMemoryBudget(tc.budget)
+			if tc.inUsePages > 0 {
+				_, err := s.Instantiate(testCtx, &Module{
+					MemorySection:           &Memory{Min: tc.inUsePages, Cap: tc.inUsePages, Max: tc.inUsePages},
+					MemoryDefinitionSection: []MemoryDefinition{{}},
+				}, "in-use", nil, nil)
+				require.NoError(t, err)
+			}
+
+			mod, err := s.Instantiate(testCtx, tc.input, "mod", nil, nil)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			res, ok := mod.MemoryInstance.Grow(tc.grow)
+			require.Equal(t, tc.expectedGrowOK, ok)
+			if ok {
+				require.Equal(t, tc.input.MemorySection.Min, res)
+			}
+		})
+	}
+}
+
+func TestStore_SetMemoryBudget_Close(t *testing.T) {
+	s := newStore()
+	s.SetMemoryBudget(3)
+
+	mod, err := s.Instantiate(testCtx, &Module{
+		MemorySection:           &Memory{Min: 2, Cap: 2, Max: 10},
+		MemoryDefinitionSection: []MemoryDefinition{{}},
+	}, "mod", nil, nil)
+	require.NoError(t, err)
+	_, ok := mod.MemoryInstance.Grow(1)
+	require.True(t, ok)
+
+	_, err = s.Instantiate(testCtx, &Module{
+		MemorySection:           &Memory{Min: 1, Cap: 1, Max: 10},
+		MemoryDefinitionSection: []MemoryDefinition{{}},
+	}, "other", nil, nil)
+	require.EqualError(t, err, "memory budget exhausted: 1 pages requested, but 3 of 3 pages are in use")
+
+	// Closing a module returns its pages, including grown ones, to the budget.
+	require.NoError(t, mod.Close(testCtx))
+	_, err = s.Instantiate(testCtx, &Module{
+		MemorySection:           &Memory{Min: 3, Cap: 3, Max: 10},
+		MemoryDefinitionSection: []MemoryDefinition{{}},
+	}, "other", nil, nil)
+	require.NoError(t, err)
+}
+
+func TestStore_SetMemoryBudget_Remove(t *testing.T) {
+	s := newStore()
+	s.SetMemoryBudget(1)
+
+	limited, err := s.Instantiate(testCtx, &Module{
+		MemorySection:           &Memory{Min: 1, Cap: 1, Max: 10},
+		MemoryDefinitionSection: []MemoryDefinition{{}},
+	}, "limited", nil, nil)
+	require.NoError(t, err)
+
+	// Removing the budget only affects modules instantiated afterwards.
+	s.SetMemoryBudget(0)
+	unlimited, err := s.Instantiate(testCtx, &Module{
+		MemorySection:           &Memory{Min: 1, Cap: 1, Max: 10},
+		MemoryDefinitionSection: []MemoryDefinition{{}},
+	}, "unlimited", nil, nil)
+	require.NoError(t, err)
+
+	_, ok := unlimited.MemoryInstance.Grow(8)
+	require.True(t, ok)
+	_, ok = limited.MemoryInstance.Grow(1)
+	require.False(t, ok)
+}
+
+func TestStore_SetMemoryBudget_Import(t *testing.T) {
+	s := newStore()
+	s.SetMemoryBudget(1)
+
+	exporter, err := s.Instantiate(testCtx, &Module{
+		MemorySection:           &Memory{Min: 1, Cap: 1, Max: 1},
+		MemoryDefinitionSection: []MemoryDefinition{{}},
+		Exports:                 map[string]*Export{"memory": {Type: ExternTypeMemory, Name: "memory"}},
+	}, "exporter", nil, nil)
+	require.NoError(t, err)
+
+	// Importing a memory doesn't use the budget.
+	importer, err := s.Instantiate(testCtx, &Module{
+		ImportMemoryCount: 1,
+		ImportSection:     []Import{{Type: ExternTypeMemory, Module: "exporter", Name: "memory", DescMem: &Memory{Min: 1, Max: 1}}},
+		ImportPerModule: map[string][]*Import{
+			"exporter": {{Type: ExternTypeMemory, Module: "exporter", Name: "memory", DescMem: &Memory{Min: 1, Max: 1}}},
+		},
+	}, "importer", nil, nil)
+	require.NoError(t, err)
+
+	// Closing the importer doesn't return the memory's pages to the budget.
+	require.NoError(t, importer.Close(testCtx))
+	_, err = s.Instantiate(testCtx, &Module{
+		MemorySection:           &Memory{Min: 1, Cap: 1, Max: 1},
+		MemoryDefinitionSection: []MemoryDefinition{{}},
+	}, "other", nil, nil)
+	require.EqualError(t, err, "memory budget exhausted: 1 pages requested, but 1 of 1 pages are in use")
+
+	// Closing the exporter does.
+	require.NoError(t, exporter.Close(testCtx))
+	_, err = s.Instantiate(testCtx, &Module{
+		MemorySection:           &Memory{Min: 1, Cap: 1, Max: 1},
+		MemoryDefinitionSection: []MemoryDefinition{{}},
+	}, "other", nil, nil)
+	require.NoError(t, err)
+}
+
+func TestStore_SetMemoryBudget_InstantiationFailure(t *testing.T) {
+	s := newStore()
+	s.SetMemoryBudget(1)
+
+	// The data segment is out of bounds, so instantiation fails after the memory was built.
+	_, err := s.Instantiate(testCtx, &Module{
+		MemorySection:           &Memory{Min: 1, Cap: 1, Max: 1},
+		MemoryDefinitionSection: []MemoryDefinition{{}},
+		DataSection: []DataSegment{
+			{OffsetExpression: ConstantExpression{Opcode: OpcodeI32Const, Data: leb128.EncodeInt32(int32(MemoryPageSize))}, Init: []byte{1}},
+		},
+	}, "fail", nil, nil)
+	require.Error(t, err)
+
+	// The pages of the failed module were returned to the budget.
+	_, err = s.Instantiate(testCtx, &Module{
+		MemorySection:           &Memory{Min: 1, Cap: 1, Max: 1},
+		MemoryDefinitionSection: []MemoryDefinition{{}},
+	}, "ok", nil, nil)
+	require.NoError(t, err)
+}
+
+// TestStore_SetMemoryBudget_CloseDuringGrow ensures closing a module while its memory grows returns every page to the
+// budget. Run with -race to check the budget isn't accessed unsafely.
+func TestStore_SetMemoryBudget_CloseDuringGrow(t *testing.T) {
+	s := newStore()
+	s.SetMemoryBudget(1000)
+	budget := s.memoryBudget
+
+	for i := 0; i < 10; i++ {
+		mod, err := s.Instantiate(testCtx, &Module{
+			MemorySection:           &Memory{Min: 1, Cap: 1, Max: 1000},
+			MemoryDefinitionSection: []MemoryDefinition{{}},
+		}, "mod", nil, nil)
+		require.NoError(t, err)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for j := 0; j < 100; j++ {
+				mod.MemoryInstance.Grow(1)
+			}
+		}()
+		require.NoError(t, mod.Close(testCtx))
+		<-done
+
+		require.Equal(t, uint64(0), budget.usedPages.Load())
+	}
+}
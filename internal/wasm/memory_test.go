@@ -465,6 +465,49 @@ func TestMemoryInstance_Read(t *testing.T) {
 	require.False(t, ok)
 }
 
+func TestMemoryInstance_ReadString(t *testing.T) {
+	mem := &MemoryInstance{Buffer: []byte{'h', 'i', '!', 0}, Min: 1}
+
+	s, ok := mem.ReadString(0, 3)
+	require.True(t, ok)
+	require.Equal(t, "hi!", s)
+
+	// Ensure the result is a copy
+	mem.Buffer[0] = 'H'
+	require.Equal(t, "hi!", s)
+
+	_, ok = mem.ReadString(2, 3)
+	require.False(t, ok)
+}
+
+func TestMemoryInstance_ReadNullTerminatedString(t *testing.T) {
+	mem := &MemoryInstance{Buffer: []byte{'h', 'i', 0, 'w', 'a', 's', 'm'}, Min: 1}
+
+	tests := []struct {
+		name           string
+		offset, maxLen uint32
+		expected       string
+		expectedOk     bool
+	}{
+		{name: "string", offset: 0, maxLen: 10, expected: "hi", expectedOk: true},
+		{name: "empty string", offset: 2, maxLen: 10, expected: "", expectedOk: true},
+		{name: "terminator at maxLen", offset: 0, maxLen: 3, expected: "hi", expectedOk: true},
+		{name: "no terminator within maxLen", offset: 0, maxLen: 2},
+		{name: "no terminator before end of memory", offset: 3, maxLen: 10},
+		{name: "offset out of range", offset: 7, maxLen: 10},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			s, ok := mem.ReadNullTerminatedString(tc.offset, tc.maxLen)
+			require.Equal(t, tc.expectedOk, ok)
+			require.Equal(t, tc.expected, s)
+		})
+	}
+}
+
 func TestMemoryInstance_WriteUint16Le(t *testing.T) {
 	memory := &MemoryInstance{Buffer: make([]byte, 100)}
 
@@ -737,6 +780,58 @@ func TestMemoryInstance_Write(t *testing.T) {
 	require.False(t, ok)
 }
 
+func TestMemoryInstance_WriteGrowing(t *testing.T) {
+	mem := NewMemoryInstance(&Memory{Min: 1, Cap: 1, Max: 2})
+	buf := []byte{1, 2, 3, 4}
+
+	// In range, so doesn't grow.
+	require.NoError(t, mem.WriteGrowing(0, buf))
+	require.Equal(t, uint32(1), mem.PageSize())
+
+	// Straddles the end of the first page, so grows by one.
+	offset := MemoryPageSize - 2
+	require.NoError(t, mem.WriteGrowing(offset, buf))
+	require.Equal(t, uint32(2), mem.PageSize())
+	actual, ok := mem.Read(offset, 4)
+	require.True(t, ok)
+	require.Equal(t, buf, actual)
+
+	// Would need a third page, which exceeds the max.
+	err := mem.WriteGrowing(2*MemoryPageSize-2, buf)
+	require.EqualError(t, err, "write of 4 bytes at offset 131070 needs 3 pages, but the maximum is 2")
+	require.Equal(t, uint32(2), mem.PageSize())
+
+	// Write remains strict.
+	require.False(t, mem.Write(2*MemoryPageSize-2, buf))
+}
+
+func TestMemoryInstance_SnapshotRestore(t *testing.T) {
+	mem := NewMemoryInstance(&Memory{Min: 1, Cap: 1, Max: 2})
+	original := []byte{1, 2, 3, 4}
+	require.True(t, mem.Write(8, original))
+
+	snapshot := mem.Snapshot()
+	require.Equal(t, int(MemoryPageSize), len(snapshot))
+
+	// Mutate memory, which must not affect the snapshot.
+	require.True(t, mem.Write(8, []byte{5, 6, 7, 8}))
+	require.True(t, mem.WriteByte(MemoryPageSize-1, 9))
+
+	require.NoError(t, mem.Restore(snapshot))
+	actual, ok := mem.Read(8, 4)
+	require.True(t, ok)
+	require.Equal(t, original, actual)
+	b, ok := mem.ReadByte(MemoryPageSize - 1)
+	require.True(t, ok)
+	require.Equal(t, byte(0), b)
+
+	// A snapshot taken before growing can't be restored.
+	_, ok = mem.Grow(1)
+	require.True(t, ok)
+	err := mem.Restore(snapshot)
+	require.EqualError(t, err, "snapshot size 65536 does not match memory size 131072")
+}
+
 func TestMemoryInstance_Write_overflow(t *testing.T) {
 	mem := &MemoryInstance{Buffer: []byte{0, 0, 0, 0, 16, 0, 0, 0}, Min: 1}
 
@@ -165,6 +165,14 @@ type Module struct {
 	// See https://www.w3.org/TR/2022/WD-wasm-core-2-20220419/appendix/changes.html#bulk-memory-and-table-instructions
 	DataCountSection *uint32
 
+	// UnknownSections are the contents of sections with an ID not defined by the specification, keyed by ID. These are
+	// only set when the decoder was asked to store them, so that tools passing a module through can re-encode them.
+	UnknownSections map[SectionID][]byte
+
+	// RawSections are the contents of each section in the binary, in order. These are only set when the decoder was
+	// asked to store them, for example to hash specific sections.
+	RawSections []RawSection
+
 	// ID is the sha256 value of the source wasm plus the configurations which affect the runtime representation of
 	// Wasm binary. This is only used for caching.
 	ID ModuleID
@@ -196,6 +204,8 @@ const (
 	MaximumGlobals       = uint32(1 << 27)
 	MaximumFunctionIndex = uint32(1 << 27)
 	MaximumTableIndex    = uint32(1 << 27)
+	// MaximumFunctionLocals is the default limit of locals declared by a single function, excluding its parameters.
+	MaximumFunctionLocals = uint32(1 << 27)
 )
 
 // AssignModuleID calculates a sha256 checksum on `wasm` and other args, and set Module.ID to the result.
@@ -225,8 +235,12 @@ func boolToByte(b bool) (ret byte) {
 	return
 }
 
-// typeOfFunction returns the wasm.FunctionType for the given function space index or nil.
-func (m *Module) typeOfFunction(funcIdx Index) *FunctionType {
+// FunctionType returns the FunctionType for the given function index space
+// index, or false if the index is out of range or refers to an invalid type.
+//
+// Note: The function index space begins with imported functions, followed by
+// those defined in the FunctionSection.
+func (m *Module) FunctionType(funcIdx Index) (*FunctionType, bool) {
 	typeSectionLength, importedFunctionCount := uint32(len(m.TypeSection)), m.ImportFunctionCount
 	if funcIdx < importedFunctionCount {
 		// Imports are not exclusively functions. This is the current function index in the loop.
@@ -238,9 +252,9 @@ func (m *Module) typeOfFunction(funcIdx Index) *FunctionType {
 			}
 			if funcIdx == cur {
 				if imp.DescFunc >= typeSectionLength {
-					return nil
+					return nil, false
 				}
-				return &m.TypeSection[imp.DescFunc]
+				return &m.TypeSection[imp.DescFunc], true
 			}
 			cur++
 		}
@@ -248,21 +262,40 @@ func (m *Module) typeOfFunction(funcIdx Index) *FunctionType {
 
 	funcSectionIdx := funcIdx - m.ImportFunctionCount
 	if funcSectionIdx >= uint32(len(m.FunctionSection)) {
-		return nil
+		return nil, false
 	}
 	typeIdx := m.FunctionSection[funcSectionIdx]
 	if typeIdx >= typeSectionLength {
-		return nil
+		return nil, false
 	}
-	return &m.TypeSection[typeIdx]
+	return &m.TypeSection[typeIdx], true
+}
+
+// ValidateOptions configures Module.ValidateWithOptions. The zero value validates like Module.Validate.
+type ValidateOptions struct {
+	// MaxBlockDepth limits the nesting of blocks, loops and ifs in a single function. Zero defaults to
+	// MaximumBlockDepth.
+	MaxBlockDepth uint32
 }
 
+// Validate ensures the module is valid with the enabled features, as needed before compiling it.
 func (m *Module) Validate(enabledFeatures api.CoreFeatures) error {
+	return m.ValidateWithOptions(enabledFeatures, ValidateOptions{})
+}
+
+// ValidateWithOptions is like Validate, except limits are set with ValidateOptions.
+func (m *Module) ValidateWithOptions(enabledFeatures api.CoreFeatures, opts ValidateOptions) error {
 	for i := range m.TypeSection {
 		tp := &m.TypeSection[i]
 		tp.CacheNumInUint64()
 	}
 
+	for i := range m.CodeSection {
+		if err := m.DecodeFunctionBody(Index(i)); err != nil {
+			return err
+		}
+	}
+
 	if err := m.validateStartSection(); err != nil {
 		return err
 	}
@@ -289,7 +322,7 @@ func (m *Module) Validate(enabledFeatures api.CoreFeatures) error {
 	}
 
 	if m.CodeSection != nil {
-		if err = m.validateFunctions(enabledFeatures, functions, globals, memory, tables, MaximumFunctionIndex); err != nil {
+		if err = m.validateFunctions(enabledFeatures, functions, globals, memory, tables, MaximumFunctionIndex, opts.MaxBlockDepth); err != nil {
 			return err
 		}
 	} // No need to validate host functions as NewHostModule validates
@@ -304,13 +337,36 @@ func (m *Module) Validate(enabledFeatures api.CoreFeatures) error {
 	return nil
 }
 
+// ValidateCommand returns an error unless this module can run as a command, which is when it has a start section or
+// exports a "_start" function with an empty (nullary) signature, as WASI commands do. This is opt-in for embedders which
+// only run commands, and is in addition to Validate.
+func (m *Module) ValidateCommand() error {
+	if m.StartSection != nil {
+		return nil
+	}
+	exp, ok := m.Export("_start")
+	if !ok {
+		return errors.New("command must export a \"_start\" function or have a start section")
+	} else if exp.Type != ExternTypeFunc {
+		return fmt.Errorf("export \"_start\" is a %s, but must be a function", ExternTypeName(exp.Type))
+	}
+	ft, ok := m.FunctionType(exp.Index)
+	if !ok {
+		return fmt.Errorf("export \"_start\": func[%d] has an invalid type", exp.Index)
+	}
+	if len(ft.Params) > 0 || len(ft.Results) > 0 {
+		return fmt.Errorf("export \"_start\" must have an empty (nullary) signature: %s", ft)
+	}
+	return nil
+}
+
 func (m *Module) validateStartSection() error {
 	// Check the start function is valid.
 	// TODO: this should be verified during decode so that errors have the correct source positions
 	if m.StartSection != nil {
 		startIndex := *m.StartSection
-		ft := m.typeOfFunction(startIndex)
-		if ft == nil { // TODO: move this check to decoder so that a module can never be decoded invalidly
+		ft, ok := m.FunctionType(startIndex)
+		if !ok { // TODO: move this check to decoder so that a module can never be decoded invalidly
 			return fmt.Errorf("invalid start function: func[%d] has an invalid type", startIndex)
 		}
 		if len(ft.Params) > 0 || len(ft.Results) > 0 {
@@ -337,7 +393,7 @@ func (m *Module) validateGlobals(globals []GlobalType, numFuncts, maxGlobals uin
 	return nil
 }
 
-func (m *Module) validateFunctions(enabledFeatures api.CoreFeatures, functions []Index, globals []GlobalType, memory *Memory, tables []Table, maximumFunctionIndex uint32) error {
+func (m *Module) validateFunctions(enabledFeatures api.CoreFeatures, functions []Index, globals []GlobalType, memory *Memory, tables []Table, maximumFunctionIndex, maxBlockDepth uint32) error {
 	if uint32(len(functions)) > maximumFunctionIndex {
 		return fmt.Errorf("too many functions (%d) in a module", len(functions))
 	}
@@ -362,7 +418,7 @@ func (m *Module) validateFunctions(enabledFeatures api.CoreFeatures, functions [
 	// we frequently need it (e.g. on every If instruction).
 	br := bytes.NewReader(nil)
 	// Also, we reuse the stacks across multiple function validations to reduce allocations.
-	vs := &stacks{}
+	vs := &stacks{cs: controlBlockStack{maxDepth: maxBlockDepth}}
 	for idx, typeIndex := range m.FunctionSection {
 		if typeIndex >= typeCount {
 			return fmt.Errorf("invalid %s: type section index %d out of range", m.funcDesc(SectionIDFunction, Index(idx)), typeIndex)
@@ -423,14 +479,34 @@ func (m *Module) declaredFunctionIndexes() (ret map[Index]struct{}, err error) {
 	for i := range m.ElementSection {
 		elem := &m.ElementSection[i]
 		for _, index := range elem.Init {
-			if index != ElementInitNullReference {
-				ret[index] = struct{}{}
+			if index&(ElementInitNullReference|ElementInitImportedGlobalFunctionReference) != 0 {
+				continue // Not a function index.
 			}
+			ret[index] = struct{}{}
 		}
 	}
 	return
 }
 
+// DecodeFunctionBody decodes the body of CodeSection[index] if its decoding was deferred by Code.DecodeBody, or
+// does nothing if it was already decoded.
+//
+// Note: This isn't safe for concurrent use with the same module.
+func (m *Module) DecodeFunctionBody(index Index) error {
+	if index >= Index(len(m.CodeSection)) {
+		return fmt.Errorf("code index %d out of range", index)
+	}
+	c := &m.CodeSection[index]
+	if c.DecodeBody == nil {
+		return nil
+	}
+	if err := c.DecodeBody(c); err != nil {
+		return fmt.Errorf("%s: %w", m.funcDesc(SectionIDCode, index), err)
+	}
+	c.DecodeBody = nil
+	return nil
+}
+
 func (m *Module) funcDesc(sectionID SectionID, sectionIndex Index) string {
 	// Try to improve the error message by collecting any exports:
 	var exportNames []string
@@ -460,6 +536,9 @@ func (m *Module) validateMemory(memory *Memory, globals []GlobalType, _ api.Core
 	if activeElementCount > 0 && memory == nil {
 		return fmt.Errorf("unknown memory")
 	}
+	if memory != nil && memory.Is64 {
+		return fmt.Errorf("64-bit memory is not supported")
+	}
 
 	// Constant expression can only reference imported globals.
 	// https://github.com/WebAssembly/spec/blob/5900d839f38641989a9d8df2df4aee0513365d39/test/core/data.wast#L84-L91
@@ -566,6 +645,8 @@ func validateConstExpression(globals []GlobalType, numFuncs uint32, expr *Consta
 		}
 		if uint32(len(globals)) <= id {
 			return fmt.Errorf("global index out of range")
+		} else if globals[id].Mutable {
+			return fmt.Errorf("global.get of mutable global[%d] is not a constant expression", id)
 		}
 		actualType = globals[id].ValType
 	case OpcodeRefNull:
@@ -640,12 +721,25 @@ func paramNames(localNames IndirectNameMap, funcIdx uint32, paramLen int) []stri
 	return nil
 }
 
-func (m *ModuleInstance) buildMemory(module *Module) {
+func (m *ModuleInstance) buildMemory(module *Module) error {
 	memSec := module.MemorySection
-	if memSec != nil {
-		m.MemoryInstance = NewMemoryInstance(memSec)
-		m.MemoryInstance.definition = &module.MemoryDefinitionSection[0]
+	if memSec == nil {
+		return nil
 	}
+	var budget *memoryBudget
+	if m.s != nil {
+		m.s.mux.RLock()
+		budget = m.s.memoryBudget
+		m.s.mux.RUnlock()
+	}
+	if budget != nil && !budget.reserve(memSec.Min) {
+		return budget.errorExhausted(memSec.Min)
+	}
+	m.MemoryInstance = NewMemoryInstance(memSec)
+	m.MemoryInstance.definition = &module.MemoryDefinitionSection[0]
+	m.MemoryInstance.budget = budget
+	m.MemoryInstance.budgetPages.Store(memSec.Min)
+	return nil
 }
 
 // Index is the offset in an index, not necessarily an absolute position in a Module section. This is because
@@ -705,6 +799,11 @@ func (f *FunctionType) EqualsSignature(params []ValueType, results []ValueType)
 	return bytes.Equal(f.Params, params) && bytes.Equal(f.Results, results)
 }
 
+// Equal returns true if other has the same parameters and results as this function type.
+func (f *FunctionType) Equal(other *FunctionType) bool {
+	return f.EqualsSignature(other.Params, other.Results)
+}
+
 // key gets or generates the key for Store.typeIDs. e.g. "i32_v" for one i32 parameter and no (void) result.
 func (f *FunctionType) key() string {
 	if f.string != "" {
@@ -759,6 +858,10 @@ type Memory struct {
 	Min, Cap, Max uint32
 	// IsMaxEncoded true if the Max is encoded in the original binary.
 	IsMaxEncoded bool
+	// IsShared true if the memory is shared for access from multiple agents.
+	IsShared bool
+	// Is64 true if the memory has 64-bit limits, added by the memory64 proposal.
+	Is64 bool
 }
 
 // Validate ensures values assigned to Min, Cap and Max are within valid thresholds.
@@ -784,6 +887,20 @@ func (m *Memory) Validate(memoryLimitPages uint32) error {
 	return nil
 }
 
+// Satisfies returns an error unless a memory of this type can be imported as the required type: its minimum must be
+// at least the required minimum, and its maximum at most the required maximum.
+//
+// Note: When matching an instantiated memory, Min should be its current size, as it may have grown.
+// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#limits%E2%91%A2
+func (m *Memory) Satisfies(required *Memory) error {
+	if m.Min < required.Min {
+		return fmt.Errorf("minimum size mismatch: %d > %d", required.Min, m.Min)
+	} else if m.Max > required.Max {
+		return fmt.Errorf("maximum size mismatch: %d < %d", required.Max, m.Max)
+	}
+	return nil
+}
+
 type GlobalType struct {
 	ValType ValueType
 	Mutable bool
@@ -819,6 +936,14 @@ type Code struct {
 	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#binary-local
 	LocalTypes []ValueType
 
+	// LocalEntries are the run-length encoded entries of LocalTypes as decoded, only set when they differ from the
+	// canonical encoding which groups all adjacent locals of the same type into one entry. For example, this is set
+	// when two adjacent entries have the same type, or an entry has a zero count.
+	//
+	// Note: This allows re-encoding the locals exactly, as some tools compare the encoding, for example to check a
+	// signature.
+	LocalEntries []LocalEntry
+
 	// Body is a sequence of expressions ending in OpcodeEnd
 	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#binary-expr
 	Body []byte
@@ -834,6 +959,18 @@ type Code struct {
 	// BodyOffsetInCodeSection is the offset of the beginning of the body in the code section.
 	// This is used for DWARF based stack trace where a program counter represents an offset in code section.
 	BodyOffsetInCodeSection uint64
+
+	// DecodeBody is non-nil when LocalTypes, LocalEntries, Body and BodyOffsetInCodeSection are not yet decoded. It
+	// is set by a decoder which defers decoding, and is called by Module.DecodeFunctionBody.
+	DecodeBody func(*Code) error
+}
+
+// LocalEntry is an entry in the run-length encoding of the locals of a function.
+// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#binary-local
+type LocalEntry struct {
+	// Count is the count of locals of Type in this entry, which can be zero.
+	Count uint32
+	Type  ValueType
 }
 
 type DataSegment struct {
@@ -885,6 +1022,18 @@ type NameSection struct {
 	// Note: This can be nil for any reason including configuration.
 	LocalNames IndirectNameMap
 
+	// GlobalNames is an association of a global index to its symbolic identifier, from the extended name section.
+	//
+	// Note: This can be nil for any reason including configuration.
+	// See https://github.com/WebAssembly/extended-name-section/blob/main/proposals/extended-name-section/Overview.md
+	GlobalNames NameMap
+
+	// DataNames is an association of a data segment index to its symbolic identifier, from the extended name section.
+	//
+	// Note: This can be nil for any reason including configuration.
+	// See https://github.com/WebAssembly/extended-name-section/blob/main/proposals/extended-name-section/Overview.md
+	DataNames NameMap
+
 	// ResultNames is a wazero-specific mechanism to store result names.
 	ResultNames IndirectNameMap
 }
@@ -893,6 +1042,25 @@ type NameSection struct {
 type CustomSection struct {
 	Name string
 	Data []byte
+
+	// After is the ID of the last non-custom section which preceded this one when decoded, or SectionIDCustom if it
+	// preceded all of them. This allows encoding the section back at the same position.
+	//
+	// Note: When nil, the section is encoded after all non-custom sections and the name section.
+	After *SectionID
+
+	// AfterNameSection is true when the name section preceded this one when decoded. As the name section is encoded
+	// after all non-custom sections, so is this section, which keeps it after the name section.
+	AfterNameSection bool
+}
+
+// RawSection is a section as it was encoded in the binary it was decoded from.
+type RawSection struct {
+	ID SectionID
+	// Data is the contents of the section, excluding its ID and size.
+	//
+	// Note: When decoded from a byte slice, this shares its memory instead of copying it, so must not be modified.
+	Data []byte
 }
 
 // NameMap associates an index with any associated names.
@@ -932,6 +1100,10 @@ func (m *Module) AllDeclarations() (functions []Index, globals []GlobalType, mem
 		case ExternTypeGlobal:
 			globals = append(globals, imp.DescGlobal)
 		case ExternTypeMemory:
+			if memory != nil { // shouldn't be possible due to the decoder
+				err = errors.New("at most one memory allowed in module")
+				return
+			}
 			memory = imp.DescMem
 		case ExternTypeTable:
 			tables = append(tables, imp.DescTable)
@@ -944,8 +1116,8 @@ func (m *Module) AllDeclarations() (functions []Index, globals []GlobalType, mem
 		globals = append(globals, g.Type)
 	}
 	if m.MemorySection != nil {
-		if memory != nil { // shouldn't be possible due to Validate
-			err = errors.New("at most one table allowed in module")
+		if memory != nil { // shouldn't be possible due to the decoder
+			err = errors.New("at most one memory allowed in module")
 			return
 		}
 		memory = m.MemorySection
@@ -956,6 +1128,135 @@ func (m *Module) AllDeclarations() (functions []Index, globals []GlobalType, mem
 	return
 }
 
+// ImportDesc describes an import required by a module, so that a host can provide it before instantiation.
+type ImportDesc struct {
+	// Module and Name are the namespaces of the import, e.g. "wasi_snapshot_preview1" and "fd_write".
+	Module, Name string
+	// Type is the kind of the import, e.g. ExternTypeFunc.
+	Type ExternType
+	// FuncType is the signature when Type equals ExternTypeFunc.
+	FuncType *FunctionType
+	// Table is the table type when Type equals ExternTypeTable.
+	Table *Table
+	// Memory is the memory limits when Type equals ExternTypeMemory.
+	Memory *Memory
+	// Global is the global type when Type equals ExternTypeGlobal.
+	Global *GlobalType
+}
+
+// Imports returns a descriptor for each import in ImportSection, in the order they were declared.
+func (m *Module) Imports() []ImportDesc {
+	ret := make([]ImportDesc, len(m.ImportSection))
+	for i := range m.ImportSection {
+		imp := &m.ImportSection[i]
+		desc := ImportDesc{Module: imp.Module, Name: imp.Name, Type: imp.Type}
+		switch imp.Type {
+		case ExternTypeFunc:
+			desc.FuncType = &m.TypeSection[imp.DescFunc]
+		case ExternTypeTable:
+			desc.Table = &imp.DescTable
+		case ExternTypeMemory:
+			desc.Memory = imp.DescMem
+		case ExternTypeGlobal:
+			desc.Global = &imp.DescGlobal
+		}
+		ret[i] = desc
+	}
+	return ret
+}
+
+// AddMemory defines the memory of this module with the given limits in pages, exporting it as exportName unless empty.
+//
+// This returns an error if the module already imports or defines a memory, as WebAssembly 1.0 (20191205) allows at
+// most one, or if exportName is already exported.
+func (m *Module) AddMemory(minPages uint32, maxPages *uint32, exportName string) error {
+	if m.MemorySection != nil || m.ImportMemoryCount > 0 {
+		return errors.New("at most one memory allowed in module")
+	}
+	if _, exists := m.Export(exportName); exportName != "" && exists {
+		return fmt.Errorf("export[%q] already exists", exportName)
+	}
+
+	mem := &Memory{Min: minPages, Cap: minPages, Max: MemoryLimitPages}
+	if maxPages != nil {
+		mem.Max, mem.IsMaxEncoded = *maxPages, true
+	}
+	if err := mem.Validate(MemoryLimitPages); err != nil {
+		return err
+	}
+
+	m.MemorySection = mem
+	if exportName != "" {
+		m.addExport(ExternTypeMemory, exportName, m.ImportMemoryCount)
+	}
+	return nil
+}
+
+// Export returns the export of the given name, or false if there is none. This uses Exports when initialized by the
+// decoder, avoiding a scan of ExportSection.
+func (m *Module) Export(name string) (*Export, bool) {
+	if m.Exports != nil {
+		exp, ok := m.Exports[name]
+		return exp, ok
+	}
+	for i := range m.ExportSection {
+		if exp := &m.ExportSection[i]; exp.Name == name {
+			return exp, true
+		}
+	}
+	return nil, false
+}
+
+// RemoveExport removes the export of the given name, returning false if there is none. What was exported, such as a
+// function, remains in the module.
+func (m *Module) RemoveExport(name string) bool {
+	for i := range m.ExportSection {
+		if m.ExportSection[i].Name != name {
+			continue
+		}
+		m.ExportSection = append(m.ExportSection[:i], m.ExportSection[i+1:]...)
+		if m.Exports != nil {
+			m.buildExports() // The removal moved the elements after it.
+		}
+		return true
+	}
+	return false
+}
+
+// RenameExport renames the export named oldName to newName. This fails if there is no such export, or if newName is
+// already exported, as export names must be unique.
+func (m *Module) RenameExport(oldName, newName string) error {
+	exp, ok := m.Export(oldName)
+	if !ok {
+		return fmt.Errorf("export[%q] not found", oldName)
+	} else if oldName == newName {
+		return nil
+	} else if _, ok = m.Export(newName); ok {
+		return fmt.Errorf("export[%q] already exists", newName)
+	}
+	exp.Name = newName
+	if m.Exports != nil {
+		delete(m.Exports, oldName)
+		m.Exports[newName] = exp
+	}
+	return nil
+}
+
+// addExport appends an Export to ExportSection and rebuilds Exports, as the append can move the elements it points to.
+func (m *Module) addExport(externType ExternType, name string, index Index) {
+	m.ExportSection = append(m.ExportSection, Export{Type: externType, Name: name, Index: index})
+	m.buildExports()
+}
+
+// buildExports sets Exports from ExportSection.
+func (m *Module) buildExports() {
+	m.Exports = make(map[string]*Export, len(m.ExportSection))
+	for i := range m.ExportSection {
+		exp := &m.ExportSection[i]
+		m.Exports[exp.Name] = exp
+	}
+}
+
 // SectionID identifies the sections of a Module in the WebAssembly 1.0 (20191205) Binary Format.
 //
 // Note: these are defined in the wasm package, instead of the binary package, as a key per section is needed regardless
@@ -0,0 +1,67 @@
+package wasm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/tetratelabs/wazero/internal/leb128"
+)
+
+// moduleHeader is the magic number ("\0asm") and version which begin the binary format.
+var moduleHeader = []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+
+// ModuleHash returns a SHA-256 over the sections of binary, for example to key a compilation cache. Custom sections
+// named in excludeCustomSections, such as "producers", are skipped, so that volatile metadata doesn't change the hash.
+//
+// Section sizes are hashed in their canonical LEB128 encoding, so padding a size doesn't change the hash either.
+//
+// Note: This only checks the header and that each section is in range. Sections aren't decoded or validated.
+func ModuleHash(binary []byte, excludeCustomSections ...string) (ModuleID, error) {
+	var ret ModuleID
+	if !bytes.HasPrefix(binary, moduleHeader) {
+		return ret, errors.New("invalid module header")
+	}
+
+	h := sha256.New()
+	h.Write(moduleHeader)
+	for offset, end := uint64(len(moduleHeader)), uint64(0); offset < uint64(len(binary)); offset = end {
+		sectionID := binary[offset]
+		size, n, err := leb128.LoadUint32(binary[offset+1:])
+		if err != nil {
+			return ret, fmt.Errorf("section %s at %#x: read size: %w", SectionIDName(sectionID), offset, err)
+		}
+		start := offset + 1 + n
+		end = start + uint64(size)
+		if end > uint64(len(binary)) {
+			return ret, fmt.Errorf("section %s at %#x: size %d exceeds the remaining %d bytes",
+				SectionIDName(sectionID), offset, size, uint64(len(binary))-start)
+		}
+
+		contents := binary[start:end]
+		if sectionID == SectionIDCustom {
+			nameLen, n, err := leb128.LoadUint32(contents)
+			if err != nil || n+uint64(nameLen) > uint64(len(contents)) {
+				return ret, fmt.Errorf("section %s at %#x: invalid name", SectionIDName(sectionID), offset)
+			}
+			if name := string(contents[n : n+uint64(nameLen)]); containsString(excludeCustomSections, name) {
+				continue
+			}
+		}
+		h.Write([]byte{sectionID})
+		h.Write(leb128.EncodeUint32(size))
+		h.Write(contents)
+	}
+	h.Sum(ret[:0])
+	return ret, nil
+}
+
+func containsString(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package wasm
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestModuleHash(t *testing.T) {
+	typeSection := []byte{SectionIDType, 4, 1, 0x60, 0, 0}
+	producers := []byte{SectionIDCustom, 11, 9, 'p', 'r', 'o', 'd', 'u', 'c', 'e', 'r', 's', 1}
+	module := func(sections ...[]byte) (ret []byte) {
+		ret = append(ret, moduleHeader...)
+		for _, s := range sections {
+			ret = append(ret, s...)
+		}
+		return
+	}
+
+	hash := func(binary []byte, excludeCustomSections ...string) ModuleID {
+		h, err := ModuleHash(binary, excludeCustomSections...)
+		require.NoError(t, err)
+		return h
+	}
+
+	withProducers := hash(module(typeSection, producers))
+
+	// Byte-identical modules hash equally.
+	require.Equal(t, withProducers, hash(module(typeSection, producers)))
+
+	// Stripping the custom section changes the hash, unless it is excluded.
+	stripped := hash(module(typeSection))
+	require.NotEqual(t, withProducers, stripped)
+	require.Equal(t, stripped, hash(module(typeSection, producers), "producers"))
+
+	// A non-canonical section size doesn't change the hash.
+	padded := []byte{SectionIDType, 0x84, 0x00, 1, 0x60, 0, 0}
+	require.Equal(t, stripped, hash(module(padded)))
+}
+
+func TestModuleHash_Errors(t *testing.T) {
+	tests := []struct {
+		name, expectedErr string
+		binary            []byte
+	}{
+		{
+			name:        "invalid magic",
+			binary:      []byte{'w', 'a', 's', 'm', 1, 0, 0, 0},
+			expectedErr: "invalid module header",
+		},
+		{
+			name:        "invalid version",
+			binary:      []byte{0, 'a', 's', 'm', 2, 0, 0, 0},
+			expectedErr: "invalid module header",
+		},
+		{
+			name:        "missing section size",
+			binary:      append(append([]byte{}, moduleHeader...), SectionIDType),
+			expectedErr: "section type at 0x8: read size: EOF",
+		},
+		{
+			name:        "section too large",
+			binary:      append(append([]byte{}, moduleHeader...), SectionIDType, 4, 1, 0x60),
+			expectedErr: "section type at 0x8: size 4 exceeds the remaining 2 bytes",
+		},
+		{
+			name:        "custom section name too large",
+			binary:      append(append([]byte{}, moduleHeader...), SectionIDCustom, 2, 5, 'a'),
+			expectedErr: "section custom at 0x8: invalid name",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ModuleHash(tc.binary)
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
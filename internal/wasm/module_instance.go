@@ -150,6 +150,11 @@ func (m *ModuleInstance) ensureResourcesClosed(ctx context.Context) (err error)
 		m.CloseNotifier = nil
 	}
 
+	// Only the module defining a memory returns it to the budget, not those importing it.
+	if mem := m.MemoryInstance; mem != nil && m.Source != nil && m.Source.MemorySection != nil {
+		mem.releaseMemoryBudget()
+	}
+
 	if sysCtx := m.Sys; sysCtx != nil { // nil if from HostModuleBuilder
 		if err = sysCtx.FS().Close(); err != nil {
 			return err
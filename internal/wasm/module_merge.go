@@ -0,0 +1,465 @@
+package wasm
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/tetratelabs/wazero/internal/leb128"
+)
+
+// MergeModules returns a new module which includes the definitions of both main and lib, where lib was to be
+// instantiated with the name libName. Neither input is modified.
+//
+// Imports in main from libName are resolved to what lib exports with that name, so that calls across the two modules
+// become direct calls and memories, tables and globals are shared. All other imports of both modules are kept, with
+// the imports of main first. Indexes of functions, globals, tables, element segments and data segments in lib are offset to follow those
+// of main. Identical function types are shared.
+//
+// Only exports of main are kept, as lib is no longer instantiated on its own. The start function of lib is kept if
+// main doesn't define one, as otherwise the order of the two would be ambiguous.
+//
+// Note: Both modules must be valid, and derived data such as FunctionDefinitionSection is not copied.
+func MergeModules(main, lib *Module, libName string) (*Module, error) {
+	if main.StartSection != nil && lib.StartSection != nil {
+		return nil, errors.New("both modules have a start function")
+	}
+	_, libGlobalTypes, libMemory, libTableTypes, err := lib.AllDeclarations()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &Module{}
+
+	// Types of main keep their index, and those of lib are added unless an equivalent one exists.
+	ret.TypeSection = append([]FunctionType(nil), main.TypeSection...)
+	libTypes := make([]Index, len(lib.TypeSection))
+	for i := range lib.TypeSection {
+		libTypes[i] = ret.addType(&lib.TypeSection[i])
+	}
+
+	// Resolve imports of main from libName to what lib exports. The values are indexes in lib, which are mapped to the
+	// merged index spaces once they are known.
+	resolved := map[Index]Index{}
+	resolvedGlobals := map[Index]Index{}
+	resolvedTables := map[Index]Index{}
+	var resolvedMemory bool
+	var funcIdx, globalIdx, tableIdx Index
+	for i := range main.ImportSection {
+		imp := &main.ImportSection[i]
+		if imp.Module == libName {
+			exp, ok := lib.Export(imp.Name)
+			if !ok || exp.Type != imp.Type {
+				return nil, errorInvalidImport(imp, fmt.Errorf("%s is not exported by %q", ExternTypeName(imp.Type), libName))
+			}
+			switch imp.Type {
+			case ExternTypeFunc:
+				expected := &main.TypeSection[imp.DescFunc]
+				actual, ok := lib.FunctionType(exp.Index)
+				if !ok || !actual.EqualsSignature(expected.Params, expected.Results) {
+					return nil, errorInvalidImport(imp, fmt.Errorf("signature mismatch: %s != %s", expected, actual))
+				}
+				resolved[funcIdx] = exp.Index
+			case ExternTypeGlobal:
+				expected, actual := imp.DescGlobal, libGlobalTypes[exp.Index]
+				if expected.Mutable != actual.Mutable {
+					return nil, errorInvalidImport(imp, fmt.Errorf("mutability mismatch: %t != %t",
+						expected.Mutable, actual.Mutable))
+				} else if expected.ValType != actual.ValType {
+					return nil, errorInvalidImport(imp, fmt.Errorf("value type mismatch: %s != %s",
+						ValueTypeName(expected.ValType), ValueTypeName(actual.ValType)))
+				}
+				resolvedGlobals[globalIdx] = exp.Index
+			case ExternTypeTable:
+				if err = libTableTypes[exp.Index].Satisfies(&imp.DescTable); err != nil {
+					return nil, errorInvalidImport(imp, err)
+				}
+				resolvedTables[tableIdx] = exp.Index
+			case ExternTypeMemory:
+				if err = libMemory.Satisfies(imp.DescMem); err != nil {
+					return nil, errorInvalidImport(imp, err)
+				}
+				resolvedMemory = true
+			}
+		}
+		switch imp.Type {
+		case ExternTypeFunc:
+			funcIdx++
+		case ExternTypeGlobal:
+			globalIdx++
+		case ExternTypeTable:
+			tableIdx++
+		}
+	}
+
+	// A memory of main imported from lib is that of lib, so it doesn't count.
+	mainHasMemory := main.MemorySection != nil || (main.ImportMemoryCount > 0 && !resolvedMemory)
+	libHasMemory := lib.MemorySection != nil || lib.ImportMemoryCount > 0
+	if mainHasMemory && libHasMemory {
+		return nil, errors.New("at most one memory allowed in module")
+	}
+
+	// Imports of main which weren't resolved are followed by all imports of lib.
+	mainFuncs := make([]Index, main.ImportFunctionCount+uint32(len(main.FunctionSection)))
+	mainGlobals := make([]Index, main.ImportGlobalCount+uint32(len(main.GlobalSection)))
+	mainTables := make([]Index, main.ImportTableCount+uint32(len(main.TableSection)))
+	libFuncs := make([]Index, lib.ImportFunctionCount+uint32(len(lib.FunctionSection)))
+	libGlobals := make([]Index, lib.ImportGlobalCount+uint32(len(lib.GlobalSection)))
+	libTables := make([]Index, lib.ImportTableCount+uint32(len(lib.TableSection)))
+
+	var mainFuncIdx, mainGlobalIdx, mainTableIdx Index
+	for i := range main.ImportSection {
+		imp := main.ImportSection[i]
+		switch imp.Type {
+		case ExternTypeFunc:
+			if _, ok := resolved[mainFuncIdx]; !ok {
+				mainFuncs[mainFuncIdx] = ret.ImportFunctionCount
+				ret.addImport(imp)
+			}
+			mainFuncIdx++
+		case ExternTypeGlobal:
+			if _, ok := resolvedGlobals[mainGlobalIdx]; !ok {
+				mainGlobals[mainGlobalIdx] = ret.ImportGlobalCount
+				ret.addImport(imp)
+			}
+			mainGlobalIdx++
+		case ExternTypeTable:
+			if _, ok := resolvedTables[mainTableIdx]; !ok {
+				mainTables[mainTableIdx] = ret.ImportTableCount
+				ret.addImport(imp)
+			}
+			mainTableIdx++
+		case ExternTypeMemory:
+			if !resolvedMemory {
+				ret.addImport(imp)
+			}
+		}
+	}
+	var libFuncIdx, libGlobalIdx, libTableIdx Index
+	for i := range lib.ImportSection {
+		imp := lib.ImportSection[i]
+		switch imp.Type {
+		case ExternTypeFunc:
+			imp.DescFunc = libTypes[imp.DescFunc]
+			libFuncs[libFuncIdx] = ret.ImportFunctionCount
+			libFuncIdx++
+		case ExternTypeGlobal:
+			libGlobals[libGlobalIdx] = ret.ImportGlobalCount
+			libGlobalIdx++
+		case ExternTypeTable:
+			libTables[libTableIdx] = ret.ImportTableCount
+			libTableIdx++
+		}
+		ret.addImport(imp)
+	}
+
+	ret.buildImportPerModule()
+
+	// Definitions of main are followed by those of lib.
+	for i := range main.FunctionSection {
+		mainFuncs[main.ImportFunctionCount+Index(i)] = ret.ImportFunctionCount + Index(i)
+	}
+	for i := range lib.FunctionSection {
+		libFuncs[lib.ImportFunctionCount+Index(i)] = ret.ImportFunctionCount + Index(len(main.FunctionSection)+i)
+	}
+	for mainIdx, libIdx := range resolved {
+		mainFuncs[mainIdx] = libFuncs[libIdx]
+	}
+	for i := range main.GlobalSection {
+		mainGlobals[main.ImportGlobalCount+Index(i)] = ret.ImportGlobalCount + Index(i)
+	}
+	for i := range lib.GlobalSection {
+		libGlobals[lib.ImportGlobalCount+Index(i)] = ret.ImportGlobalCount + Index(len(main.GlobalSection)+i)
+	}
+	for mainIdx, libIdx := range resolvedGlobals {
+		mainGlobals[mainIdx] = libGlobals[libIdx]
+	}
+	for i := range main.TableSection {
+		mainTables[main.ImportTableCount+Index(i)] = ret.ImportTableCount + Index(i)
+	}
+	for i := range lib.TableSection {
+		libTables[lib.ImportTableCount+Index(i)] = ret.ImportTableCount + Index(len(main.TableSection)+i)
+	}
+	for mainIdx, libIdx := range resolvedTables {
+		mainTables[mainIdx] = libTables[libIdx]
+	}
+
+	mainMapping := &indexMapping{funcs: mainFuncs, globals: mainGlobals, tables: mainTables}
+	libMapping := &indexMapping{
+		funcs:          libFuncs,
+		types:          libTypes,
+		globals:        libGlobals,
+		tables:         libTables,
+		elementsOffset: Index(len(main.ElementSection)),
+		dataOffset:     Index(len(main.DataSection)),
+	}
+	for _, src := range []struct {
+		m       *Module
+		mapping *indexMapping
+	}{{main, mainMapping}, {lib, libMapping}} {
+		if err := ret.mergeDefinitions(src.m, src.mapping); err != nil {
+			return nil, err
+		}
+	}
+
+	if main.MemorySection != nil {
+		mem := *main.MemorySection
+		ret.MemorySection = &mem
+	} else if lib.MemorySection != nil {
+		mem := *lib.MemorySection
+		ret.MemorySection = &mem
+	}
+
+	if main.StartSection != nil {
+		start := mainFuncs[*main.StartSection]
+		ret.StartSection = &start
+	} else if lib.StartSection != nil {
+		start := libFuncs[*lib.StartSection]
+		ret.StartSection = &start
+	}
+
+	for i := range main.ExportSection {
+		exp := main.ExportSection[i]
+		switch exp.Type {
+		case ExternTypeFunc:
+			exp.Index = mainFuncs[exp.Index]
+		case ExternTypeGlobal:
+			exp.Index = mainGlobals[exp.Index]
+		case ExternTypeTable:
+			exp.Index = mainTables[exp.Index]
+		}
+		ret.ExportSection = append(ret.ExportSection, exp)
+	}
+	ret.Exports = make(map[string]*Export, len(ret.ExportSection))
+	for i := range ret.ExportSection {
+		exp := &ret.ExportSection[i]
+		ret.Exports[exp.Name] = exp
+	}
+
+	if main.DataCountSection != nil || lib.DataCountSection != nil {
+		count := uint32(len(ret.DataSection))
+		ret.DataCountSection = &count
+	}
+
+	ret.NameSection = mergeNameSections(main, lib, mainMapping, libMapping, resolved, resolvedGlobals)
+	ret.CustomSections = append(append([]*CustomSection(nil), main.CustomSections...), lib.CustomSections...)
+	return ret, nil
+}
+
+// indexMapping maps the index spaces of a module being merged to those of the merged module.
+type indexMapping struct {
+	// funcs, globals and tables are indexed by the original index.
+	funcs, globals, tables []Index
+	// types is indexed by the original type index, or nil to keep them as-is.
+	types []Index
+	// elementsOffset and dataOffset are added to element and data segment indexes.
+	elementsOffset, dataOffset Index
+}
+
+func (im *indexMapping) remap(imm indexImmediate) Index {
+	switch imm.kind {
+	case indexKindFunction:
+		return im.funcs[imm.index]
+	case indexKindType:
+		if im.types != nil {
+			return im.types[imm.index]
+		}
+	case indexKindGlobal:
+		return im.globals[imm.index]
+	case indexKindTable:
+		return im.tables[imm.index]
+	case indexKindElement:
+		return imm.index + im.elementsOffset
+	case indexKindData:
+		return imm.index + im.dataOffset
+	}
+	return imm.index
+}
+
+func (im *indexMapping) typeIndex(typeIdx Index) Index {
+	if im.types != nil {
+		return im.types[typeIdx]
+	}
+	return typeIdx
+}
+
+// remapConstExpr returns a copy of the expression with any global or function index remapped.
+func (im *indexMapping) remapConstExpr(expr ConstantExpression) (ConstantExpression, error) {
+	switch expr.Opcode {
+	case OpcodeGlobalGet, OpcodeRefFunc:
+		idx, _, err := leb128.LoadUint32(expr.Data)
+		if err != nil {
+			return expr, fmt.Errorf("read index: %w", err)
+		}
+		if expr.Opcode == OpcodeGlobalGet {
+			idx = im.globals[idx]
+		} else {
+			idx = im.funcs[idx]
+		}
+		expr.Data = leb128.EncodeUint32(idx)
+	}
+	return expr, nil
+}
+
+// mergeDefinitions appends the functions, tables, globals, element segments and data segments defined in src.
+func (m *Module) mergeDefinitions(src *Module, mapping *indexMapping) error {
+	for i, typeIdx := range src.FunctionSection {
+		code := src.CodeSection[i]
+		if code.GoFunc == nil {
+			immediates, err := indexImmediates(code.Body)
+			if err != nil {
+				return fmt.Errorf("%s: %w", src.funcDesc(SectionIDCode, Index(i)), err)
+			}
+			code.Body = remapIndexImmediates(code.Body, immediates, mapping.remap)
+		}
+		m.FunctionSection = append(m.FunctionSection, mapping.typeIndex(typeIdx))
+		m.CodeSection = append(m.CodeSection, code)
+	}
+
+	m.TableSection = append(m.TableSection, src.TableSection...)
+
+	for i := range src.GlobalSection {
+		g := src.GlobalSection[i]
+		init, err := mapping.remapConstExpr(g.Init)
+		if err != nil {
+			return fmt.Errorf("global[%d]: %w", i, err)
+		}
+		g.Init = init
+		m.GlobalSection = append(m.GlobalSection, g)
+	}
+
+	for i := range src.ElementSection {
+		elem := src.ElementSection[i]
+		offset, err := mapping.remapConstExpr(elem.OffsetExpr)
+		if err != nil {
+			return fmt.Errorf("element[%d]: %w", i, err)
+		}
+		elem.OffsetExpr = offset
+		if elem.Mode == ElementModeActive {
+			elem.TableIndex = mapping.tables[elem.TableIndex]
+		}
+		elem.Init = make([]Index, len(src.ElementSection[i].Init))
+		for j, idx := range src.ElementSection[i].Init {
+			switch {
+			case idx&ElementInitNullReference != 0:
+			case idx&ElementInitImportedGlobalFunctionReference != 0:
+				idx = ElementInitImportedGlobalFunctionReference | mapping.globals[idx&^ElementInitImportedGlobalFunctionReference]
+			default:
+				idx = mapping.funcs[idx]
+			}
+			elem.Init[j] = idx
+		}
+		m.ElementSection = append(m.ElementSection, elem)
+	}
+
+	for i := range src.DataSection {
+		d := src.DataSection[i]
+		if !d.IsPassive() {
+			offset, err := mapping.remapConstExpr(d.OffsetExpression)
+			if err != nil {
+				return fmt.Errorf("data[%d]: %w", i, err)
+			}
+			d.OffsetExpression = offset
+		}
+		m.DataSection = append(m.DataSection, d)
+	}
+	return nil
+}
+
+// addType returns the index of a type equivalent to ft, adding it to TypeSection if there is none.
+func (m *Module) addType(ft *FunctionType) Index {
+	for i := range m.TypeSection {
+		if m.TypeSection[i].EqualsSignature(ft.Params, ft.Results) {
+			return Index(i)
+		}
+	}
+	m.TypeSection = append(m.TypeSection, *ft)
+	return Index(len(m.TypeSection) - 1)
+}
+
+// addImport appends the import to ImportSection, updating the import counts.
+//
+// Note: ImportPerModule must be built after all imports are added, as appending can move the elements it points to.
+func (m *Module) addImport(imp Import) {
+	switch imp.Type {
+	case ExternTypeFunc:
+		imp.IndexPerType = m.ImportFunctionCount
+		m.ImportFunctionCount++
+	case ExternTypeGlobal:
+		imp.IndexPerType = m.ImportGlobalCount
+		m.ImportGlobalCount++
+	case ExternTypeMemory:
+		imp.IndexPerType = m.ImportMemoryCount
+		m.ImportMemoryCount++
+	case ExternTypeTable:
+		imp.IndexPerType = m.ImportTableCount
+		m.ImportTableCount++
+	}
+	m.ImportSection = append(m.ImportSection, imp)
+}
+
+// buildImportPerModule sets ImportPerModule from ImportSection, if there are any imports.
+func (m *Module) buildImportPerModule() {
+	if len(m.ImportSection) == 0 {
+		return
+	}
+	m.ImportPerModule = make(map[string][]*Import)
+	for i := range m.ImportSection {
+		imp := &m.ImportSection[i]
+		m.ImportPerModule[imp.Module] = append(m.ImportPerModule[imp.Module], imp)
+	}
+}
+
+// mergeNameSections returns the names of both modules in the merged index spaces, or nil if neither has names.
+//
+// The module name of main is kept. Names of resolved imports are dropped, as the function or global is now that of lib.
+func mergeNameSections(main, lib *Module, mainMapping, libMapping *indexMapping, resolved, resolvedGlobals map[Index]Index) *NameSection {
+	if main.NameSection == nil && lib.NameSection == nil {
+		return nil
+	}
+	ret := &NameSection{}
+	for _, src := range []struct {
+		n           *NameSection
+		mapping     *indexMapping
+		skip        map[Index]Index
+		skipGlobals map[Index]Index
+	}{{main.NameSection, mainMapping, resolved, resolvedGlobals}, {lib.NameSection, libMapping, nil, nil}} {
+		n := src.n
+		if n == nil {
+			continue
+		}
+		if ret.ModuleName == "" {
+			ret.ModuleName = n.ModuleName
+		}
+		for _, na := range n.FunctionNames {
+			if _, ok := src.skip[na.Index]; !ok {
+				ret.FunctionNames = append(ret.FunctionNames, NameAssoc{Index: src.mapping.funcs[na.Index], Name: na.Name})
+			}
+		}
+		for _, nm := range n.LocalNames {
+			if _, ok := src.skip[nm.Index]; !ok {
+				ret.LocalNames = append(ret.LocalNames, NameMapAssoc{Index: src.mapping.funcs[nm.Index], NameMap: nm.NameMap})
+			}
+		}
+		for _, nm := range n.ResultNames {
+			if _, ok := src.skip[nm.Index]; !ok {
+				ret.ResultNames = append(ret.ResultNames, NameMapAssoc{Index: src.mapping.funcs[nm.Index], NameMap: nm.NameMap})
+			}
+		}
+		for _, na := range n.GlobalNames {
+			if _, ok := src.skipGlobals[na.Index]; !ok {
+				ret.GlobalNames = append(ret.GlobalNames, NameAssoc{Index: src.mapping.globals[na.Index], Name: na.Name})
+			}
+		}
+		for _, na := range n.DataNames {
+			ret.DataNames = append(ret.DataNames, NameAssoc{Index: na.Index + src.mapping.dataOffset, Name: na.Name})
+		}
+	}
+
+	// Keep names in ascending order by index, as required by the name section.
+	sort.Slice(ret.FunctionNames, func(i, j int) bool { return ret.FunctionNames[i].Index < ret.FunctionNames[j].Index })
+	sort.Slice(ret.LocalNames, func(i, j int) bool { return ret.LocalNames[i].Index < ret.LocalNames[j].Index })
+	sort.Slice(ret.ResultNames, func(i, j int) bool { return ret.ResultNames[i].Index < ret.ResultNames[j].Index })
+	sort.Slice(ret.GlobalNames, func(i, j int) bool { return ret.GlobalNames[i].Index < ret.GlobalNames[j].Index })
+	return ret
+}
@@ -0,0 +1,260 @@
+package wasm
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestMergeModules(t *testing.T) {
+	main := &Module{
+		TypeSection: []FunctionType{i32i32_i32, v_i32, i32_v},
+		ImportSection: []Import{
+			{Type: ExternTypeFunc, Module: "lib", Name: "add", DescFunc: 0},
+			{Type: ExternTypeFunc, Module: "env", Name: "log", DescFunc: 2},
+		},
+		ImportFunctionCount: 2,
+		FunctionSection:     []Index{1},
+		CodeSection: []Code{
+			// func[2] run: calls lib.add
+			{Body: []byte{OpcodeI32Const, 1, OpcodeGlobalGet, 0, OpcodeCall, 0, OpcodeEnd}},
+		},
+		GlobalSection: []Global{
+			{Type: GlobalType{ValType: i32}, Init: ConstantExpression{Opcode: OpcodeI32Const, Data: []byte{1}}},
+		},
+		ExportSection: []Export{{Type: ExternTypeFunc, Name: "run", Index: 2}},
+		NameSection: &NameSection{
+			ModuleName:    "main",
+			FunctionNames: NameMap{{Index: 0, Name: "add"}, {Index: 2, Name: "run"}},
+		},
+	}
+	lib := &Module{
+		TypeSection: []FunctionType{i32_v, i32i32_i32, i32_i32},
+		ImportSection: []Import{
+			{Type: ExternTypeFunc, Module: "env", Name: "log", DescFunc: 0},
+		},
+		ImportFunctionCount: 1,
+		FunctionSection:     []Index{1, 2},
+		CodeSection: []Code{
+			// func[1] add: calls double
+			{Body: []byte{OpcodeLocalGet, 0, OpcodeLocalGet, 1, OpcodeI32Add, OpcodeGlobalGet, 0, OpcodeI32Add, OpcodeCall, 2, OpcodeEnd}},
+			// func[2] double
+			{Body: []byte{OpcodeLocalGet, 0, OpcodeLocalGet, 0, OpcodeI32Add, OpcodeEnd}},
+		},
+		GlobalSection: []Global{
+			{Type: GlobalType{ValType: i32}, Init: ConstantExpression{Opcode: OpcodeI32Const, Data: []byte{2}}},
+		},
+		ExportSection: []Export{{Type: ExternTypeFunc, Name: "add", Index: 1}},
+		NameSection: &NameSection{
+			ModuleName:    "lib",
+			FunctionNames: NameMap{{Index: 1, Name: "add"}, {Index: 2, Name: "double"}},
+		},
+	}
+
+	m, err := MergeModules(main, lib, "lib")
+	require.NoError(t, err)
+
+	require.Equal(t, []FunctionType{i32i32_i32, v_i32, i32_v, i32_i32}, m.TypeSection)
+	require.Equal(t, []Import{
+		{Type: ExternTypeFunc, Module: "env", Name: "log", DescFunc: 2, IndexPerType: 0},
+		{Type: ExternTypeFunc, Module: "env", Name: "log", DescFunc: 2, IndexPerType: 1},
+	}, m.ImportSection)
+	require.Equal(t, Index(2), m.ImportFunctionCount)
+	require.Equal(t, 2, len(m.ImportPerModule["env"]))
+	require.Equal(t, []Index{1, 0, 3}, m.FunctionSection)
+	require.Equal(t, []Code{
+		{Body: []byte{OpcodeI32Const, 1, OpcodeGlobalGet, 0, OpcodeCall, 3, OpcodeEnd}},
+		{Body: []byte{OpcodeLocalGet, 0, OpcodeLocalGet, 1, OpcodeI32Add, OpcodeGlobalGet, 1, OpcodeI32Add, OpcodeCall, 4, OpcodeEnd}},
+		{Body: []byte{OpcodeLocalGet, 0, OpcodeLocalGet, 0, OpcodeI32Add, OpcodeEnd}},
+	}, m.CodeSection)
+	require.Equal(t, 2, len(m.GlobalSection))
+	require.Equal(t, []Export{{Type: ExternTypeFunc, Name: "run", Index: 2}}, m.ExportSection)
+	require.Equal(t, &m.ExportSection[0], m.Exports["run"])
+	require.Equal(t, &NameSection{
+		ModuleName: "main",
+		FunctionNames: NameMap{
+			{Index: 2, Name: "run"},
+			{Index: 3, Name: "add"},
+			{Index: 4, Name: "double"},
+		},
+	}, m.NameSection)
+	require.NoError(t, m.Validate(api.CoreFeaturesV2))
+
+	// The inputs are not modified.
+	require.Equal(t, []byte{OpcodeI32Const, 1, OpcodeGlobalGet, 0, OpcodeCall, 0, OpcodeEnd}, main.CodeSection[0].Body)
+	require.Equal(t, Index(0), lib.ImportSection[0].DescFunc)
+}
+
+func TestMergeModules_SharedDefinitions(t *testing.T) {
+	main := &Module{
+		TypeSection: []FunctionType{v_i32},
+		ImportSection: []Import{
+			{Type: ExternTypeMemory, Module: "lib", Name: "memory", DescMem: &Memory{Min: 1, Max: 2, IsMaxEncoded: true}},
+			{Type: ExternTypeGlobal, Module: "lib", Name: "g", DescGlobal: GlobalType{ValType: i32}},
+			{Type: ExternTypeTable, Module: "lib", Name: "t", DescTable: Table{Type: RefTypeFuncref, Min: 1}},
+		},
+		ImportMemoryCount: 1,
+		ImportGlobalCount: 1,
+		ImportTableCount:  1,
+		FunctionSection:   []Index{0},
+		CodeSection: []Code{{Body: []byte{
+			OpcodeGlobalGet, 0, OpcodeGlobalGet, 1, OpcodeI32Add,
+			OpcodeI32Const, 0, OpcodeI32Load, 0x2, 0x0, OpcodeI32Add,
+			OpcodeI32Const, 0, OpcodeTableGet, 0, OpcodeDrop,
+			OpcodeEnd,
+		}}},
+		GlobalSection: []Global{
+			{Type: GlobalType{ValType: i32}, Init: ConstantExpression{Opcode: OpcodeI32Const, Data: []byte{1}}},
+		},
+		ExportSection: []Export{
+			{Type: ExternTypeFunc, Name: "run", Index: 0},
+			{Type: ExternTypeMemory, Name: "memory", Index: 0},
+			{Type: ExternTypeGlobal, Name: "g", Index: 0},
+		},
+		NameSection: &NameSection{GlobalNames: NameMap{{Index: 0, Name: "g"}, {Index: 1, Name: "own"}}},
+	}
+	lib := &Module{
+		MemorySection: &Memory{Min: 1, Max: 2, IsMaxEncoded: true},
+		GlobalSection: []Global{
+			{Type: GlobalType{ValType: i32}, Init: ConstantExpression{Opcode: OpcodeI32Const, Data: []byte{2}}},
+			{Type: GlobalType{ValType: i32}, Init: ConstantExpression{Opcode: OpcodeI32Const, Data: []byte{3}}},
+		},
+		TableSection: []Table{{Type: RefTypeFuncref, Min: 1}, {Type: RefTypeFuncref, Min: 1}},
+		ExportSection: []Export{
+			{Type: ExternTypeMemory, Name: "memory", Index: 0},
+			{Type: ExternTypeGlobal, Name: "g", Index: 1},
+			{Type: ExternTypeTable, Name: "t", Index: 1},
+		},
+	}
+
+	m, err := MergeModules(main, lib, "lib")
+	require.NoError(t, err)
+
+	require.Zero(t, len(m.ImportSection))
+	require.Equal(t, lib.MemorySection, m.MemorySection)
+	require.NotSame(t, lib.MemorySection, m.MemorySection)
+	require.Equal(t, 3, len(m.GlobalSection))
+	require.Equal(t, 2, len(m.TableSection))
+	require.Equal(t, []byte{
+		OpcodeGlobalGet, 2, OpcodeGlobalGet, 0, OpcodeI32Add,
+		OpcodeI32Const, 0, OpcodeI32Load, 0x2, 0x0, OpcodeI32Add,
+		OpcodeI32Const, 0, OpcodeTableGet, 1, OpcodeDrop,
+		OpcodeEnd,
+	}, m.CodeSection[0].Body)
+	require.Equal(t, []Export{
+		{Type: ExternTypeFunc, Name: "run", Index: 0},
+		{Type: ExternTypeMemory, Name: "memory", Index: 0},
+		{Type: ExternTypeGlobal, Name: "g", Index: 2},
+	}, m.ExportSection)
+	require.Equal(t, &NameSection{GlobalNames: NameMap{{Index: 0, Name: "own"}}}, m.NameSection)
+	require.NoError(t, m.Validate(api.CoreFeaturesV2))
+}
+
+func TestMergeModules_Errors(t *testing.T) {
+	start := Index(0)
+	tests := []struct {
+		name        string
+		main, lib   *Module
+		expectedErr string
+	}{
+		{
+			name:        "both have start",
+			main:        &Module{StartSection: &start},
+			lib:         &Module{StartSection: &start},
+			expectedErr: "both modules have a start function",
+		},
+		{
+			name:        "both have memory",
+			main:        &Module{MemorySection: &Memory{}},
+			lib:         &Module{ImportMemoryCount: 1},
+			expectedErr: "at most one memory allowed in module",
+		},
+		{
+			name: "both have memory when one is imported from lib",
+			main: &Module{
+				ImportSection: []Import{
+					{Type: ExternTypeMemory, Module: "env", Name: "memory", DescMem: &Memory{Min: 1}},
+				},
+				ImportMemoryCount: 1,
+			},
+			lib:         &Module{MemorySection: &Memory{Min: 1}},
+			expectedErr: "at most one memory allowed in module",
+		},
+		{
+			name: "global not exported",
+			main: &Module{
+				ImportSection: []Import{{Type: ExternTypeGlobal, Module: "lib", Name: "g"}},
+			},
+			lib: &Module{
+				FunctionSection: []Index{0},
+				ExportSection:   []Export{{Type: ExternTypeFunc, Name: "g", Index: 0}},
+			},
+			expectedErr: `import global[lib.g]: global is not exported by "lib"`,
+		},
+		{
+			name: "global mutability mismatch",
+			main: &Module{
+				ImportSection: []Import{{Type: ExternTypeGlobal, Module: "lib", Name: "g", DescGlobal: GlobalType{ValType: i32, Mutable: true}}},
+			},
+			lib: &Module{
+				GlobalSection: []Global{{Type: GlobalType{ValType: i32}}},
+				ExportSection: []Export{{Type: ExternTypeGlobal, Name: "g", Index: 0}},
+			},
+			expectedErr: "import global[lib.g]: mutability mismatch: true != false",
+		},
+		{
+			name: "table type mismatch",
+			main: &Module{
+				ImportSection: []Import{{Type: ExternTypeTable, Module: "lib", Name: "t", DescTable: Table{Type: RefTypeExternref}}},
+			},
+			lib: &Module{
+				TableSection:  []Table{{Type: RefTypeFuncref}},
+				ExportSection: []Export{{Type: ExternTypeTable, Name: "t", Index: 0}},
+			},
+			expectedErr: "import table[lib.t]: table type mismatch: externref != funcref",
+		},
+		{
+			name: "memory size mismatch",
+			main: &Module{
+				ImportSection: []Import{{Type: ExternTypeMemory, Module: "lib", Name: "memory", DescMem: &Memory{Min: 2}}},
+			},
+			lib: &Module{
+				MemorySection: &Memory{Min: 1},
+				ExportSection: []Export{{Type: ExternTypeMemory, Name: "memory", Index: 0}},
+			},
+			expectedErr: "import memory[lib.memory]: minimum size mismatch: 2 > 1",
+		},
+		{
+			name: "not exported",
+			main: &Module{
+				TypeSection:   []FunctionType{v_v},
+				ImportSection: []Import{{Type: ExternTypeFunc, Module: "lib", Name: "f"}},
+			},
+			lib:         &Module{},
+			expectedErr: `import func[lib.f]: func is not exported by "lib"`,
+		},
+		{
+			name: "signature mismatch",
+			main: &Module{
+				TypeSection:   []FunctionType{v_v},
+				ImportSection: []Import{{Type: ExternTypeFunc, Module: "lib", Name: "f"}},
+			},
+			lib: &Module{
+				TypeSection:     []FunctionType{v_i32},
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{{Body: []byte{OpcodeI32Const, 0, OpcodeEnd}}},
+				ExportSection:   []Export{{Type: ExternTypeFunc, Name: "f", Index: 0}},
+			},
+			expectedErr: "import func[lib.f]: signature mismatch: v_v != v_i32",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := MergeModules(tc.main, tc.lib, "lib")
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
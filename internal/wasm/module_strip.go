@@ -0,0 +1,43 @@
+package wasm
+
+import "strings"
+
+// dwarfSectionPrefix is the name prefix of custom sections which contain DWARF data.
+const dwarfSectionPrefix = ".debug_"
+
+// StripCustomSections removes all custom sections, including the name section, for example to reduce the size of a
+// module before encoding it for production. This doesn't affect how the module executes.
+func (m *Module) StripCustomSections() {
+	m.CustomSections = nil
+	m.NameSection = nil
+	m.DWARFLines = nil
+}
+
+// DWARFSections returns the data of each DWARF section, which are custom sections with the ".debug_" prefix, keyed by
+// name. For example, the data of ".debug_info" and ".debug_abbrev" can be passed to dwarf.New.
+//
+// Note: This is empty unless custom sections were decoded.
+func (m *Module) DWARFSections() map[string][]byte {
+	ret := map[string][]byte{}
+	for _, c := range m.CustomSections {
+		if strings.HasPrefix(c.Name, dwarfSectionPrefix) {
+			ret[c.Name] = c.Data
+		}
+	}
+	return ret
+}
+
+// StripDebug removes debug information: the name section and DWARF sections, which are custom sections with the
+// ".debug_" prefix. Other custom sections, such as "producers", are kept.
+func (m *Module) StripDebug() {
+	m.NameSection = nil
+	m.DWARFLines = nil
+
+	var kept []*CustomSection
+	for _, c := range m.CustomSections {
+		if !strings.HasPrefix(c.Name, dwarfSectionPrefix) {
+			kept = append(kept, c)
+		}
+	}
+	m.CustomSections = kept
+}
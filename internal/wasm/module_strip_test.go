@@ -0,0 +1,57 @@
+package wasm
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasmdebug"
+)
+
+func TestModule_StripCustomSections(t *testing.T) {
+	m := &Module{
+		TypeSection:    []FunctionType{v_v},
+		NameSection:    &NameSection{ModuleName: "m"},
+		CustomSections: []*CustomSection{{Name: "producers"}, {Name: ".debug_info"}},
+		DWARFLines:     &wasmdebug.DWARFLines{},
+	}
+	m.StripCustomSections()
+	require.Equal(t, &Module{TypeSection: []FunctionType{v_v}}, m)
+}
+
+func TestModule_StripDebug(t *testing.T) {
+	m := &Module{
+		TypeSection: []FunctionType{v_v},
+		NameSection: &NameSection{ModuleName: "m"},
+		CustomSections: []*CustomSection{
+			{Name: ".debug_info"},
+			{Name: "producers", Data: []byte{1}},
+			{Name: ".debug_line"},
+			{Name: "target_features", Data: []byte{2}},
+		},
+		DWARFLines: &wasmdebug.DWARFLines{},
+	}
+	m.StripDebug()
+	require.Equal(t, &Module{
+		TypeSection: []FunctionType{v_v},
+		CustomSections: []*CustomSection{
+			{Name: "producers", Data: []byte{1}},
+			{Name: "target_features", Data: []byte{2}},
+		},
+	}, m)
+}
+
+func TestModule_DWARFSections(t *testing.T) {
+	m := &Module{
+		CustomSections: []*CustomSection{
+			{Name: ".debug_info", Data: []byte{1}},
+			{Name: "producers", Data: []byte{2}},
+			{Name: ".debug_abbrev", Data: []byte{3}},
+		},
+	}
+	require.Equal(t, map[string][]byte{
+		".debug_info":   {1},
+		".debug_abbrev": {3},
+	}, m.DWARFSections())
+
+	require.Equal(t, map[string][]byte{}, (&Module{}).DWARFSections())
+}
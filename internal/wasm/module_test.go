@@ -121,6 +121,50 @@ func TestMemory_Validate(t *testing.T) {
 	}
 }
 
+func TestMemory_Satisfies(t *testing.T) {
+	tests := []struct {
+		name             string
+		actual, required *Memory
+		expectedErr      string
+	}{
+		{
+			name:     "same limits",
+			actual:   &Memory{Min: 1, Max: 2},
+			required: &Memory{Min: 1, Max: 2},
+		},
+		{
+			name:     "larger min and smaller max",
+			actual:   &Memory{Min: 2, Max: 3},
+			required: &Memory{Min: 1, Max: 4},
+		},
+		{
+			name:        "min too small",
+			actual:      &Memory{Min: 1, Max: 2},
+			required:    &Memory{Min: 2, Max: 2},
+			expectedErr: "minimum size mismatch: 2 > 1",
+		},
+		{
+			name:        "max too large",
+			actual:      &Memory{Min: 1, Max: MemoryLimitPages},
+			required:    &Memory{Min: 1, Max: 2},
+			expectedErr: "maximum size mismatch: 2 < 65536",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.actual.Satisfies(tc.required)
+			if tc.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
 func TestModule_allDeclarations(t *testing.T) {
 	tests := []struct {
 		module            *Module
@@ -208,6 +252,17 @@ func TestModule_allDeclarations(t *testing.T) {
 			require.Equal(t, tc.expectedMemory, memory)
 		})
 	}
+
+	t.Run("multiple memories", func(t *testing.T) {
+		memoryImport := Import{Type: ExternTypeMemory, DescMem: &Memory{Min: 1}}
+		for _, m := range []*Module{
+			{ImportSection: []Import{memoryImport, memoryImport}},
+			{ImportSection: []Import{memoryImport}, MemorySection: &Memory{Min: 1}},
+		} {
+			_, _, _, _, err := m.AllDeclarations()
+			require.EqualError(t, err, "at most one memory allowed in module")
+		}
+	})
 }
 
 func TestValidateConstExpression(t *testing.T) {
@@ -342,6 +397,20 @@ func TestModule_Validate_Errors(t *testing.T) {
 			},
 			expectedErr: "invalid start function: func[0] has an invalid type",
 		},
+		{
+			name: "export of a nonexistent global",
+			input: &Module{
+				ImportSection: []Import{
+					{Type: ExternTypeGlobal, Module: "env", Name: "g", DescGlobal: GlobalType{ValType: ValueTypeI32}},
+				},
+				ImportGlobalCount: 1,
+				GlobalSection: []Global{
+					{Type: GlobalType{ValType: ValueTypeI32}, Init: ConstantExpression{Opcode: OpcodeI32Const, Data: const0}},
+				},
+				ExportSection: []Export{{Type: ExternTypeGlobal, Name: "g2", Index: 2}},
+			},
+			expectedErr: `unknown global for export["g2"]`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -354,6 +423,370 @@ func TestModule_Validate_Errors(t *testing.T) {
 	}
 }
 
+func TestModule_Export(t *testing.T) {
+	exports := []Export{
+		{Type: ExternTypeFunc, Name: "fn", Index: 1},
+		{Type: ExternTypeMemory, Name: "memory", Index: 0},
+	}
+
+	t.Run("Exports", func(t *testing.T) {
+		m := &Module{ExportSection: exports, Exports: map[string]*Export{
+			"fn":     &exports[0],
+			"memory": &exports[1],
+		}}
+		exp, ok := m.Export("memory")
+		require.True(t, ok)
+		require.Equal(t, &exports[1], exp)
+
+		_, ok = m.Export("missing")
+		require.False(t, ok)
+	})
+
+	t.Run("ExportSection only", func(t *testing.T) {
+		m := &Module{ExportSection: exports}
+		exp, ok := m.Export("fn")
+		require.True(t, ok)
+		require.Equal(t, &exports[0], exp)
+
+		_, ok = m.Export("missing")
+		require.False(t, ok)
+	})
+}
+
+func TestModule_RemoveExport(t *testing.T) {
+	tests := []struct {
+		name  string
+		input *Module
+		// buildExports sets Exports from ExportSection before removing, as the decoder does.
+		buildExports          bool
+		exportName            string
+		expectedRemoved       bool
+		expectedExportSection []Export
+	}{
+		{
+			name: "function stays",
+			input: &Module{
+				TypeSection:     []FunctionType{v_v},
+				FunctionSection: []Index{0, 0},
+				CodeSection:     []Code{{Body: []byte{OpcodeEnd}}, {Body: []byte{OpcodeEnd}}},
+				ExportSection: []Export{
+					{Type: ExternTypeFunc, Name: "a", Index: 0},
+					{Type: ExternTypeFunc, Name: "b", Index: 1},
+				},
+			},
+			buildExports:          true,
+			exportName:            "a",
+			expectedRemoved:       true,
+			expectedExportSection: []Export{{Type: ExternTypeFunc, Name: "b", Index: 1}},
+		},
+		{
+			name: "ExportSection only",
+			input: &Module{
+				TypeSection:     []FunctionType{v_v},
+				FunctionSection: []Index{0, 0},
+				CodeSection:     []Code{{Body: []byte{OpcodeEnd}}, {Body: []byte{OpcodeEnd}}},
+				ExportSection: []Export{
+					{Type: ExternTypeFunc, Name: "a", Index: 0},
+					{Type: ExternTypeFunc, Name: "b", Index: 1},
+				},
+			},
+			exportName:            "b",
+			expectedRemoved:       true,
+			expectedExportSection: []Export{{Type: ExternTypeFunc, Name: "a", Index: 0}},
+		},
+		{
+			name: "not found",
+			input: &Module{
+				TypeSection:     []FunctionType{v_v},
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{{Body: []byte{OpcodeEnd}}},
+				ExportSection:   []Export{{Type: ExternTypeFunc, Name: "a", Index: 0}},
+			},
+			buildExports:          true,
+			exportName:            "missing",
+			expectedExportSection: []Export{{Type: ExternTypeFunc, Name: "a", Index: 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			m := tc.input
+			if tc.buildExports {
+				m.buildExports()
+			}
+			functionSection, codeSection := m.FunctionSection, m.CodeSection
+
+			require.Equal(t, tc.expectedRemoved, m.RemoveExport(tc.exportName))
+			require.False(t, m.RemoveExport(tc.exportName))
+			require.Equal(t, tc.expectedExportSection, m.ExportSection)
+			_, ok := m.Export(tc.exportName)
+			require.False(t, ok)
+
+			if tc.buildExports {
+				expectedExports := map[string]*Export{}
+				for i := range m.ExportSection {
+					expectedExports[m.ExportSection[i].Name] = &m.ExportSection[i]
+				}
+				require.Equal(t, expectedExports, m.Exports)
+			} else {
+				require.Nil(t, m.Exports)
+			}
+
+			// Only the export is removed, not what it exported.
+			require.Equal(t, functionSection, m.FunctionSection)
+			require.Equal(t, codeSection, m.CodeSection)
+			require.NoError(t, m.Validate(api.CoreFeaturesV2))
+		})
+	}
+}
+
+func TestModule_RenameExport(t *testing.T) {
+	tests := []struct {
+		name  string
+		input *Module
+		// buildExports sets Exports from ExportSection before renaming, as the decoder does.
+		buildExports          bool
+		oldName, newName      string
+		expectedErr           string
+		expectedExportSection []Export
+	}{
+		{
+			name: "ok",
+			input: &Module{ExportSection: []Export{
+				{Type: ExternTypeFunc, Name: "a", Index: 0},
+				{Type: ExternTypeMemory, Name: "memory", Index: 0},
+			}},
+			buildExports: true,
+			oldName:      "a",
+			newName:      "b",
+			expectedExportSection: []Export{
+				{Type: ExternTypeFunc, Name: "b", Index: 0},
+				{Type: ExternTypeMemory, Name: "memory", Index: 0},
+			},
+		},
+		{
+			name: "same name",
+			input: &Module{ExportSection: []Export{
+				{Type: ExternTypeFunc, Name: "a", Index: 0},
+			}},
+			buildExports:          true,
+			oldName:               "a",
+			newName:               "a",
+			expectedExportSection: []Export{{Type: ExternTypeFunc, Name: "a", Index: 0}},
+		},
+		{
+			name: "collision",
+			input: &Module{ExportSection: []Export{
+				{Type: ExternTypeFunc, Name: "a", Index: 0},
+				{Type: ExternTypeMemory, Name: "memory", Index: 0},
+			}},
+			buildExports: true,
+			oldName:      "a",
+			newName:      "memory",
+			expectedErr:  `export["memory"] already exists`,
+			expectedExportSection: []Export{
+				{Type: ExternTypeFunc, Name: "a", Index: 0},
+				{Type: ExternTypeMemory, Name: "memory", Index: 0},
+			},
+		},
+		{
+			name: "not found",
+			input: &Module{ExportSection: []Export{
+				{Type: ExternTypeFunc, Name: "a", Index: 0},
+			}},
+			buildExports:          true,
+			oldName:               "missing",
+			newName:               "b",
+			expectedErr:           `export["missing"] not found`,
+			expectedExportSection: []Export{{Type: ExternTypeFunc, Name: "a", Index: 0}},
+		},
+		{
+			name: "ExportSection only",
+			input: &Module{ExportSection: []Export{
+				{Type: ExternTypeFunc, Name: "a", Index: 0},
+			}},
+			oldName:               "a",
+			newName:               "b",
+			expectedExportSection: []Export{{Type: ExternTypeFunc, Name: "b", Index: 0}},
+		},
+		{
+			name: "ExportSection only collision",
+			input: &Module{ExportSection: []Export{
+				{Type: ExternTypeFunc, Name: "a", Index: 0},
+				{Type: ExternTypeMemory, Name: "memory", Index: 0},
+			}},
+			oldName:     "a",
+			newName:     "memory",
+			expectedErr: `export["memory"] already exists`,
+			expectedExportSection: []Export{
+				{Type: ExternTypeFunc, Name: "a", Index: 0},
+				{Type: ExternTypeMemory, Name: "memory", Index: 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			m := tc.input
+			if tc.buildExports {
+				m.buildExports()
+			}
+
+			err := m.RenameExport(tc.oldName, tc.newName)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.expectedExportSection, m.ExportSection)
+
+			if tc.buildExports {
+				expectedExports := map[string]*Export{}
+				for i := range m.ExportSection {
+					expectedExports[m.ExportSection[i].Name] = &m.ExportSection[i]
+				}
+				require.Equal(t, expectedExports, m.Exports)
+			} else {
+				require.Nil(t, m.Exports)
+			}
+		})
+	}
+}
+
+func TestModule_FunctionType(t *testing.T) {
+	m := &Module{
+		TypeSection: []FunctionType{v_v, i32_i32, v_i32},
+		ImportSection: []Import{
+			{Type: ExternTypeFunc, Module: "env", Name: "f", DescFunc: 1},
+			{Type: ExternTypeMemory, Module: "env", Name: "memory", DescMem: &Memory{}},
+			{Type: ExternTypeFunc, Module: "env", Name: "g", DescFunc: 2},
+		},
+		ImportFunctionCount: 2,
+		FunctionSection:     []Index{0, 1, 3 /* invalid */},
+	}
+
+	tests := []struct {
+		name     string
+		index    Index
+		expected *FunctionType
+	}{
+		{name: "imported", index: 0, expected: &m.TypeSection[1]},
+		{name: "imported after memory", index: 1, expected: &m.TypeSection[2]},
+		{name: "defined", index: 2, expected: &m.TypeSection[0]},
+		{name: "defined last", index: 3, expected: &m.TypeSection[1]},
+		{name: "invalid type index", index: 4},
+		{name: "out of range", index: 5},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			ft, ok := m.FunctionType(tc.index)
+			if tc.expected == nil {
+				require.False(t, ok)
+				require.Nil(t, ft)
+			} else {
+				require.True(t, ok)
+				require.Equal(t, tc.expected, ft)
+			}
+		})
+	}
+}
+
+func TestModule_Validate_ExportIndexes(t *testing.T) {
+	// Exports index the space of each kind, where imports precede definitions.
+	m := &Module{
+		TypeSection: []FunctionType{v_v},
+		ImportSection: []Import{
+			{Type: ExternTypeFunc, Module: "env", Name: "f", DescFunc: 0},
+			{Type: ExternTypeGlobal, Module: "env", Name: "g", DescGlobal: GlobalType{ValType: ValueTypeI32}},
+			{Type: ExternTypeTable, Module: "env", Name: "t", DescTable: Table{Type: RefTypeFuncref}},
+			{Type: ExternTypeMemory, Module: "env", Name: "m", DescMem: &Memory{}},
+		},
+		ImportFunctionCount: 1,
+		ImportGlobalCount:   1,
+		ImportTableCount:    1,
+		ImportMemoryCount:   1,
+		FunctionSection:     []Index{0},
+		CodeSection:         []Code{{Body: []byte{OpcodeEnd}}},
+		GlobalSection: []Global{
+			{Type: GlobalType{ValType: ValueTypeI32}, Init: ConstantExpression{Opcode: OpcodeI32Const, Data: const0}},
+		},
+		ExportSection: []Export{
+			{Type: ExternTypeFunc, Name: "imported func", Index: 0},
+			{Type: ExternTypeFunc, Name: "func", Index: 1},
+			{Type: ExternTypeGlobal, Name: "imported global", Index: 0},
+			{Type: ExternTypeGlobal, Name: "global", Index: 1},
+			{Type: ExternTypeTable, Name: "imported table", Index: 0},
+			{Type: ExternTypeMemory, Name: "imported memory", Index: 0},
+		},
+	}
+	require.NoError(t, m.Validate(api.CoreFeaturesV2))
+}
+
+func TestModule_ValidateCommand(t *testing.T) {
+	start := Index(0)
+	tests := []struct {
+		name        string
+		module      *Module
+		expectedErr string
+	}{
+		{
+			name: "exports _start",
+			module: &Module{
+				TypeSection:     []FunctionType{v_v},
+				FunctionSection: []Index{0},
+				Exports:         map[string]*Export{"_start": {Type: ExternTypeFunc, Name: "_start", Index: 0}},
+			},
+		},
+		{
+			name: "start section",
+			module: &Module{
+				TypeSection:     []FunctionType{v_v},
+				FunctionSection: []Index{0},
+				StartSection:    &start,
+			},
+		},
+		{
+			name:        "neither",
+			module:      &Module{},
+			expectedErr: `command must export a "_start" function or have a start section`,
+		},
+		{
+			name: "_start is a global",
+			module: &Module{
+				Exports: map[string]*Export{"_start": {Type: ExternTypeGlobal, Name: "_start", Index: 0}},
+			},
+			expectedErr: `export "_start" is a global, but must be a function`,
+		},
+		{
+			name: "_start has params",
+			module: &Module{
+				TypeSection:     []FunctionType{i32_v},
+				FunctionSection: []Index{0},
+				Exports:         map[string]*Export{"_start": {Type: ExternTypeFunc, Name: "_start", Index: 0}},
+			},
+			expectedErr: `export "_start" must have an empty (nullary) signature: i32_v`,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.module.ValidateCommand()
+			if tc.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
 func TestModule_validateStartSection(t *testing.T) {
 	t.Run("no start section", func(t *testing.T) {
 		m := Module{}
@@ -452,6 +885,48 @@ func TestModule_validateGlobals(t *testing.T) {
 		err := m.validateGlobals(globalDeclarations, 0, 9)
 		require.NoError(t, err)
 	})
+	t.Run("reference to defined global", func(t *testing.T) {
+		m := Module{
+			ImportGlobalCount: 1,
+			GlobalSection: []Global{
+				{
+					Type: GlobalType{ValType: ValueTypeI32},
+					Init: ConstantExpression{Opcode: OpcodeI32Const, Data: const0},
+				},
+				{
+					Type: GlobalType{ValType: ValueTypeI32},
+					// Trying to reference globals[1] which is defined, so not yet initialized.
+					Init: ConstantExpression{Opcode: OpcodeGlobalGet, Data: []byte{1}},
+				},
+			},
+			ImportSection: []Import{{Type: ExternTypeGlobal}},
+		}
+		globalDeclarations := []GlobalType{
+			{ValType: ValueTypeI32}, // Imported one.
+			{ValType: ValueTypeI32}, // the local ones.
+			{ValType: ValueTypeI32},
+		}
+		err := m.validateGlobals(globalDeclarations, 0, 9)
+		require.EqualError(t, err, "global index out of range")
+	})
+	t.Run("reference to mutable imported global", func(t *testing.T) {
+		m := Module{
+			ImportGlobalCount: 1,
+			GlobalSection: []Global{
+				{
+					Type: GlobalType{ValType: ValueTypeI32},
+					Init: ConstantExpression{Opcode: OpcodeGlobalGet, Data: []byte{0}},
+				},
+			},
+			ImportSection: []Import{{Type: ExternTypeGlobal}},
+		}
+		globalDeclarations := []GlobalType{
+			{ValType: ValueTypeI32, Mutable: true}, // Imported one.
+			{ValType: ValueTypeI32},                // the local one trying to validate.
+		}
+		err := m.validateGlobals(globalDeclarations, 0, 9)
+		require.EqualError(t, err, "global.get of mutable global[0] is not a constant expression")
+	})
 }
 
 func TestModule_validateFunctions(t *testing.T) {
@@ -461,12 +936,12 @@ func TestModule_validateFunctions(t *testing.T) {
 			FunctionSection: []uint32{0},
 			CodeSection:     []Code{{Body: []byte{OpcodeI32Const, 0, OpcodeDrop, OpcodeEnd}}},
 		}
-		err := m.validateFunctions(api.CoreFeaturesV1, nil, nil, nil, nil, MaximumFunctionIndex)
+		err := m.validateFunctions(api.CoreFeaturesV1, nil, nil, nil, nil, MaximumFunctionIndex, MaximumBlockDepth)
 		require.NoError(t, err)
 	})
 	t.Run("too many functions", func(t *testing.T) {
 		m := Module{}
-		err := m.validateFunctions(api.CoreFeaturesV1, []uint32{1, 2, 3, 4}, nil, nil, nil, 3)
+		err := m.validateFunctions(api.CoreFeaturesV1, []uint32{1, 2, 3, 4}, nil, nil, nil, 3, MaximumBlockDepth)
 		require.Error(t, err)
 		require.EqualError(t, err, "too many functions (4) in a module")
 	})
@@ -476,7 +951,7 @@ func TestModule_validateFunctions(t *testing.T) {
 			FunctionSection: []Index{0},
 			CodeSection:     nil,
 		}
-		err := m.validateFunctions(api.CoreFeaturesV1, nil, nil, nil, nil, MaximumFunctionIndex)
+		err := m.validateFunctions(api.CoreFeaturesV1, nil, nil, nil, nil, MaximumFunctionIndex, MaximumBlockDepth)
 		require.Error(t, err)
 		require.EqualError(t, err, "code count (0) != function count (1)")
 	})
@@ -486,7 +961,7 @@ func TestModule_validateFunctions(t *testing.T) {
 			FunctionSection: []Index{1},
 			CodeSection:     []Code{{Body: []byte{OpcodeEnd}}},
 		}
-		err := m.validateFunctions(api.CoreFeaturesV1, nil, nil, nil, nil, MaximumFunctionIndex)
+		err := m.validateFunctions(api.CoreFeaturesV1, nil, nil, nil, nil, MaximumFunctionIndex, MaximumBlockDepth)
 		require.Error(t, err)
 		require.EqualError(t, err, "invalid function[0]: type section index 1 out of range")
 	})
@@ -496,7 +971,7 @@ func TestModule_validateFunctions(t *testing.T) {
 			FunctionSection: []Index{0},
 			CodeSection:     []Code{{Body: []byte{OpcodeF32Abs}}},
 		}
-		err := m.validateFunctions(api.CoreFeaturesV1, nil, nil, nil, nil, MaximumFunctionIndex)
+		err := m.validateFunctions(api.CoreFeaturesV1, nil, nil, nil, nil, MaximumFunctionIndex, MaximumBlockDepth)
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "invalid function[0]: cannot pop the 1st f32 operand")
 	})
@@ -507,7 +982,7 @@ func TestModule_validateFunctions(t *testing.T) {
 			CodeSection:     []Code{{Body: []byte{OpcodeF32Abs}}},
 			ExportSection:   []Export{{Name: "f1", Type: ExternTypeFunc, Index: 0}},
 		}
-		err := m.validateFunctions(api.CoreFeaturesV1, nil, nil, nil, nil, MaximumFunctionIndex)
+		err := m.validateFunctions(api.CoreFeaturesV1, nil, nil, nil, nil, MaximumFunctionIndex, MaximumBlockDepth)
 		require.Error(t, err)
 		require.Contains(t, err.Error(), `invalid function[0] export["f1"]: cannot pop the 1st f32`)
 	})
@@ -520,7 +995,7 @@ func TestModule_validateFunctions(t *testing.T) {
 			CodeSection:         []Code{{Body: []byte{OpcodeF32Abs}}},
 			ExportSection:       []Export{{Name: "f1", Type: ExternTypeFunc, Index: 1}},
 		}
-		err := m.validateFunctions(api.CoreFeaturesV1, nil, nil, nil, nil, MaximumFunctionIndex)
+		err := m.validateFunctions(api.CoreFeaturesV1, nil, nil, nil, nil, MaximumFunctionIndex, MaximumBlockDepth)
 		require.Error(t, err)
 		require.Contains(t, err.Error(), `invalid function[0] export["f1"]: cannot pop the 1st f32`)
 	})
@@ -534,10 +1009,57 @@ func TestModule_validateFunctions(t *testing.T) {
 				{Name: "f2", Type: ExternTypeFunc, Index: 0},
 			},
 		}
-		err := m.validateFunctions(api.CoreFeaturesV1, nil, nil, nil, nil, MaximumFunctionIndex)
+		err := m.validateFunctions(api.CoreFeaturesV1, nil, nil, nil, nil, MaximumFunctionIndex, MaximumBlockDepth)
 		require.Error(t, err)
 		require.Contains(t, err.Error(), `invalid function[0] export["f1","f2"]: cannot pop the 1st f32`)
 	})
+	t.Run("ref.func", func(t *testing.T) {
+		refFunc := func(index byte) []byte {
+			return []byte{OpcodeRefFunc, index, OpcodeDrop, OpcodeEnd}
+		}
+		for _, tc := range []struct {
+			name        string
+			module      *Module
+			expectedErr string
+		}{
+			{
+				name: "declared by export",
+				module: &Module{
+					FunctionSection: []Index{0, 0},
+					CodeSection:     []Code{{Body: refFunc(1)}, {Body: []byte{OpcodeEnd}}},
+					ExportSection:   []Export{{Name: "f", Type: ExternTypeFunc, Index: 1}},
+				},
+			},
+			{
+				name: "declared by element",
+				module: &Module{
+					FunctionSection: []Index{0, 0},
+					CodeSection:     []Code{{Body: refFunc(1)}, {Body: []byte{OpcodeEnd}}},
+					ElementSection:  []ElementSegment{{Mode: ElementModeDeclarative, Init: []Index{1}}},
+				},
+			},
+			{
+				name: "undeclared",
+				module: &Module{
+					FunctionSection: []Index{0, 0},
+					CodeSection:     []Code{{Body: refFunc(1)}, {Body: []byte{OpcodeEnd}}},
+					ExportSection:   []Export{{Name: "f", Type: ExternTypeFunc, Index: 0}},
+				},
+				expectedErr: `invalid function[0] export["f"]: undeclared function index 1 for ref.func`,
+			},
+		} {
+			tc := tc
+			t.Run(tc.name, func(t *testing.T) {
+				tc.module.TypeSection = []FunctionType{v_v}
+				err := tc.module.validateFunctions(api.CoreFeaturesV2, []Index{0, 0}, nil, nil, nil, MaximumFunctionIndex, MaximumBlockDepth)
+				if tc.expectedErr == "" {
+					require.NoError(t, err)
+				} else {
+					require.EqualError(t, err, tc.expectedErr)
+				}
+			})
+		}
+	})
 }
 
 func TestModule_validateMemory(t *testing.T) {
@@ -556,6 +1078,33 @@ func TestModule_validateMemory(t *testing.T) {
 		err := m.validateMemory(&Memory{}, nil, api.CoreFeaturesV1)
 		require.EqualError(t, err, "calculate offset: invalid opcode for const expression: 0x0")
 	})
+	t.Run("i64 offset", func(t *testing.T) {
+		m := Module{DataSection: []DataSegment{{
+			Init: []byte{0x1},
+			OffsetExpression: ConstantExpression{
+				Opcode: OpcodeI64Const, // Memory offsets must be i32 in WebAssembly 1.0 (20191205)
+				Data:   leb128.EncodeInt64(1),
+			},
+		}}}
+		err := m.validateMemory(&Memory{}, nil, api.CoreFeaturesV1)
+		require.EqualError(t, err, "calculate offset: const expression type mismatch expected i32 but got i64")
+	})
+	t.Run("f32 offset", func(t *testing.T) {
+		m := Module{DataSection: []DataSegment{{
+			Init: []byte{0x1},
+			OffsetExpression: ConstantExpression{
+				Opcode: OpcodeF32Const,
+				Data:   u64.LeBytes(api.EncodeF32(1))[:4],
+			},
+		}}}
+		err := m.validateMemory(&Memory{}, nil, api.CoreFeaturesV1)
+		require.EqualError(t, err, "calculate offset: const expression type mismatch expected i32 but got f32")
+	})
+	t.Run("64-bit memory", func(t *testing.T) {
+		m := Module{}
+		err := m.validateMemory(&Memory{Is64: true}, nil, api.CoreFeaturesV2|experimental.CoreFeaturesMemory64)
+		require.EqualError(t, err, "64-bit memory is not supported")
+	})
 	t.Run("ok", func(t *testing.T) {
 		m := Module{DataSection: []DataSegment{{
 			Init: []byte{0x1},
@@ -594,6 +1143,16 @@ func TestModule_validateImports(t *testing.T) {
 			i:               &Import{Module: "m", Name: "n", Type: ExternTypeFunc, DescFunc: 100},
 			expectedErr:     "invalid import[\"m\".\"n\"] function: type index out of range",
 		},
+		{
+			name:            "global var",
+			enabledFeatures: api.CoreFeaturesV1,
+			i: &Import{
+				Module:     "m",
+				Name:       "n",
+				Type:       ExternTypeGlobal,
+				DescGlobal: GlobalType{ValType: ValueTypeI32, Mutable: true},
+			},
+		},
 		{
 			name:            "global var disabled",
 			enabledFeatures: api.CoreFeaturesV1.SetEnabled(api.CoreFeatureMutableGlobal, false),
@@ -838,7 +1397,8 @@ func TestModule_buildGlobals(t *testing.T) {
 func TestModule_buildMemoryInstance(t *testing.T) {
 	t.Run("nil", func(t *testing.T) {
 		m := ModuleInstance{}
-		m.buildMemory(&Module{})
+		err := m.buildMemory(&Module{})
+		require.NoError(t, err)
 		require.Nil(t, m.MemoryInstance)
 	})
 	t.Run("non-nil", func(t *testing.T) {
@@ -846,10 +1406,11 @@ func TestModule_buildMemoryInstance(t *testing.T) {
 		max := uint32(10)
 		mDef := MemoryDefinition{moduleName: "foo"}
 		m := ModuleInstance{}
-		m.buildMemory(&Module{
+		err := m.buildMemory(&Module{
 			MemorySection:           &Memory{Min: min, Cap: min, Max: max},
 			MemoryDefinitionSection: []MemoryDefinition{mDef},
 		})
+		require.NoError(t, err)
 		mem := m.MemoryInstance
 		require.Equal(t, min, mem.Min)
 		require.Equal(t, max, mem.Max)
@@ -943,6 +1504,18 @@ func TestModule_declaredFunctionIndexes(t *testing.T) {
 			},
 			exp: map[uint32]struct{}{0: {}, 1: {}, 2: {}, 5: {}},
 		},
+		{
+			name: "element global.get",
+			mod: &Module{
+				ElementSection: []ElementSegment{
+					{
+						Mode: ElementModePassive,
+						Init: []Index{3, ElementInitImportedGlobalFunctionReference | 1},
+					},
+				},
+			},
+			exp: map[uint32]struct{}{3: {}},
+		},
 		{
 			name: "all",
 			mod: &Module{
@@ -1011,6 +1584,103 @@ func TestModule_declaredFunctionIndexes(t *testing.T) {
 	}
 }
 
+func TestModule_Imports(t *testing.T) {
+	mem := &Memory{Min: 1, Cap: 1, Max: 2, IsMaxEncoded: true}
+	m := &Module{
+		TypeSection: []FunctionType{{}, {Params: []ValueType{i32}, Results: []ValueType{i32}}},
+		ImportSection: []Import{
+			{Type: ExternTypeFunc, Module: "env", Name: "add", DescFunc: 1},
+			{Type: ExternTypeMemory, Module: "env", Name: "memory", DescMem: mem},
+		},
+	}
+
+	require.Equal(t, []ImportDesc{
+		{Module: "env", Name: "add", Type: ExternTypeFunc, FuncType: &m.TypeSection[1]},
+		{Module: "env", Name: "memory", Type: ExternTypeMemory, Memory: mem},
+	}, m.Imports())
+	require.Zero(t, len((&Module{}).Imports()))
+}
+
+func TestModule_AddMemory(t *testing.T) {
+	t.Run("exported", func(t *testing.T) {
+		max := uint32(10)
+		m := &Module{
+			TypeSection:     []FunctionType{v_v},
+			FunctionSection: []Index{0},
+			CodeSection:     []Code{{Body: []byte{OpcodeEnd}}},
+			ExportSection:   []Export{{Type: ExternTypeFunc, Name: "run"}},
+		}
+		require.NoError(t, m.AddMemory(1, &max, "memory"))
+
+		require.Equal(t, &Memory{Min: 1, Cap: 1, Max: 10, IsMaxEncoded: true}, m.MemorySection)
+		require.Equal(t, []Export{
+			{Type: ExternTypeFunc, Name: "run"},
+			{Type: ExternTypeMemory, Name: "memory"},
+		}, m.ExportSection)
+		require.Equal(t, &m.ExportSection[1], m.Exports["memory"])
+		require.Equal(t, &m.ExportSection[0], m.Exports["run"])
+		require.NoError(t, m.Validate(api.CoreFeaturesV2))
+	})
+
+	t.Run("not exported", func(t *testing.T) {
+		m := &Module{}
+		require.NoError(t, m.AddMemory(1, nil, ""))
+
+		require.Equal(t, &Memory{Min: 1, Cap: 1, Max: MemoryLimitPages}, m.MemorySection)
+		require.Nil(t, m.ExportSection)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		max := uint32(1)
+		tests := []struct {
+			name        string
+			module      *Module
+			min         uint32
+			max         *uint32
+			exportName  string
+			expectedErr string
+		}{
+			{
+				name:        "second memory",
+				module:      &Module{MemorySection: &Memory{}},
+				expectedErr: "at most one memory allowed in module",
+			},
+			{
+				name:        "imported memory",
+				module:      &Module{ImportMemoryCount: 1},
+				expectedErr: "at most one memory allowed in module",
+			},
+			{
+				name:        "duplicate export",
+				module:      &Module{ExportSection: []Export{{Type: ExternTypeFunc, Name: "memory"}}},
+				exportName:  "memory",
+				expectedErr: `export["memory"] already exists`,
+			},
+			{
+				name:        "min > max",
+				module:      &Module{},
+				min:         2,
+				max:         &max,
+				expectedErr: "min 2 pages (128 Ki) > max 1 pages (64 Ki)",
+			},
+		}
+
+		for _, tt := range tests {
+			tc := tt
+			t.Run(tc.name, func(t *testing.T) {
+				err := tc.module.AddMemory(tc.min, tc.max, tc.exportName)
+				require.EqualError(t, err, tc.expectedErr)
+			})
+		}
+	})
+
+	t.Run("second AddMemory", func(t *testing.T) {
+		m := &Module{}
+		require.NoError(t, m.AddMemory(1, nil, "memory"))
+		require.EqualError(t, m.AddMemory(1, nil, "memory2"), "at most one memory allowed in module")
+	})
+}
+
 func TestModule_AssignModuleID(t *testing.T) {
 	getID := func(bin []byte, lsns []experimental.FunctionListener, withEnsureTermination bool) ModuleID {
 		m := Module{}
@@ -0,0 +1,171 @@
+package wasm
+
+import (
+	"fmt"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+)
+
+// RequiredFeatures returns the post-MVP features this module uses, so that embedders can tell which features must be
+// enabled to run it. For example, a module using OpcodeMiscMemoryCopy requires api.CoreFeatureBulkMemoryOperations.
+//
+// Note: The module must have been validated, as this only decodes enough of each function body to find the next
+// instruction.
+func (m *Module) RequiredFeatures() (ret api.CoreFeatures, err error) {
+	valueTypes := func(types []ValueType) {
+		for _, vt := range types {
+			switch vt {
+			case ValueTypeV128:
+				ret |= api.CoreFeatureSIMD
+			case ValueTypeFuncref, ValueTypeExternref:
+				ret |= api.CoreFeatureReferenceTypes
+			}
+		}
+	}
+
+	for i := range m.TypeSection {
+		ft := &m.TypeSection[i]
+		valueTypes(ft.Params)
+		valueTypes(ft.Results)
+		if len(ft.Results) > 1 {
+			ret |= api.CoreFeatureMultiValue
+		}
+	}
+
+	// mutableGlobals is whether each global, imported then defined, is mutable.
+	mutableGlobals := make([]bool, 0, m.ImportGlobalCount+uint32(len(m.GlobalSection)))
+	for i := range m.ImportSection {
+		switch imp := &m.ImportSection[i]; imp.Type {
+		case ExternTypeGlobal:
+			mutableGlobals = append(mutableGlobals, imp.DescGlobal.Mutable)
+			valueTypes([]ValueType{imp.DescGlobal.ValType})
+			if imp.DescGlobal.Mutable {
+				ret |= api.CoreFeatureMutableGlobal
+			}
+		case ExternTypeTable:
+			valueTypes([]ValueType{imp.DescTable.Type})
+		case ExternTypeMemory:
+			if imp.DescMem.IsShared {
+				ret |= experimental.CoreFeaturesThreads
+			}
+			if imp.DescMem.Is64 {
+				ret |= experimental.CoreFeaturesMemory64
+			}
+		}
+	}
+
+	if m.ImportTableCount+uint32(len(m.TableSection)) > 1 {
+		ret |= api.CoreFeatureReferenceTypes
+	}
+	for i := range m.TableSection {
+		valueTypes([]ValueType{m.TableSection[i].Type})
+	}
+
+	if mem := m.MemorySection; mem != nil {
+		if mem.IsShared {
+			ret |= experimental.CoreFeaturesThreads
+		}
+		if mem.Is64 {
+			ret |= experimental.CoreFeaturesMemory64
+		}
+	}
+
+	for i := range m.GlobalSection {
+		g := &m.GlobalSection[i]
+		mutableGlobals = append(mutableGlobals, g.Type.Mutable)
+		valueTypes([]ValueType{g.Type.ValType})
+	}
+
+	for i := range m.ExportSection {
+		exp := &m.ExportSection[i]
+		if exp.Type != ExternTypeGlobal {
+			continue
+		}
+		if exp.Index >= uint32(len(mutableGlobals)) {
+			return 0, fmt.Errorf("global for export[%q] out of range", exp.Name)
+		}
+		if mutableGlobals[exp.Index] {
+			ret |= api.CoreFeatureMutableGlobal
+		}
+	}
+
+	for i := range m.ElementSection {
+		elem := &m.ElementSection[i]
+		if !elem.IsActive() {
+			ret |= api.CoreFeatureBulkMemoryOperations
+		}
+		if elem.TableIndex != 0 || elem.Type != RefTypeFuncref {
+			ret |= api.CoreFeatureReferenceTypes
+		}
+	}
+
+	if m.DataCountSection != nil {
+		ret |= api.CoreFeatureBulkMemoryOperations
+	}
+	for i := range m.DataSection {
+		if m.DataSection[i].IsPassive() {
+			ret |= api.CoreFeatureBulkMemoryOperations
+		}
+	}
+
+	for i := range m.CodeSection {
+		code := &m.CodeSection[i]
+		if code.GoFunc != nil {
+			continue
+		}
+		valueTypes(code.LocalTypes)
+
+		immediates, walkErr := walkInstructions(code.Body, func(op Opcode, subOp uint32, _ uint64) {
+			ret |= instructionFeature(op, subOp)
+		})
+		if walkErr != nil {
+			return 0, fmt.Errorf("%s: %w", m.funcDesc(SectionIDCode, Index(i)), walkErr)
+		}
+		for _, imm := range immediates {
+			switch imm.kind {
+			case indexKindType:
+				// Block types which are type indexes may have params or multiple results.
+				if !imm.signed {
+					continue // call_indirect
+				}
+				if imm.index >= uint32(len(m.TypeSection)) {
+					return 0, fmt.Errorf("%s: block type index %d out of range", m.funcDesc(SectionIDCode, Index(i)), imm.index)
+				}
+				if ft := &m.TypeSection[imm.index]; len(ft.Params) > 0 || len(ft.Results) > 1 {
+					ret |= api.CoreFeatureMultiValue
+				}
+			case indexKindTable:
+				if imm.index != 0 {
+					ret |= api.CoreFeatureReferenceTypes
+				}
+			}
+		}
+	}
+	return
+}
+
+// instructionFeature returns the feature required by the instruction, or zero if it is in the MVP.
+func instructionFeature(op Opcode, subOp uint32) api.CoreFeatures {
+	switch {
+	case OpcodeI32Extend8S <= op && op <= OpcodeI64Extend32S:
+		return api.CoreFeatureSignExtensionOps
+	case op == OpcodeTypedSelect, op == OpcodeRefNull, op == OpcodeRefIsNull, op == OpcodeRefFunc,
+		op == OpcodeTableGet, op == OpcodeTableSet:
+		return api.CoreFeatureReferenceTypes
+	case op == OpcodeMiscPrefix:
+		switch miscOp := OpcodeMisc(subOp); {
+		case miscOp <= OpcodeMiscI64TruncSatF64U:
+			return api.CoreFeatureNonTrappingFloatToIntConversion
+		case miscOp <= OpcodeMiscTableCopy:
+			return api.CoreFeatureBulkMemoryOperations
+		default: // table.grow, table.size and table.fill
+			return api.CoreFeatureReferenceTypes
+		}
+	case op == OpcodeVecPrefix:
+		return api.CoreFeatureSIMD
+	case op == OpcodeAtomicPrefix:
+		return experimental.CoreFeaturesThreads
+	}
+	return 0
+}
@@ -0,0 +1,126 @@
+package wasm
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestModule_RequiredFeatures(t *testing.T) {
+	tests := []struct {
+		name     string
+		module   *Module
+		expected api.CoreFeatures
+	}{
+		{
+			name:   "mvp",
+			module: &Module{TypeSection: []FunctionType{v_v}, FunctionSection: []Index{0}, CodeSection: []Code{{Body: []byte{OpcodeEnd}}}},
+		},
+		{
+			name: "memory.copy",
+			module: &Module{
+				TypeSection:     []FunctionType{v_v},
+				FunctionSection: []Index{0},
+				MemorySection:   &Memory{Min: 1},
+				CodeSection: []Code{{Body: []byte{
+					OpcodeI32Const, 0, OpcodeI32Const, 0, OpcodeI32Const, 0,
+					OpcodeMiscPrefix, OpcodeMiscMemoryCopy, 0, 0,
+					OpcodeEnd,
+				}}},
+			},
+			expected: api.CoreFeatureBulkMemoryOperations,
+		},
+		{
+			name: "instructions",
+			module: &Module{
+				TypeSection:     []FunctionType{v_v, i32i32_i32},
+				FunctionSection: []Index{0},
+				CodeSection: []Code{{Body: []byte{
+					OpcodeI32Const, 0, OpcodeI32Extend8S, OpcodeDrop,
+					OpcodeF32Const, 0, 0, 0, 0, OpcodeMiscPrefix, OpcodeMiscI32TruncSatF32S, OpcodeDrop,
+					OpcodeRefNull, RefTypeExternref, OpcodeDrop,
+					OpcodeI32Const, 0, OpcodeI32Const, 0,
+					OpcodeBlock, 0x01, // type index 1 has params
+					OpcodeI32Add,
+					OpcodeEnd,
+					OpcodeDrop,
+					OpcodeEnd,
+				}}},
+			},
+			expected: api.CoreFeatureSignExtensionOps | api.CoreFeatureNonTrappingFloatToIntConversion |
+				api.CoreFeatureReferenceTypes | api.CoreFeatureMultiValue,
+		},
+		{
+			name: "sections",
+			module: &Module{
+				TypeSection: []FunctionType{{Params: []ValueType{ValueTypeV128}}},
+				ImportSection: []Import{
+					{Type: ExternTypeMemory, DescMem: &Memory{Min: 1, Max: 1, IsShared: true}},
+				},
+				ImportMemoryCount: 1,
+				GlobalSection: []Global{
+					{Type: GlobalType{ValType: ValueTypeI32, Mutable: true}, Init: ConstantExpression{Opcode: OpcodeI32Const, Data: []byte{0}}},
+				},
+				ExportSection: []Export{{Type: ExternTypeGlobal, Name: "g", Index: 0}},
+				DataSection:   []DataSegment{{Passive: true}},
+			},
+			expected: api.CoreFeatureSIMD | experimental.CoreFeaturesThreads | api.CoreFeatureMutableGlobal |
+				api.CoreFeatureBulkMemoryOperations,
+		},
+		{
+			name:     "64-bit memory",
+			module:   &Module{MemorySection: &Memory{Min: 1, Max: 1, Is64: true}},
+			expected: experimental.CoreFeaturesMemory64,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := tc.module.RequiredFeatures()
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestModule_RequiredFeatures_Errors(t *testing.T) {
+	tests := []struct {
+		name, expectedErr string
+		module            *Module
+	}{
+		{
+			name:        "exported global out of range",
+			module:      &Module{ExportSection: []Export{{Type: ExternTypeGlobal, Name: "g", Index: 0}}},
+			expectedErr: `global for export["g"] out of range`,
+		},
+		{
+			name: "block type index out of range",
+			module: &Module{
+				TypeSection:     []FunctionType{v_v},
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{{Body: []byte{OpcodeBlock, 1, OpcodeEnd, OpcodeEnd}}},
+			},
+			expectedErr: "code[0]: block type index 1 out of range",
+		},
+		{
+			name: "truncated body",
+			module: &Module{
+				TypeSection:     []FunctionType{v_v},
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{{Body: []byte{OpcodeBlock}}},
+			},
+			expectedErr: "code[0]: read immediates of block: unexpected EOF",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.module.RequiredFeatures()
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
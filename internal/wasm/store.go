@@ -3,7 +3,10 @@ package wasm
 import (
 	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 
@@ -58,6 +61,12 @@ type (
 		// Note: this is fixed to 2^27 but have this a field for testability.
 		functionMaxTypes uint32
 
+		// memoryBudget is non-nil when SetMemoryBudget limits the total memory pages of modules in this store.
+		memoryBudget *memoryBudget // guarded by mux
+
+		// deterministicProfile is set by SetDeterministicProfile.
+		deterministicProfile bool // guarded by mux
+
 		// mux is used to guard the fields from concurrent access.
 		mux sync.RWMutex
 	}
@@ -126,6 +135,10 @@ type (
 
 		// CloseNotifier is an experimental hook called once on close.
 		CloseNotifier close.Notifier
+
+		// DeterministicProfile is true when Store.SetDeterministicProfile was enabled when this module was
+		// instantiated. ModuleEngine must then make NaN results of floating-point instructions canonical.
+		DeterministicProfile bool
 	}
 
 	// DataInstance holds bytes corresponding to the data segment in a module.
@@ -332,6 +345,9 @@ func (s *Store) instantiate(
 	typeIDs []FunctionTypeID,
 ) (m *ModuleInstance, err error) {
 	m = &ModuleInstance{ModuleName: name, TypeIDs: typeIDs, Sys: sysCtx, s: s, Source: module}
+	s.mux.RLock()
+	m.DeterministicProfile = s.deterministicProfile
+	s.mux.RUnlock()
 
 	m.Tables = make([]*TableInstance, int(module.ImportTableCount)+len(module.TableSection))
 	m.Globals = make([]*GlobalInstance, int(module.ImportGlobalCount)+len(module.GlobalSection))
@@ -352,7 +368,17 @@ func (s *Store) instantiate(
 	}
 
 	m.buildGlobals(module, m.Engine.FunctionInstanceReference)
-	m.buildMemory(module)
+	if err = m.buildMemory(module); err != nil {
+		return nil, err
+	}
+	if mem := m.MemoryInstance; mem != nil && module.MemorySection != nil {
+		// Return the memory pages to the budget, as this module won't be closed on error.
+		defer func() {
+			if err != nil {
+				mem.releaseMemoryBudget()
+			}
+		}()
+	}
 	m.Exports = module.Exports
 
 	// As of reference types proposal, data segment validation must happen after instantiation,
@@ -390,26 +416,37 @@ func (s *Store) instantiate(
 	return
 }
 
+// resolveImports links each import of the module to the corresponding export of an already instantiated module.
+//
+// Imports which cannot be found are collected, so that the returned error lists all of them instead of only the
+// first. Other errors, such as a type mismatch, are returned immediately.
 func (m *ModuleInstance) resolveImports(module *Module) (err error) {
+	var unresolved []string
+	defer func() {
+		if err == nil && len(unresolved) > 0 {
+			err = errorUnresolvedImports(unresolved)
+		}
+	}()
+
 	for moduleName, imports := range module.ImportPerModule {
-		var importedModule *ModuleInstance
-		importedModule, err = m.s.module(moduleName)
-		if err != nil {
-			return err
+		importedModule, moduleErr := m.s.module(moduleName)
+		if moduleErr != nil {
+			unresolved = append(unresolved, moduleErr.Error())
+			continue
 		}
 
 		for _, i := range imports {
-			var imported *Export
-			imported, err = importedModule.getExport(i.Name, i.Type)
-			if err != nil {
-				return
+			imported, exportErr := importedModule.getExport(i.Name, i.Type)
+			if exportErr != nil {
+				unresolved = append(unresolved, exportErr.Error())
+				continue
 			}
 
 			switch i.Type {
 			case ExternTypeFunc:
 				expectedType := &module.TypeSection[i.DescFunc]
 				src := importedModule.Source
-				actual := src.typeOfFunction(imported.Index)
+				actual, _ := src.FunctionType(imported.Index)
 				if !actual.EqualsSignature(expectedType.Params, expectedType.Results) {
 					err = errorInvalidImport(i, fmt.Errorf("signature mismatch: %s != %s", expectedType, actual))
 					return
@@ -417,41 +454,18 @@ func (m *ModuleInstance) resolveImports(module *Module) (err error) {
 
 				m.Engine.ResolveImportedFunction(i.IndexPerType, imported.Index, importedModule.Engine)
 			case ExternTypeTable:
-				expected := i.DescTable
 				importedTable := importedModule.Tables[imported.Index]
-				if expected.Type != importedTable.Type {
-					err = errorInvalidImport(i, fmt.Errorf("table type mismatch: %s != %s",
-						RefTypeName(expected.Type), RefTypeName(importedTable.Type)))
+				actual := &Table{Min: importedTable.Min, Max: importedTable.Max, Type: importedTable.Type}
+				if err = actual.Satisfies(&i.DescTable); err != nil {
+					err = errorInvalidImport(i, err)
 					return
 				}
-
-				if expected.Min > importedTable.Min {
-					err = errorMinSizeMismatch(i, expected.Min, importedTable.Min)
-					return
-				}
-
-				if expected.Max != nil {
-					expectedMax := *expected.Max
-					if importedTable.Max == nil {
-						err = errorNoMax(i, expectedMax)
-						return
-					} else if expectedMax < *importedTable.Max {
-						err = errorMaxSizeMismatch(i, expectedMax, *importedTable.Max)
-						return
-					}
-				}
 				m.Tables[i.IndexPerType] = importedTable
 			case ExternTypeMemory:
-				expected := i.DescMem
 				importedMemory := importedModule.MemoryInstance
-
-				if expected.Min > memoryBytesNumToPages(uint64(len(importedMemory.Buffer))) {
-					err = errorMinSizeMismatch(i, expected.Min, importedMemory.Min)
-					return
-				}
-
-				if expected.Max < importedMemory.Max {
-					err = errorMaxSizeMismatch(i, expected.Max, importedMemory.Max)
+				actual := &Memory{Min: memoryBytesNumToPages(uint64(len(importedMemory.Buffer))), Max: importedMemory.Max}
+				if err = actual.Satisfies(i.DescMem); err != nil {
+					err = errorInvalidImport(i, err)
 					return
 				}
 				m.MemoryInstance = importedMemory
@@ -478,16 +492,14 @@ func (m *ModuleInstance) resolveImports(module *Module) (err error) {
 	return
 }
 
-func errorMinSizeMismatch(i *Import, expected, actual uint32) error {
-	return errorInvalidImport(i, fmt.Errorf("minimum size mismatch: %d > %d", expected, actual))
-}
-
-func errorNoMax(i *Import, expected uint32) error {
-	return errorInvalidImport(i, fmt.Errorf("maximum size mismatch: %d, but actual has no max", expected))
-}
-
-func errorMaxSizeMismatch(i *Import, expected, actual uint32) error {
-	return errorInvalidImport(i, fmt.Errorf("maximum size mismatch: %d < %d", expected, actual))
+// errorUnresolvedImports returns the only message as-is, or all of them sorted for a deterministic error as
+// ImportPerModule is a map.
+func errorUnresolvedImports(unresolved []string) error {
+	if len(unresolved) == 1 {
+		return errors.New(unresolved[0])
+	}
+	sort.Strings(unresolved)
+	return fmt.Errorf("%d imports could not be resolved:\n\t%s", len(unresolved), strings.Join(unresolved, "\n\t"))
 }
 
 func errorInvalidImport(i *Import, err error) error {
@@ -572,6 +584,17 @@ func (g *GlobalInstance) String() string {
 	}
 }
 
+// SetDeterministicProfile enables or disables deterministic floating-point results for modules instantiated after
+// this call. When enabled, every NaN result of a scalar floating-point instruction, including min and max, is the
+// canonical NaN with a positive sign, instead of a NaN whose sign and payload depend on the operands and the platform.
+//
+// Note: Only the interpreter implements this profile, so callers must not enable it with another Engine.
+func (s *Store) SetDeterministicProfile(enabled bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.deterministicProfile = enabled
+}
+
 func (s *Store) GetFunctionTypeIDs(ts []FunctionType) ([]FunctionTypeID, error) {
 	ret := make([]FunctionTypeID, len(ts))
 	for i := range ts {
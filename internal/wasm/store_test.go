@@ -117,6 +117,25 @@ func TestStore_Instantiate(t *testing.T) {
 	})
 }
 
+func TestStore_SetDeterministicProfile(t *testing.T) {
+	s := newStore()
+	m := &Module{}
+
+	before, err := s.Instantiate(testCtx, m, "before", nil, nil)
+	require.NoError(t, err)
+	s.SetDeterministicProfile(true)
+	after, err := s.Instantiate(testCtx, m, "after", nil, nil)
+	require.NoError(t, err)
+	s.SetDeterministicProfile(false)
+	disabled, err := s.Instantiate(testCtx, m, "disabled", nil, nil)
+	require.NoError(t, err)
+
+	// Only modules instantiated while the profile is enabled use it.
+	require.False(t, before.DeterministicProfile)
+	require.True(t, after.DeterministicProfile)
+	require.False(t, disabled.DeterministicProfile)
+}
+
 func TestStore_CloseWithExitCode(t *testing.T) {
 	const importedModuleName = "imported"
 	const importingModuleName = "test"
@@ -697,6 +716,34 @@ func Test_resolveImports(t *testing.T) {
 		err := m.resolveImports(&Module{ImportPerModule: map[string][]*Import{moduleName: {{Name: "unknown"}}}})
 		require.EqualError(t, err, "\"unknown\" is not exported in module \"test\"")
 	})
+	t.Run("reports all unresolved imports", func(t *testing.T) {
+		s := newStore()
+		s.nameToModule[moduleName] = &ModuleInstance{
+			Exports:    map[string]*Export{"provided": {Type: ExternTypeFunc, Index: 0}},
+			ModuleName: moduleName,
+			Source: &Module{
+				FunctionSection: []Index{0},
+				TypeSection:     []FunctionType{{}},
+			},
+		}
+		module := &Module{
+			TypeSection:         []FunctionType{{}},
+			ImportFunctionCount: 3,
+			ImportPerModule: map[string][]*Import{
+				moduleName: {
+					{Module: moduleName, Name: "missing1", Type: ExternTypeFunc, IndexPerType: 0},
+					{Module: moduleName, Name: "provided", Type: ExternTypeFunc, IndexPerType: 1},
+					{Module: moduleName, Name: "missing2", Type: ExternTypeFunc, IndexPerType: 2},
+				},
+			},
+		}
+
+		m := &ModuleInstance{Engine: &mockModuleEngine{resolveImportsCalled: map[Index]Index{}}, s: s, Source: module}
+		err := m.resolveImports(module)
+		require.EqualError(t, err, `2 imports could not be resolved:
+	"missing1" is not exported in module "test"
+	"missing2" is not exported in module "test"`)
+	})
 	t.Run("func", func(t *testing.T) {
 		t.Run("ok", func(t *testing.T) {
 			s := newStore()
@@ -846,7 +893,7 @@ func Test_resolveImports(t *testing.T) {
 			importMemoryType := &Memory{Min: 2, Cap: 2}
 			s := newStore()
 			s.nameToModule[moduleName] = &ModuleInstance{
-				MemoryInstance: &MemoryInstance{Min: importMemoryType.Min - 1, Cap: 2},
+				MemoryInstance: &MemoryInstance{Min: importMemoryType.Min - 1, Cap: 2, Buffer: make([]byte, MemoryPageSize)},
 				Exports: map[string]*Export{name: {
 					Type: ExternTypeMemory,
 				}},
@@ -860,6 +907,26 @@ func Test_resolveImports(t *testing.T) {
 			})
 			require.EqualError(t, err, "import memory[test.target]: minimum size mismatch: 2 > 1")
 		})
+		t.Run("grown memory satisfies minimum size", func(t *testing.T) {
+			importMemoryType := &Memory{Min: 2, Cap: 2, Max: MemoryLimitPages}
+			memoryInst := &MemoryInstance{Min: 1, Cap: 2, Max: MemoryLimitPages, Buffer: make([]byte, 2*MemoryPageSize)}
+			s := newStore()
+			s.nameToModule[moduleName] = &ModuleInstance{
+				MemoryInstance: memoryInst,
+				Exports: map[string]*Export{name: {
+					Type: ExternTypeMemory,
+				}},
+				ModuleName: moduleName,
+			}
+			m := &ModuleInstance{s: s, Engine: &mockModuleEngine{}}
+			err := m.resolveImports(&Module{
+				ImportPerModule: map[string][]*Import{
+					moduleName: {{Module: moduleName, Name: name, Type: ExternTypeMemory, DescMem: importMemoryType}},
+				},
+			})
+			require.NoError(t, err)
+			require.Equal(t, memoryInst, m.MemoryInstance)
+		})
 		t.Run("maximum size mismatch", func(t *testing.T) {
 			s := newStore()
 			s.nameToModule[moduleName] = &ModuleInstance{
@@ -15,6 +15,27 @@ type Table struct {
 	Type RefType
 }
 
+// Satisfies returns an error unless a table of this type can be imported as the required type: it must have the same
+// reference type, its minimum must be at least the required minimum, and if the required type has a maximum, its
+// maximum must be present and at most that.
+//
+// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#limits%E2%91%A2
+func (t *Table) Satisfies(required *Table) error {
+	if t.Type != required.Type {
+		return fmt.Errorf("table type mismatch: %s != %s", RefTypeName(required.Type), RefTypeName(t.Type))
+	} else if t.Min < required.Min {
+		return fmt.Errorf("minimum size mismatch: %d > %d", required.Min, t.Min)
+	}
+	if required.Max != nil {
+		if t.Max == nil {
+			return fmt.Errorf("maximum size mismatch: %d, but actual has no max", *required.Max)
+		} else if *t.Max > *required.Max {
+			return fmt.Errorf("maximum size mismatch: %d < %d", *required.Max, *t.Max)
+		}
+	}
+	return nil
+}
+
 // RefType is either RefTypeFuncref or RefTypeExternref as of WebAssembly core 2.0.
 type RefType = byte
 
@@ -165,6 +186,9 @@ func (m *Module) validateTable(enabledFeatures api.CoreFeatures, tables []Table,
 				if ok {
 					if index >= globalsCount {
 						return fmt.Errorf("%s[%d].init[%d] globalidx %d out of range", SectionIDName(SectionIDElement), idx, ei, index)
+					} else if index >= m.ImportGlobalCount {
+						// Constant expressions can only reference imported globals, as others aren't initialized yet.
+						return fmt.Errorf("%s[%d].init[%d] globalidx %d is not an imported global", SectionIDName(SectionIDElement), idx, ei, index)
 					}
 				} else {
 					if index >= funcCount {
@@ -292,6 +316,8 @@ func (m *Module) verifyImportGlobalI32(sectionID SectionID, sectionIdx Index, id
 			if ig == idx {
 				if imp.DescGlobal.ValType != ValueTypeI32 {
 					return fmt.Errorf("%s[%d] (global.get %d): import[%d].global.ValType != i32", SectionIDName(sectionID), sectionIdx, idx, i)
+				} else if imp.DescGlobal.Mutable {
+					return fmt.Errorf("%s[%d] (global.get %d): import[%d].global is mutable", SectionIDName(sectionID), sectionIdx, idx, i)
 				}
 				return nil
 			}
@@ -89,6 +89,63 @@ func Test_resolveImports_table(t *testing.T) {
 
 var codeEnd = Code{Body: []byte{OpcodeEnd}}
 
+func TestTable_Satisfies(t *testing.T) {
+	one, two, three := uint32(1), uint32(2), uint32(3)
+	tests := []struct {
+		name             string
+		actual, required *Table
+		expectedErr      string
+	}{
+		{
+			name:     "no max required",
+			actual:   &Table{Min: 1, Type: RefTypeFuncref},
+			required: &Table{Min: 1, Type: RefTypeFuncref},
+		},
+		{
+			name:     "larger min and smaller max",
+			actual:   &Table{Min: 2, Max: &two, Type: RefTypeExternref},
+			required: &Table{Min: 1, Max: &three, Type: RefTypeExternref},
+		},
+		{
+			name:        "type mismatch",
+			actual:      &Table{Type: RefTypeExternref},
+			required:    &Table{Type: RefTypeFuncref},
+			expectedErr: "table type mismatch: funcref != externref",
+		},
+		{
+			name:        "min too small",
+			actual:      &Table{Min: 1, Type: RefTypeFuncref},
+			required:    &Table{Min: 2, Type: RefTypeFuncref},
+			expectedErr: "minimum size mismatch: 2 > 1",
+		},
+		{
+			name:        "no max",
+			actual:      &Table{Type: RefTypeFuncref},
+			required:    &Table{Max: &two, Type: RefTypeFuncref},
+			expectedErr: "maximum size mismatch: 2, but actual has no max",
+		},
+		{
+			name:        "max too large",
+			actual:      &Table{Max: &two, Type: RefTypeFuncref},
+			required:    &Table{Max: &one, Type: RefTypeFuncref},
+			expectedErr: "maximum size mismatch: 1 < 2",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.actual.Satisfies(tc.required)
+			if tc.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
 func TestModule_validateTable(t *testing.T) {
 	const maxTableIndex = 5
 	three := uint32(3)
@@ -445,6 +502,22 @@ func TestModule_validateTable_Errors(t *testing.T) {
 			},
 			expectedErr: "element[0] has an invalid const expression: i64.const",
 		},
+		{
+			name: "constant derived element offset - f32",
+			input: &Module{
+				TypeSection:     []FunctionType{{}},
+				TableSection:    []Table{{Type: RefTypeFuncref}},
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{codeEnd},
+				ElementSection: []ElementSegment{
+					{
+						OffsetExpr: ConstantExpression{Opcode: OpcodeF32Const, Data: []byte{0, 0, 0, 0}}, Init: []Index{0},
+						Type: RefTypeFuncref,
+					},
+				},
+			},
+			expectedErr: "element[0] has an invalid const expression: f32.const",
+		},
 		{
 			name: "constant derived element offset - missing table",
 			input: &Module{
@@ -604,6 +677,45 @@ func TestModule_validateTable_Errors(t *testing.T) {
 			},
 			expectedErr: "element[0] (global.get 0): import[0].global.ValType != i32",
 		},
+		{
+			name: "imported global derived element offset - mutable",
+			input: &Module{
+				TypeSection: []FunctionType{{}},
+				ImportSection: []Import{
+					{Type: ExternTypeGlobal, DescGlobal: GlobalType{ValType: ValueTypeI32, Mutable: true}},
+				},
+				TableSection:    []Table{{Type: RefTypeFuncref}},
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{codeEnd},
+				ElementSection: []ElementSegment{
+					{
+						OffsetExpr: ConstantExpression{Opcode: OpcodeGlobalGet, Data: []byte{0x0}}, Init: []Index{0},
+						Type: RefTypeFuncref,
+					},
+				},
+			},
+			expectedErr: "element[0] (global.get 0): import[0].global is mutable",
+		},
+		{
+			name: "element init references a defined global",
+			input: &Module{
+				ImportGlobalCount: 1,
+				TypeSection:       []FunctionType{{}},
+				TableSection:      []Table{{Min: 1}},
+				GlobalSection:     []Global{{Type: GlobalType{ValType: ValueTypeFuncref}}},
+				FunctionSection:   []Index{0},
+				CodeSection:       []Code{codeEnd},
+				ElementSection: []ElementSegment{
+					{
+						OffsetExpr: ConstantExpression{Opcode: OpcodeI32Const, Data: const0}, Init: []Index{
+							ElementInitImportedGlobalFunctionReference | 1,
+						},
+						Type: RefTypeFuncref,
+					},
+				},
+			},
+			expectedErr: "element[0].init[0] globalidx 1 is not an imported global",
+		},
 		{
 			name: "imported global derived element offset - decode error",
 			input: &Module{
@@ -0,0 +1,85 @@
+package wasm
+
+import "fmt"
+
+// DeduplicateTypes removes each type in TypeSection which is Equal to an earlier one, and rewrites the type indexes of
+// function imports, FunctionSection, OpcodeCallIndirect and block types to the first equal type.
+//
+// This is useful after merging modules or building one programmatically, where the same signature is often declared
+// more than once.
+func (m *Module) DeduplicateTypes() error {
+	typeCount := uint32(len(m.TypeSection))
+	for i := range m.ImportSection {
+		if imp := &m.ImportSection[i]; imp.Type == ExternTypeFunc && imp.DescFunc >= typeCount {
+			return fmt.Errorf("import[%d]: type index out of range: %d", i, imp.DescFunc)
+		}
+	}
+	for i, typeIdx := range m.FunctionSection {
+		if typeIdx >= typeCount {
+			return fmt.Errorf("%s: type index out of range: %d", m.funcDesc(SectionIDFunction, Index(i)), typeIdx)
+		}
+	}
+	bodyImmediates := make([][]indexImmediate, len(m.CodeSection))
+	for i := range m.CodeSection {
+		code := &m.CodeSection[i]
+		if code.GoFunc != nil {
+			continue
+		}
+		immediates, err := indexImmediates(code.Body)
+		if err != nil {
+			return fmt.Errorf("%s: %w", m.funcDesc(SectionIDCode, Index(i)), err)
+		}
+		for _, imm := range immediates {
+			if imm.kind == indexKindType && imm.index >= typeCount {
+				return fmt.Errorf("%s: type index out of range: %d", m.funcDesc(SectionIDCode, Index(i)), imm.index)
+			}
+		}
+		bodyImmediates[i] = immediates
+	}
+
+	mapping := make([]Index, len(m.TypeSection))
+	types := make([]FunctionType, 0, len(m.TypeSection))
+	firstIndexes := make(map[string]Index, len(m.TypeSection))
+	for i := range m.TypeSection {
+		// Note: key is unique per signature, so it is equivalent to comparing each type with Equal. A copy is used to
+		// avoid caching the key in TypeSection.
+		ft := m.TypeSection[i]
+		key := ft.key()
+		if idx, ok := firstIndexes[key]; ok {
+			mapping[i] = idx
+			continue
+		}
+		idx := Index(len(types))
+		firstIndexes[key] = idx
+		mapping[i] = idx
+		types = append(types, m.TypeSection[i])
+	}
+	if len(types) == len(m.TypeSection) {
+		return nil // No duplicates.
+	}
+	m.TypeSection = types
+	m.remapTypeIndexes(mapping, bodyImmediates)
+	return nil
+}
+
+// remapTypeIndexes rewrites the type indexes of function imports, FunctionSection and the type immediates in
+// bodyImmediates, which are index-correlated with CodeSection, from their position in mapping to the value there.
+func (m *Module) remapTypeIndexes(mapping []Index, bodyImmediates [][]indexImmediate) {
+	// Note: ImportPerModule points into ImportSection, so it is updated as well.
+	for i := range m.ImportSection {
+		if imp := &m.ImportSection[i]; imp.Type == ExternTypeFunc {
+			imp.DescFunc = mapping[imp.DescFunc]
+		}
+	}
+	for i, typeIdx := range m.FunctionSection {
+		m.FunctionSection[i] = mapping[typeIdx]
+	}
+	for i := range m.CodeSection {
+		m.CodeSection[i].Body = remapIndexImmediates(m.CodeSection[i].Body, bodyImmediates[i], func(imm indexImmediate) Index {
+			if imm.kind != indexKindType {
+				return imm.index
+			}
+			return mapping[imm.index]
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package wasm
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestFunctionType_Equal(t *testing.T) {
+	require.True(t, (&FunctionType{Params: []ValueType{ValueTypeI32}}).Equal(&i32_v))
+	require.True(t, (&FunctionType{}).Equal(&v_v))
+	require.False(t, i32_v.Equal(&v_i32))
+	require.False(t, i32_i32.Equal(&i32i32_i32))
+}
+
+func TestModule_DeduplicateTypes(t *testing.T) {
+	m := &Module{
+		// Type 2 is equal to type 0, so it collapses into it, and type 3 moves to index 2.
+		TypeSection: []FunctionType{i32_i32, v_v, {Params: []ValueType{ValueTypeI32}, Results: []ValueType{ValueTypeI32}}, i32i32_i32},
+		ImportSection: []Import{
+			{Type: ExternTypeFunc, Module: "env", Name: "f", DescFunc: 2},
+		},
+		ImportFunctionCount: 1,
+		FunctionSection:     []Index{1, 3, 2},
+		TableSection:        []Table{{Min: 1, Type: RefTypeFuncref}},
+		CodeSection: []Code{
+			{Body: []byte{
+				OpcodeI32Const, 1,
+				OpcodeI32Const, 0,
+				OpcodeCallIndirect, 2 /* type i32_i32 */, 0,
+				OpcodeDrop,
+				OpcodeI32Const, 1,
+				OpcodeBlock, 2 /* type i32_i32 */, OpcodeEnd,
+				OpcodeDrop,
+				OpcodeEnd,
+			}},
+			{Body: []byte{OpcodeLocalGet, 0, OpcodeEnd}},
+			{Body: []byte{OpcodeLocalGet, 0, OpcodeCall, 0, OpcodeEnd}},
+		},
+	}
+
+	err := m.DeduplicateTypes()
+	require.NoError(t, err)
+
+	require.Equal(t, []FunctionType{i32_i32, v_v, i32i32_i32}, m.TypeSection)
+	require.Equal(t, Index(0), m.ImportSection[0].DescFunc)
+	require.Equal(t, []Index{1, 2, 0}, m.FunctionSection)
+	require.Equal(t, []byte{
+		OpcodeI32Const, 1,
+		OpcodeI32Const, 0,
+		OpcodeCallIndirect, 0, 0,
+		OpcodeDrop,
+		OpcodeI32Const, 1,
+		OpcodeBlock, 0, OpcodeEnd,
+		OpcodeDrop,
+		OpcodeEnd,
+	}, m.CodeSection[0].Body)
+
+	require.NoError(t, m.Validate(api.CoreFeaturesV2))
+}
+
+func TestModule_DeduplicateTypes_NoDuplicates(t *testing.T) {
+	m := &Module{
+		TypeSection:     []FunctionType{v_v, i32_v},
+		FunctionSection: []Index{1},
+		CodeSection:     []Code{{Body: []byte{OpcodeEnd}}},
+	}
+	err := m.DeduplicateTypes()
+	require.NoError(t, err)
+	require.Equal(t, []FunctionType{v_v, i32_v}, m.TypeSection)
+	require.Equal(t, []Index{1}, m.FunctionSection)
+}
+
+func TestModule_DeduplicateTypes_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		module      *Module
+		expectedErr string
+	}{
+		{
+			name: "import type out of range",
+			module: &Module{
+				TypeSection:   []FunctionType{v_v},
+				ImportSection: []Import{{Type: ExternTypeFunc, DescFunc: 1}},
+			},
+			expectedErr: "import[0]: type index out of range: 1",
+		},
+		{
+			name: "function type out of range",
+			module: &Module{
+				TypeSection:     []FunctionType{v_v},
+				FunctionSection: []Index{1},
+			},
+			expectedErr: "function[0]: type index out of range: 1",
+		},
+		{
+			name: "call_indirect type out of range",
+			module: &Module{
+				TypeSection:     []FunctionType{v_v},
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{{Body: []byte{OpcodeI32Const, 0, OpcodeCallIndirect, 1, 0, OpcodeEnd}}},
+			},
+			expectedErr: "code[0]: type index out of range: 1",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.module.DeduplicateTypes()
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
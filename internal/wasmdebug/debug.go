@@ -131,6 +131,9 @@ func (s *stackTrace) FromRecovered(recovered interface{}) error {
 	if wasmErr, ok := recovered.(*wasmruntime.Error); ok {
 		return fmt.Errorf("wasm error: %w\nwasm stack trace:\n\t%s", wasmErr, stack)
 	}
+	if unsupportedErr, ok := recovered.(*wasmruntime.ErrUnsupportedOpcode); ok {
+		return fmt.Errorf("wasm error: %w\nwasm stack trace:\n\t%s", unsupportedErr, stack)
+	}
 
 	// If we have a runtime.Error, something severe happened which should include the stack trace. This could be
 	// a nil pointer from wazero or a user-defined function from HostModuleBuilder.
@@ -3,6 +3,8 @@
 // Note: This only imports "api" as importing "wasm" would create a cyclic dependency.
 package wasmruntime
 
+import "fmt"
+
 var (
 	// ErrRuntimeStackOverflow indicates that there are too many function calls,
 	// and the Engine terminated the execution.
@@ -42,3 +44,21 @@ func New(text string) *Error {
 func (e *Error) Error() string {
 	return e.s
 }
+
+// ErrUnsupportedOpcode is returned by a wasm.Engine when it executes an instruction which is decoded and validated, but
+// not yet implemented by that engine.
+type ErrUnsupportedOpcode struct {
+	// Op is the opcode of the instruction, or its prefix if it is a multi-byte instruction, e.g. 0xfe for atomics.
+	Op byte
+	// SubOp is the opcode following the prefix, or zero if Op isn't a prefix.
+	SubOp uint32
+}
+
+func (e *ErrUnsupportedOpcode) Error() string {
+	switch e.Op {
+	case 0xfc, 0xfd, 0xfe: // wasm.OpcodeMiscPrefix, OpcodeVecPrefix and OpcodeAtomicPrefix, as SubOp can be zero.
+		return fmt.Sprintf("unsupported opcode %#x %#x", e.Op, e.SubOp)
+	default:
+		return fmt.Sprintf("unsupported opcode %#x", e.Op)
+	}
+}
@@ -0,0 +1,38 @@
+package wasmruntime
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestErrUnsupportedOpcode_Error(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    *ErrUnsupportedOpcode
+		expected string
+	}{
+		{
+			name:     "single byte",
+			input:    &ErrUnsupportedOpcode{Op: 0x00},
+			expected: "unsupported opcode 0x0",
+		},
+		{
+			name:     "prefixed",
+			input:    &ErrUnsupportedOpcode{Op: 0xfe, SubOp: 0x10},
+			expected: "unsupported opcode 0xfe 0x10",
+		},
+		{
+			name:     "prefixed with a zero sub-opcode",
+			input:    &ErrUnsupportedOpcode{Op: 0xfe, SubOp: 0x00}, // memory.atomic.notify
+			expected: "unsupported opcode 0xfe 0x0",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			require.EqualError(t, tc.input, tc.expected)
+		})
+	}
+}
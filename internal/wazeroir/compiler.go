@@ -1019,13 +1019,21 @@ operatorSwitch:
 		)
 	case wasm.OpcodeMemorySize:
 		c.result.UsesMemory = true
-		c.pc++ // Skip the reserved one byte.
+		_, num, err := leb128.LoadUint32(c.body[c.pc+1:])
+		if err != nil {
+			return fmt.Errorf("reading memory index: %v", err)
+		}
+		c.pc += num // Skip the memory index, which is always zero.
 		c.emit(
 			NewOperationMemorySize(),
 		)
 	case wasm.OpcodeMemoryGrow:
 		c.result.UsesMemory = true
-		c.pc++ // Skip the reserved one byte.
+		_, num, err := leb128.LoadUint32(c.body[c.pc+1:])
+		if err != nil {
+			return fmt.Errorf("reading memory index: %v", err)
+		}
+		c.pc += num // Skip the memory index, which is always zero.
 		c.emit(
 			NewOperationMemoryGrow(),
 		)
@@ -2848,6 +2856,17 @@ operatorSwitch:
 		default:
 			return fmt.Errorf("unsupported vector instruction in wazeroir: %s", wasm.VectorInstructionName(vecOp))
 		}
+	case wasm.OpcodeAtomicPrefix:
+		c.pc++
+		atomicOp := c.body[c.pc]
+		if atomicOp == wasm.OpcodeAtomicFence {
+			c.pc++ // Skip the reserved zero byte.
+		} else if _, err := c.readMemoryArg(wasm.AtomicInstructionName(atomicOp)); err != nil {
+			return err
+		}
+		// TODO: atomic instructions are only decoded and validated for now, so executing one traps.
+		c.emit(NewOperationUnsupported(op, uint32(atomicOp)))
+		c.markUnreachable()
 	default:
 		return fmt.Errorf("unsupported instruction in wazeroir: 0x%x", op)
 	}
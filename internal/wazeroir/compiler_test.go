@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
@@ -171,6 +172,33 @@ func TestCompile(t *testing.T) {
 				UsesMemory: true,
 			},
 		},
+		{
+			name:            "memory.grow multi-byte memory index",
+			enabledFeatures: api.CoreFeaturesV2 | experimental.CoreFeaturesMultiMemory,
+			module: &wasm.Module{
+				TypeSection:     []wasm.FunctionType{i32_i32},
+				FunctionSection: []wasm.Index{0},
+				CodeSection: []wasm.Code{{Body: []byte{
+					wasm.OpcodeLocalGet, 0, wasm.OpcodeMemoryGrow, 0x80, 0x00, wasm.OpcodeEnd,
+				}}},
+			},
+			expected: &CompilationResult{
+				Operations: []UnionOperation{ // begin with params: [$delta]
+					NewOperationPick(0, false),                         // [$delta, $delta]
+					NewOperationMemoryGrow(),                           // [$delta, $old_size]
+					NewOperationDrop(InclusiveRange{Start: 1, End: 1}), // [$old_size]
+					NewOperationBr(NewLabel(LabelKindReturn, 0)),       // return!
+				},
+				LabelCallers: map[Label]uint32{},
+				Types: []wasm.FunctionType{{
+					Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32},
+					ParamNumInUint64:  1,
+					ResultNumInUint64: 1,
+				}},
+				Functions:  []uint32{0},
+				UsesMemory: true,
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -666,6 +694,37 @@ func TestCompile_SignExtensionOps(t *testing.T) {
 	require.Equal(t, expected, actual)
 }
 
+// TestCompile_Atomic ensures atomic instructions compile to a trap until they are implemented.
+func TestCompile_Atomic(t *testing.T) {
+	module := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{i32_i32},
+		FunctionSection: []wasm.Index{0},
+		MemorySection:   &wasm.Memory{},
+		// (func (param i32) (result i32) local.get 0 i32.atomic.load)
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeLocalGet, 0, wasm.OpcodeAtomicPrefix, wasm.OpcodeAtomicI32Load, 0x2, 0x0, wasm.OpcodeEnd,
+		}}},
+	}
+
+	expected := &CompilationResult{
+		Operations: []UnionOperation{ // begin with params: [$0]
+			NewOperationPick(0, false), // [$0, $0]
+			NewOperationUnsupported(wasm.OpcodeAtomicPrefix, uint32(wasm.OpcodeAtomicI32Load)), // trap!
+		},
+		LabelCallers: map[Label]uint32{},
+		Functions:    []wasm.Index{0},
+		Types:        []wasm.FunctionType{i32_i32},
+		HasMemory:    true,
+		UsesMemory:   true,
+	}
+	c, err := NewCompiler(api.CoreFeaturesV2|experimental.CoreFeaturesThreads, 0, module, false)
+	require.NoError(t, err)
+
+	actual, err := c.Next()
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+}
+
 func requireCompilationResult(t *testing.T, enabledFeatures api.CoreFeatures, expected *CompilationResult, module *wasm.Module) {
 	if enabledFeatures == 0 {
 		enabledFeatures = api.CoreFeaturesV2
@@ -415,6 +415,8 @@ func (o OperationKind) String() (ret string) {
 		ret = "V128ITruncSatFromF"
 	case OperationKindBuiltinFunctionCheckExitCode:
 		ret = "BuiltinFunctionCheckExitCode"
+	case OperationKindUnsupported:
+		ret = "Unsupported"
 	default:
 		panic(fmt.Errorf("unknown operation %d", o))
 	}
@@ -705,6 +707,9 @@ const (
 	// OperationKindBuiltinFunctionCheckExitCode is the Kind for NewOperationBuiltinFunctionCheckExitCode.
 	OperationKindBuiltinFunctionCheckExitCode
 
+	// OperationKindUnsupported is the Kind for NewOperationUnsupported.
+	OperationKindUnsupported
+
 	// operationKindEnd is always placed at the bottom of this iota definition to be used in the test.
 	operationKindEnd
 )
@@ -717,6 +722,17 @@ func NewOperationBuiltinFunctionCheckExitCode() UnionOperation {
 	return UnionOperation{Kind: OperationKindBuiltinFunctionCheckExitCode}
 }
 
+// NewOperationUnsupported is a constructor for UnionOperation with Kind OperationKindUnsupported.
+//
+// This corresponds to an instruction which is decoded and validated, but not yet implemented. op is its opcode, or
+// the prefix of a multi-byte instruction, in which case subOp is the opcode following it.
+//
+// The interpreter exits the execution with wasmruntime.ErrUnsupportedOpcode, while the compiler handles this like
+// OperationKindUnreachable.
+func NewOperationUnsupported(op byte, subOp uint32) UnionOperation {
+	return UnionOperation{Kind: OperationKindUnsupported, B1: op, U1: uint64(subOp)}
+}
+
 // Label is the unique identifier for each block in a single function in wazeroir
 // where "block" consists of multiple operations, and must End with branching operations
 // (e.g. OperationKindBr or OperationKindBrIf).
@@ -836,6 +852,9 @@ func (o UnionOperation) String() string {
 		OperationKindGlobalSet:
 		return fmt.Sprintf("%s %d", o.Kind, o.B1)
 
+	case OperationKindUnsupported:
+		return fmt.Sprintf("%s %#x %#x", o.Kind, o.B1, o.U1)
+
 	case OperationKindLabel:
 		return Label(o.U1).String()
 
@@ -159,8 +159,11 @@ func NewRuntimeWithConfig(ctx context.Context, rConfig RuntimeConfig) Runtime {
 		engine = config.newEngine(ctx, config.enabledFeatures, nil)
 	}
 	store := wasm.NewStore(config.enabledFeatures, engine)
+	store.SetMemoryBudget(config.memoryBudgetPages)
+	store.SetDeterministicProfile(config.deterministicProfile)
 	return &runtime{
 		cache:                 cacheImpl,
+		moduleCache:           config.moduleCache,
 		store:                 store,
 		enabledFeatures:       config.enabledFeatures,
 		memoryLimitPages:      config.memoryLimitPages,
@@ -175,6 +178,7 @@ func NewRuntimeWithConfig(ctx context.Context, rConfig RuntimeConfig) Runtime {
 type runtime struct {
 	store                 *wasm.Store
 	cache                 *cache
+	moduleCache           ModuleCache
 	enabledFeatures       api.CoreFeatures
 	memoryLimitPages      uint32
 	memoryCapacityFromMax bool
@@ -206,14 +210,22 @@ func (r *runtime) CompileModule(ctx context.Context, binary []byte) (CompiledMod
 		return nil, err
 	}
 
-	internal, err := binaryformat.DecodeModule(binary, r.enabledFeatures,
+	// Listeners are compiled into the module, so only cache modules without them.
+	var key [32]byte
+	useCache := r.moduleCache != nil && ctx.Value(experimentalapi.FunctionListenerFactoryKey{}) == nil
+	if useCache {
+		var err error
+		if key, err = wasm.ModuleHash(binary); err != nil {
+			useCache = false // Let decoding report the error.
+		} else if c, ok := r.cachedModule(ctx, key); ok {
+			return c, nil
+		}
+	}
+
+	internal, err := binaryformat.DecodeAndValidateModule(binary, r.enabledFeatures,
 		r.memoryLimitPages, r.memoryCapacityFromMax, !r.dwarfDisabled, r.storeCustomSections)
 	if err != nil {
 		return nil, err
-	} else if err = internal.Validate(r.enabledFeatures); err != nil {
-		// TODO: decoders should validate before returning, as that allows
-		// them to err with the correct position in the wasm binary.
-		return nil, err
 	}
 
 	// Now that the module is validated, cache the memory definitions.
@@ -237,9 +249,31 @@ func (r *runtime) CompileModule(ctx context.Context, binary []byte) (CompiledMod
 	if err = r.store.Engine.CompileModule(ctx, internal, listeners, r.ensureTermination); err != nil {
 		return nil, err
 	}
+	if useCache {
+		r.moduleCache.Put(key, c)
+	}
 	return c, nil
 }
 
+// cachedModule returns a copy of the module in ModuleCache for the key, which the engine compiles again if it was
+// closed since. This returns false if there is no such module or it was compiled by another Runtime.
+func (r *runtime) cachedModule(ctx context.Context, key [32]byte) (*compiledModule, bool) {
+	cached, ok := r.moduleCache.Get(key)
+	if !ok {
+		return nil, false
+	}
+	c, ok := cached.(*compiledModule)
+	if !ok || c.compiledEngine != r.store.Engine {
+		return nil, false
+	}
+	ret := *c // copy, as closeWithModule is per use.
+	ret.closeWithModule = false
+	if err := r.store.Engine.CompileModule(ctx, ret.module, nil, r.ensureTermination); err != nil {
+		return nil, false
+	}
+	return &ret, true
+}
+
 func buildFunctionListeners(ctx context.Context, internal *wasm.Module) ([]experimentalapi.FunctionListener, error) {
 	// Test to see if internal code are using an experimental feature.
 	fnlf := ctx.Value(experimentalapi.FunctionListenerFactoryKey{})
@@ -235,6 +235,123 @@ func TestModule_Memory(t *testing.T) {
 	}
 }
 
+// TestModule_ExportedFunction_ParamNames ensures parameter names in the name section are visible on the definition of
+// an exported function, for use in generated bindings.
+func TestModule_ExportedFunction_ParamNames(t *testing.T) {
+	i32 := api.ValueTypeI32
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []api.ValueType{i32, i32}, Results: []api.ValueType{i32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeEnd}}},
+		ExportSection:   []wasm.Export{{Name: "first", Type: api.ExternTypeFunc, Index: 0}},
+		NameSection: &wasm.NameSection{
+			LocalNames: wasm.IndirectNameMap{
+				{Index: 0, NameMap: wasm.NameMap{{Index: 0, Name: "x"}, {Index: 1, Name: "y"}}},
+			},
+		},
+	})
+
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	module, err := r.Instantiate(testCtx, bin)
+	require.NoError(t, err)
+
+	def := module.ExportedFunction("first").Definition()
+	require.Equal(t, []api.ValueType{i32, i32}, def.ParamTypes())
+	require.Equal(t, []string{"x", "y"}, def.ParamNames())
+	require.Equal(t, []api.ValueType{i32}, def.ResultTypes())
+	require.Nil(t, def.ResultNames())
+}
+
+func TestModule_ExportedFunctionDefinitions(t *testing.T) {
+	i32 := api.ValueTypeI32
+
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	_, err := r.NewHostModuleBuilder("env").
+		NewFunctionBuilder().
+		WithFunc(func(x, y uint32) uint32 { return x + y }).
+		Export("add").
+		Instantiate(testCtx)
+	require.NoError(t, err)
+
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			{Params: []api.ValueType{i32, i32}, Results: []api.ValueType{i32}},
+			{Results: []api.ValueType{i32}},
+		},
+		ImportSection:   []wasm.Import{{Module: "env", Name: "add", Type: api.ExternTypeFunc, DescFunc: 0}},
+		FunctionSection: []wasm.Index{1},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeEnd}}},
+		ExportSection: []wasm.Export{
+			{Name: "host_add", Type: api.ExternTypeFunc, Index: 0},
+			{Name: "run", Type: api.ExternTypeFunc, Index: 1},
+		},
+	})
+	module, err := r.Instantiate(testCtx, bin)
+	require.NoError(t, err)
+
+	defs := module.ExportedFunctionDefinitions()
+	require.Equal(t, 2, len(defs))
+
+	// An imported host function re-exported by the module is listed with its import.
+	hostAdd := defs["host_add"]
+	require.Equal(t, []api.ValueType{i32, i32}, hostAdd.ParamTypes())
+	require.Equal(t, []api.ValueType{i32}, hostAdd.ResultTypes())
+	moduleName, name, isImport := hostAdd.Import()
+	require.Equal(t, "env", moduleName)
+	require.Equal(t, "add", name)
+	require.True(t, isImport)
+	require.NotNil(t, module.ExportedFunction("host_add"))
+
+	run := defs["run"]
+	require.Equal(t, 0, len(run.ParamTypes()))
+	require.Equal(t, []api.ValueType{i32}, run.ResultTypes())
+	_, _, isImport = run.Import()
+	require.False(t, isImport)
+	results, err := module.ExportedFunction("run").Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1}, results)
+}
+
+func TestModule_StripDebug(t *testing.T) {
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Results: []api.ValueType{api.ValueTypeI32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeI32Const, 42, wasm.OpcodeEnd}}},
+		ExportSection:   []wasm.Export{{Name: "run", Type: api.ExternTypeFunc, Index: 0}},
+		NameSection: &wasm.NameSection{
+			ModuleName:    "debug",
+			FunctionNames: wasm.NameMap{{Index: 0, Name: "run"}},
+		},
+		CustomSections: []*wasm.CustomSection{
+			{Name: "producers", Data: []byte{0}},
+			{Name: ".debug_info", Data: make([]byte, 100)},
+		},
+	}
+	bin := binaryencoding.EncodeModule(m)
+	m.StripDebug()
+	stripped := binaryencoding.EncodeModule(m)
+	require.True(t, len(stripped) < len(bin))
+
+	r := NewRuntimeWithConfig(testCtx, NewRuntimeConfig().WithCustomSections(true))
+	defer r.Close(testCtx)
+
+	compiled, err := r.CompileModule(testCtx, stripped)
+	require.NoError(t, err)
+	require.Equal(t, "", compiled.Name()) // the name section was removed.
+	require.Equal(t, 1, len(compiled.CustomSections()))
+	require.Equal(t, "producers", compiled.CustomSections()[0].Name())
+
+	module, err := r.InstantiateModule(testCtx, compiled, NewModuleConfig())
+	require.NoError(t, err)
+	results, err := module.ExportedFunction("run").Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{42}, results)
+}
+
 // TestModule_Global only covers a couple cases to avoid duplication of internal/wasm/global_test.go
 func TestModule_Global(t *testing.T) {
 	globalVal := int64(100) // intentionally a value that differs in signed vs unsigned encoding
@@ -722,6 +839,143 @@ func TestRuntime_Close_ClosesCompiledModules(t *testing.T) {
 	}
 }
 
+// countingModuleCache is a ModuleCache which counts its hits and puts.
+type countingModuleCache struct {
+	ModuleCache
+	hits, puts int
+}
+
+// Get implements ModuleCache.Get
+func (c *countingModuleCache) Get(key [32]byte) (CompiledModule, bool) {
+	compiled, ok := c.ModuleCache.Get(key)
+	if ok {
+		c.hits++
+	}
+	return compiled, ok
+}
+
+// Put implements ModuleCache.Put
+func (c *countingModuleCache) Put(key [32]byte, compiled CompiledModule) {
+	c.puts++
+	c.ModuleCache.Put(key, compiled)
+}
+
+func TestRuntime_ModuleCache(t *testing.T) {
+	cache := &countingModuleCache{ModuleCache: NewModuleCache()}
+	r := NewRuntimeWithConfig(testCtx, NewRuntimeConfigInterpreter().WithModuleCache(cache))
+	defer r.Close(testCtx)
+	engine := r.(*runtime).store.Engine
+
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeEnd}}},
+		ExportSection:   []wasm.Export{{Type: api.ExternTypeFunc, Name: "f", Index: 0}},
+	})
+
+	m1, err := r.InstantiateWithConfig(testCtx, bin, NewModuleConfig().WithName("1"))
+	require.NoError(t, err)
+	require.Equal(t, 0, cache.hits)
+	require.Equal(t, 1, cache.puts)
+	m2, err := r.InstantiateWithConfig(testCtx, bin, NewModuleConfig().WithName("2"))
+	require.NoError(t, err)
+
+	// The second instantiation reused the module compiled by the first, instead of decoding it again.
+	source := m1.(*wasm.ModuleInstance).Source
+	require.Same(t, source, m2.(*wasm.ModuleInstance).Source)
+	require.Equal(t, 1, cache.hits)
+	require.Equal(t, 1, cache.puts)
+	require.Equal(t, uint32(1), engine.CompiledModuleCount())
+
+	// Closing both releases the module from the engine, but it is compiled again without decoding.
+	require.NoError(t, m1.Close(testCtx))
+	require.NoError(t, m2.Close(testCtx))
+	require.Zero(t, engine.CompiledModuleCount())
+
+	m3, err := r.InstantiateWithConfig(testCtx, bin, NewModuleConfig().WithName("3"))
+	require.NoError(t, err)
+	require.Same(t, source, m3.(*wasm.ModuleInstance).Source)
+	require.Equal(t, 2, cache.hits)
+	require.Equal(t, uint32(1), engine.CompiledModuleCount())
+	_, err = m3.ExportedFunction("f").Call(testCtx)
+	require.NoError(t, err)
+
+	// Different bytes aren't mistaken for the cached module.
+	m4, err := r.InstantiateWithConfig(testCtx, binaryNamedZero, NewModuleConfig().WithName("4"))
+	require.NoError(t, err)
+	require.NotSame(t, source, m4.(*wasm.ModuleInstance).Source)
+	require.Equal(t, 2, cache.hits)
+	require.Equal(t, 2, cache.puts)
+}
+
+func TestRuntime_MemoryBudget(t *testing.T) {
+	r := NewRuntimeWithConfig(testCtx, NewRuntimeConfigInterpreter().WithMemoryBudget(5))
+	defer r.Close(testCtx)
+
+	i32 := wasm.ValueTypeI32
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}},
+		FunctionSection: []wasm.Index{0},
+		MemorySection:   &wasm.Memory{Min: 2, Max: 10, IsMaxEncoded: true},
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeLocalGet, 0, wasm.OpcodeMemoryGrow, 0, wasm.OpcodeEnd,
+		}}},
+		ExportSection: []wasm.Export{{Type: api.ExternTypeFunc, Name: "grow", Index: 0}},
+	})
+	compiled, err := r.CompileModule(testCtx, bin)
+	require.NoError(t, err)
+
+	m1, err := r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithName("1"))
+	require.NoError(t, err)
+	m2, err := r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithName("2"))
+	require.NoError(t, err)
+
+	// Another module's minimum doesn't fit into the remaining page.
+	_, err = r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithName("3"))
+	require.EqualError(t, err, "memory budget exhausted: 2 pages requested, but 4 of 5 pages are in use")
+
+	// The remaining page can be grown into once, then memory.grow returns -1.
+	results, err := m2.ExportedFunction("grow").Call(testCtx, 1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), results[0])
+	results, err = m2.ExportedFunction("grow").Call(testCtx, 1)
+	require.NoError(t, err)
+	require.Equal(t, int32(-1), int32(results[0]))
+
+	// Closing a module returns its pages to the budget.
+	require.NoError(t, m1.Close(testCtx))
+	_, err = r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithName("3"))
+	require.NoError(t, err)
+}
+
+func TestRuntime_DeterministicProfile(t *testing.T) {
+	r := NewRuntimeWithConfig(testCtx, NewRuntimeConfigInterpreter().WithDeterministicProfile(true))
+	defer r.Close(testCtx)
+
+	f32, f64 := wasm.ValueTypeF32, wasm.ValueTypeF64
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{f64, f32}, Results: []wasm.ValueType{f64, f32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 0, wasm.OpcodeF64Mul,
+			wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 1, wasm.OpcodeF32Max,
+			wasm.OpcodeEnd,
+		}}},
+		ExportSection: []wasm.Export{{Type: api.ExternTypeFunc, Name: "nan", Index: 0}},
+	})
+	mod, err := r.Instantiate(testCtx, bin)
+	require.NoError(t, err)
+
+	// Negative NaNs with a payload, which would otherwise propagate to the results.
+	params := []uint64{0xfff4_0000_0000_0123, 0xff80_0123}
+	first, err := mod.ExportedFunction("nan").Call(testCtx, params...)
+	require.NoError(t, err)
+	second, err := mod.ExportedFunction("nan").Call(testCtx, params...)
+	require.NoError(t, err)
+	require.Equal(t, first, second)
+	require.Equal(t, []uint64{0x7ff8_0000_0000_0000, 0x7fc0_0000}, first)
+}
+
 // TestRuntime_Closed ensures invocation of closed Runtime's methods is safe.
 func TestRuntime_Closed(t *testing.T) {
 	for _, tc := range []struct {